@@ -0,0 +1,146 @@
+package stalecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// All calls all the loaders concurrently with ctx,
+// and returns after all of them returned,
+// with the errors from them joined by errors.Join.
+//
+// It's for loading from multiple caches (of different types) concurrently,
+// for example:
+//
+//	var user *User
+//	var prefs *Prefs
+//	err := stalecache.All(
+//		ctx,
+//		func(ctx context.Context) (err error) {
+//			user, err = users.Load(ctx)
+//			return err
+//		},
+//		func(ctx context.Context) (err error) {
+//			prefs, err = prefsCache.Load(ctx)
+//			return err
+//		},
+//	)
+//
+// See Load2 to Load5 for the typed variants.
+func All(ctx context.Context, loaders ...func(context.Context) error) error {
+	errs := make([]error, len(loaders))
+	var wg sync.WaitGroup
+	for i, loader := range loaders {
+		wg.Add(1)
+		go func(i int, loader func(context.Context) error) {
+			defer wg.Done()
+			errs[i] = loader(ctx)
+		}(i, loader)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Load2 calls Load on a and b concurrently with ctx,
+// and returns their results,
+// with the errors joined by errors.Join.
+func Load2[A, B any](ctx context.Context, a *Cache[A], b *Cache[B]) (dataA *A, dataB *B, err error) {
+	err = All(
+		ctx,
+		func(ctx context.Context) (err error) {
+			dataA, err = a.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataB, err = b.Load(ctx)
+			return err
+		},
+	)
+	return dataA, dataB, err
+}
+
+// Load3 is Load2 with 3 caches.
+func Load3[A, B, C any](ctx context.Context, a *Cache[A], b *Cache[B], c *Cache[C]) (dataA *A, dataB *B, dataC *C, err error) {
+	err = All(
+		ctx,
+		func(ctx context.Context) (err error) {
+			dataA, err = a.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataB, err = b.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataC, err = c.Load(ctx)
+			return err
+		},
+	)
+	return dataA, dataB, dataC, err
+}
+
+// Load4 is Load2 with 4 caches.
+func Load4[A, B, C, D any](
+	ctx context.Context,
+	a *Cache[A],
+	b *Cache[B],
+	c *Cache[C],
+	d *Cache[D],
+) (dataA *A, dataB *B, dataC *C, dataD *D, err error) {
+	err = All(
+		ctx,
+		func(ctx context.Context) (err error) {
+			dataA, err = a.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataB, err = b.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataC, err = c.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataD, err = d.Load(ctx)
+			return err
+		},
+	)
+	return dataA, dataB, dataC, dataD, err
+}
+
+// Load5 is Load2 with 5 caches.
+func Load5[A, B, C, D, E any](
+	ctx context.Context,
+	a *Cache[A],
+	b *Cache[B],
+	c *Cache[C],
+	d *Cache[D],
+	e *Cache[E],
+) (dataA *A, dataB *B, dataC *C, dataD *D, dataE *E, err error) {
+	err = All(
+		ctx,
+		func(ctx context.Context) (err error) {
+			dataA, err = a.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataB, err = b.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataC, err = c.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataD, err = d.Load(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			dataE, err = e.Load(ctx)
+			return err
+		},
+	)
+	return dataA, dataB, dataC, dataD, dataE, err
+}
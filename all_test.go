@@ -0,0 +1,84 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestAll(t *testing.T) {
+	const sleep = 10 * time.Millisecond
+	errA := errors.New("a")
+	errB := errors.New("b")
+	var calls atomic.Int64
+	start := time.Now()
+	err := stalecache.All(
+		context.Background(),
+		func(context.Context) error {
+			calls.Add(1)
+			time.Sleep(sleep)
+			return errA
+		},
+		func(context.Context) error {
+			calls.Add(1)
+			time.Sleep(sleep)
+			return nil
+		},
+		func(context.Context) error {
+			calls.Add(1)
+			time.Sleep(sleep)
+			return errB
+		},
+	)
+	if took := time.Since(start); took >= 3*sleep {
+		t.Errorf("All took %v, want < %v", took, 3*sleep)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Got %d calls, want 3", got)
+	}
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("All got error %v, want both %v and %v", err, errA, errB)
+	}
+	if err := stalecache.All(context.Background()); err != nil {
+		t.Errorf("All with no loaders got error: %v", err)
+	}
+}
+
+func TestLoadN(t *testing.T) {
+	ints := stalecache.New(func(context.Context) (*int, error) {
+		data := 1
+		return &data, nil
+	})
+	strings := stalecache.New(func(context.Context) (*string, error) {
+		data := "foo"
+		return &data, nil
+	})
+	bools := stalecache.New(func(context.Context) (*bool, error) {
+		data := true
+		return &data, nil
+	})
+	floats := stalecache.New(func(context.Context) (*float64, error) {
+		data := 1.5
+		return &data, nil
+	})
+	wantErr := errors.New("bar")
+	failing := stalecache.New(func(context.Context) (*byte, error) {
+		return nil, wantErr
+	})
+
+	i, s, err := stalecache.Load2(context.Background(), ints, strings)
+	if err != nil || *i != 1 || *s != "foo" {
+		t.Errorf("Load2 got %v, %v, %v", i, s, err)
+	}
+	i, s, b, f, e, err := stalecache.Load5(context.Background(), ints, strings, bools, floats, failing)
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Load5 got error %v, want %v", err, wantErr)
+	}
+	if *i != 1 || *s != "foo" || !*b || *f != 1.5 || e != nil {
+		t.Errorf("Load5 got %v, %v, %v, %v, %v", i, s, b, f, e)
+	}
+}
@@ -0,0 +1,125 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// ErrNotYetLoaded is the error returned by Load with WithAsyncLoad when the
+// first load has not finished successfully yet.
+var ErrNotYetLoaded error = &CacheError{Code: CodeNotYetLoaded}
+
+// WithAsyncLoad is an Option to make Load never block on the loader before
+// the first successful load.
+//
+// Default is false.
+// When set to true, Load on a cache that has never been loaded successfully
+// returns ErrNotYetLoaded immediately,
+// and calls the loader in a background goroutine with context.Background()
+// (see WithContextFunc).
+// After the first successful load, the normal ttl based behavior resumes.
+//
+// It's useful for caches initialized with the application,
+// where callers can tolerate brief unavailability.
+func WithAsyncLoad[T any](async bool) Option[T] {
+	return func(o *opt[T]) {
+		o.asyncLoad = async
+	}
+}
+
+// loadAsync makes sure that the current entry is being loaded in background,
+// and returns ErrNotYetLoaded.
+//
+// It returns nil if the current entry is already loaded successfully.
+func (c *Cache[T]) loadAsync(ctx context.Context) error {
+	curr := c.current()
+	if curr.done.Load() {
+		if curr.err == nil {
+			return nil
+		}
+		// last load failed, try again
+		newCached := c.poolGet()
+		newCached.prev.Store(curr.prev.Load())
+		if !c.cached.CompareAndSwap(curr, newCached) {
+			c.poolPut(newCached)
+		}
+		curr = c.current()
+	}
+	if curr.asyncStarted.CompareAndSwap(false, true) {
+		go c.await(c.opt.backgroundContext(ctx), curr)
+	}
+	return ErrNotYetLoaded
+}
+
+// LoadAsync is the same as Load,
+// but returns a channel to receive the result instead of blocking.
+//
+// The returned channel is 1-buffered,
+// and it's closed after exactly one Result is sent.
+// If the cached value is fresh, the Result is sent before LoadAsync returns,
+// otherwise Load is called in a background goroutine.
+func (c *Cache[T]) LoadAsync(ctx context.Context) <-chan Result[T] {
+	ch := make(chan Result[T], 1)
+	if curr := c.current(); curr.done.Load() && curr.err == nil && !curr.invalidated.Load() && !c.hasValidator() && !c.expired(curr) {
+		// Load does not block on fresh value
+		data, err := c.Load(ctx)
+		ch <- newResult(data, err)
+		close(ch)
+		return ch
+	}
+	go func() {
+		defer close(ch)
+		ch <- newResult(c.Load(ctx))
+	}()
+	return ch
+}
+
+// StreamedValue is the result of LoadStreamed.
+type StreamedValue[T any] struct {
+	// Ready is closed once the value is available.
+	Ready <-chan struct{}
+
+	// Peek returns the loaded value, blocking until Ready is closed.
+	Peek func() *T
+
+	// Err returns the error of the Load, blocking until Ready is closed.
+	Err func() error
+}
+
+// LoadStreamed is the same as LoadAsync,
+// but returns the result as a StreamedValue.
+//
+// If the cached value is fresh, Ready is already closed when it returns,
+// otherwise Ready is closed once Load called in a background goroutine
+// returns.
+// It's for the callers to start writing a response (for example, the headers
+// with http.Flusher) before the value is available.
+//
+// It only returns an error when ctx is already canceled.
+func (c *Cache[T]) LoadStreamed(ctx context.Context) (*StreamedValue[T], error) {
+	if ctx.Err() != nil {
+		return nil, canceledError(ctx)
+	}
+	ch := c.LoadAsync(ctx)
+	ready := make(chan struct{})
+	var result Result[T]
+	select {
+	case result = <-ch:
+		close(ready)
+	default:
+		go func() {
+			defer close(ready)
+			result = <-ch
+		}()
+	}
+	return &StreamedValue[T]{
+		Ready: ready,
+		Peek: func() *T {
+			<-ready
+			return result.Value
+		},
+		Err: func() error {
+			<-ready
+			return result.Err
+		},
+	}, nil
+}
@@ -0,0 +1,162 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestAsyncLoad(t *testing.T) {
+	const n = 5
+	wantErr := errors.New("foo")
+	release := make(chan struct{})
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			<-release
+			if loaderCalls.Add(1) == 1 {
+				return nil, wantErr
+			}
+			data := n
+			return &data, nil
+		},
+		stalecache.WithAsyncLoad[int](true),
+	)
+
+	for i := 0; i < n; i++ {
+		if _, err := cache.Load(context.Background()); !errors.Is(err, stalecache.ErrNotYetLoaded) {
+			t.Fatalf("Load #%d got error %v, want %v", i, err, stalecache.ErrNotYetLoaded)
+		}
+	}
+	close(release)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		data, err := cache.Load(context.Background())
+		if err == nil {
+			if *data != n {
+				t.Errorf("Load got %d, want %d", *data, n)
+			}
+			break
+		}
+		if !errors.Is(err, stalecache.ErrNotYetLoaded) {
+			t.Fatalf("Load got error %v, want %v", err, stalecache.ErrNotYetLoaded)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cache not loaded in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls, want 2", calls)
+	}
+}
+
+func TestLoadAsync(t *testing.T) {
+	const timeout = time.Second
+	release := make(chan struct{})
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		<-release
+		data := 1
+		return &data, nil
+	})
+
+	receive := func(t *testing.T, ch <-chan stalecache.Result[int]) {
+		t.Helper()
+		select {
+		case result := <-ch:
+			data, err := result.Unwrap()
+			if err != nil {
+				t.Fatalf("LoadAsync got error: %v", err)
+			}
+			if *data != 1 {
+				t.Errorf("LoadAsync got %d, want 1", *data)
+			}
+		case <-time.After(timeout):
+			t.Fatalf("LoadAsync did not return in %v", timeout)
+		}
+		if _, ok := <-ch; ok {
+			t.Error("LoadAsync sent more than one Result")
+		}
+	}
+
+	t.Run("load", func(t *testing.T) {
+		ch := cache.LoadAsync(context.Background())
+		select {
+		case <-ch:
+			t.Fatal("LoadAsync returned before the loader finished")
+		default:
+		}
+		close(release)
+		receive(t, ch)
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		ch := cache.LoadAsync(context.Background())
+		if len(ch) != 1 {
+			t.Errorf("LoadAsync on fresh value got %d buffered results, want 1", len(ch))
+		}
+		receive(t, ch)
+	})
+}
+
+func TestLoadStreamed(t *testing.T) {
+	const timeout = time.Second
+	release := make(chan struct{})
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		<-release
+		data := 1
+		return &data, nil
+	})
+
+	t.Run("load", func(t *testing.T) {
+		v, err := cache.LoadStreamed(context.Background())
+		if err != nil {
+			t.Fatalf("LoadStreamed got error: %v", err)
+		}
+		select {
+		case <-v.Ready:
+			t.Fatal("Ready closed before the loader finished")
+		default:
+		}
+		close(release)
+		select {
+		case <-v.Ready:
+		case <-time.After(timeout):
+			t.Fatalf("Ready not closed in %v", timeout)
+		}
+		if data := v.Peek(); data == nil || *data != 1 {
+			t.Errorf("Peek got %v, want 1", data)
+		}
+		if err := v.Err(); err != nil {
+			t.Errorf("Err got %v", err)
+		}
+	})
+
+	t.Run("fresh", func(t *testing.T) {
+		v, err := cache.LoadStreamed(context.Background())
+		if err != nil {
+			t.Fatalf("LoadStreamed got error: %v", err)
+		}
+		select {
+		case <-v.Ready:
+		default:
+			t.Error("Ready not closed for fresh value")
+		}
+		if data := v.Peek(); data == nil || *data != 1 {
+			t.Errorf("Peek got %v, want 1", data)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if _, err := cache.LoadStreamed(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("LoadStreamed got error %v, want %v", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package stalecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+type atomicEntry[T any] struct {
+	data   *T
+	loaded time.Time
+}
+
+// AtomicCache is a lock-free variant of Cache.
+//
+// Reads are a single atomic load, and writes use a CAS loop.
+// The trade-off is that concurrent Load calls on a stale AtomicCache could call
+// the loader at the same time (there's no coalescing),
+// so it works best with read-heavy workloads where the loader is rarely
+// called (for example, ttl > 1s).
+//
+// AtomicCache only supports the following options,
+// NewAtomic panics (and NewAtomicE returns an error) on the other options:
+//
+//   - WithTTL (but not WithJitter) and WithClock
+//   - WithValidator, WithAllValidators and WithAnyValidator
+//     (but not WithValidatorV2)
+//   - WithWeightedLoaders and WithAdaptiveWeights
+//   - WithErrorWrapper
+//   - WithMutualExclusion and WithMutualExclusionTimeout
+//   - WithMaxConcurrentLoads and WithSharedSemaphore
+//
+// Loader errors are not cached, every Load on a stale AtomicCache retries the
+// loader.
+type AtomicCache[T any] struct {
+	opt opt[T]
+
+	entry atomic.Pointer[atomicEntry[T]]
+}
+
+// NewAtomic creates a new AtomicCache with loader and options.
+//
+// It panics if the options are invalid or not supported by AtomicCache,
+// use NewAtomicE to get the error instead.
+func NewAtomic[T any](loader Loader[T], options ...Option[T]) *AtomicCache[T] {
+	c, err := NewAtomicE(loader, options...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewAtomicE is NewAtomic but returns an error instead of panicking,
+// if the options are invalid (the same as NewE) or not supported by
+// AtomicCache.
+func NewAtomicE[T any](loader Loader[T], options ...Option[T]) (*AtomicCache[T], error) {
+	if err := atomicUnsupported(options); err != nil {
+		return nil, err
+	}
+	o := newOpt(loader, options)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return &AtomicCache[T]{
+		opt: *o,
+	}, nil
+}
+
+// atomicUnsupported returns an error naming each of the options not supported
+// by AtomicCache.
+func atomicUnsupported[T any](options []Option[T]) error {
+	var o opt[T]
+	for _, option := range options {
+		option(&o)
+	}
+	var errs []error
+	for _, u := range []struct {
+		option string
+		set    bool
+	}{
+		{"WithBatchLoader", o.batcher != nil},
+		{"WithErrorTTL", o.errorTTL != 0},
+		{"WithNegativeCaching", o.negTTL != 0 || o.isNegative != nil},
+		{"WithSlidingTTL", o.slidingTTL != 0},
+		{"WithDynamicTTL", o.dynamicTTL != nil},
+		{"WithIdleTTL", o.idleTTL != 0},
+		{"WithBucketTTL", o.bucketTTL != 0},
+		{"WithDebounce", o.debounce != 0},
+		{"WithJitter", o.jitter != 0},
+		{"WithMaxStale", o.maxStale != 0},
+		{"WithGracePeriod", o.grace != 0},
+		{"WithValidatorV2", o.validatorV2 != nil},
+		{"WithCoalescing", o.noCoalescing},
+		{"WithConcurrencyMetrics", o.concurrencyMetrics},
+		{"WithEagerInvalidation", o.eagerInvalidation},
+		{"WithBackgroundRefresh", o.refreshAhead != 0},
+		{"WithProbabilisticExpiry", o.xfetchBeta != 0},
+		{"WithAsyncLoad", o.asyncLoad},
+		{"WithContextFunc", o.contextFunc != nil},
+		{"WithGlobalPool", o.pool != nil},
+		{"WithOnPoolGet", o.onPoolGet != nil},
+		{"WithOnPoolPut", o.onPoolPut != nil},
+		{"WithInitialValue", o.initialValue != nil},
+		{"WithPartialUpdate", o.merge != nil},
+		{"WithEqualFunc", o.equal != nil},
+		{"WithCopyFunc", o.copyFn != nil},
+		{"WithCOW", o.cow},
+		{"WithSizeLimit", o.sizeFn != nil || o.maxBytes != 0},
+		{"WithTransform", o.transform != nil},
+		{"WithHooks", len(o.hooks) > 0},
+		{"WithKeyedHooks and WithNamedHooks", len(o.keyedHooks) > 0},
+		{"WithCacheKey", o.key != ""},
+		{"WithName", o.name != ""},
+		{"WithMiddleware", len(o.middlewares) > 0},
+		{"WithPreRefreshHook", o.preRefresh != nil},
+		{"WithPostRefreshHook", o.postRefresh != nil},
+		{"WithOnStale", o.onStale != nil},
+		{"WithOnEvict", o.onEvict != nil},
+		{"WithTraceFunc", o.traceFunc != nil},
+		{"WithSkipCondition", o.skip != nil},
+		{"WithMetadataStore", o.updateMeta != nil},
+		{"WithFallbackLoader", o.streamFallback != nil},
+		{"WithReconnectBackoff", o.streamBackoffMin != 0 || o.streamBackoffMax != 0},
+		{"WithSmartTTL", o.smartTTL != nil},
+		{"WithRetry", o.retryAttempts != 0 || o.retryDelay != 0},
+		{"WithRefreshRetryLimit", o.refreshRetryLimit != 0},
+		{"WithLoadTimeout", o.loadTimeout != 0},
+		{"WithRecoverPanics", o.recoverPanics},
+		{"WithRateLimit", o.rateLimit},
+		{"WithCircuitBreaker", o.circuitThreshold != 0 || o.circuitCooldown != 0},
+		{"WithCacheWarming", o.warmer != nil || o.warmerInterval != 0},
+		{"WithPubSub", o.pubsub != nil},
+		{"WithWriteBack", o.writer != nil},
+		{"WithInvalidationChannel", len(o.invalidations) > 0},
+		{"WithOnErrorFallback", o.errorFallback != nil},
+		{"WithCodec", o.encode != nil || o.decode != nil},
+	} {
+		if u.set {
+			errs = append(errs, fmt.Errorf("stalecache: %s is not supported by AtomicCache", u.option))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Load loads the cached value.
+//
+// If the cached value is stale (or never loaded before),
+// the set loader will be called to load it from external source.
+// If the loader call failed,
+// it returns the cached stale data (if any) with the error from the loader.
+func (c *AtomicCache[T]) Load(ctx context.Context) (*T, error) {
+	curr := c.entry.Load()
+	if curr != nil {
+		fresh := c.opt.ttl <= 0 || curr.loaded.Add(c.opt.ttl).After(c.opt.now())
+		if fresh && c.opt.validator != nil {
+			fresh = c.validate(ctx, curr)
+		}
+		if fresh {
+			return curr.data, nil
+		}
+	}
+	data, err := c.opt.loader(ctx)
+	if err != nil {
+		if curr != nil {
+			return curr.data, err
+		}
+		return nil, err
+	}
+	c.store(&atomicEntry[T]{
+		data:   data,
+		loaded: c.opt.now(),
+	})
+	return data, nil
+}
+
+// validate calls the validator of c,
+// unless it's already running in the call chain of ctx.
+func (c *AtomicCache[T]) validate(ctx context.Context, curr *atomicEntry[T]) bool {
+	ctx, ok := enterValidator(ctx, c)
+	if !ok {
+		return false
+	}
+	return c.opt.validator(ctx, curr.data, curr.loaded)
+}
+
+// Update updates the cache with data and current timestamp.
+//
+// It has the same signature as Cache.Update,
+// but it always returns nil as WithWriteBack is not supported by AtomicCache.
+func (c *AtomicCache[T]) Update(_ context.Context, data *T) error {
+	c.store(&atomicEntry[T]{
+		data:   data,
+		loaded: c.opt.now(),
+	})
+	return nil
+}
+
+// store stores entry unless a newer entry is already stored.
+func (c *AtomicCache[T]) store(entry *atomicEntry[T]) {
+	for {
+		curr := c.entry.Load()
+		if curr != nil && curr.loaded.After(entry.loaded) {
+			return
+		}
+		if c.entry.CompareAndSwap(curr, entry) {
+			return
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestAtomicCache(t *testing.T) {
+	const (
+		ttl = 10 * time.Millisecond
+		n   = 5
+	)
+	var loaderCalls atomic.Int64
+	wantErr := errors.New("foo")
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.NewAtomic(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, wantErr
+			}
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			data, err := cache.Load(context.Background())
+			if err != nil {
+				t.Errorf("Load #%d returned error: %v", i, err)
+			}
+			if data == nil {
+				t.Errorf("Load #%d returned nil data", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	calls := loaderCalls.Load()
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if after := loaderCalls.Load(); after != calls {
+		t.Errorf("Load on fresh cache called loader %d times", after-calls)
+	}
+
+	clock.Advance(ttl)
+	fail.Store(true)
+	data, err := cache.Load(context.Background())
+	if !errors.Is(err, wantErr) {
+		t.Errorf("Load got error %v, want %v", err, wantErr)
+	}
+	if data == nil {
+		t.Error("Load did not return stale data on loader failure")
+	}
+}
+
+func TestNewAtomicE(t *testing.T) {
+	loader := func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	}
+	for _, c := range []struct {
+		label   string
+		options []stalecache.Option[int]
+		wantErr bool
+		// wantOption is the option named in the error, if not empty.
+		wantOption string
+	}{
+		{
+			label: "supported",
+			options: []stalecache.Option[int]{
+				stalecache.WithTTL[int](time.Second),
+				stalecache.WithMaxConcurrentLoads[int](1),
+			},
+		},
+		{
+			label:   "negative-ttl",
+			options: []stalecache.Option[int]{stalecache.WithTTL[int](-time.Second)},
+			wantErr: true,
+		},
+		{
+			label:      "unsupported",
+			options:    []stalecache.Option[int]{stalecache.WithSlidingTTL[int](time.Second)},
+			wantErr:    true,
+			wantOption: "WithSlidingTTL",
+		},
+		{
+			label: "jitter",
+			options: []stalecache.Option[int]{
+				stalecache.WithTTL[int](time.Second),
+				stalecache.WithJitter[int](0.1),
+			},
+			wantErr:    true,
+			wantOption: "WithJitter",
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cache, err := stalecache.NewAtomicE(loader, c.options...)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Fatalf("NewAtomicE got error %v, want error: %v", err, c.wantErr)
+			}
+			if c.wantOption != "" && !strings.Contains(err.Error(), c.wantOption) {
+				t.Errorf("NewAtomicE got error %v, want it to name %s", err, c.wantOption)
+			}
+			if c.wantErr {
+				return
+			}
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Errorf("Load got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestAtomicCacheValidatorReentry(t *testing.T) {
+	loader := func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	}
+	var a, b *stalecache.AtomicCache[int]
+	a = stalecache.NewAtomic(
+		loader,
+		stalecache.WithValidator(func(ctx context.Context, _ *int, _ time.Time) bool {
+			_, err := b.Load(ctx)
+			return err == nil
+		}),
+	)
+	b = stalecache.NewAtomic(
+		loader,
+		stalecache.WithValidator(func(ctx context.Context, _ *int, _ time.Time) bool {
+			_, err := a.Load(ctx)
+			return err == nil
+		}),
+	)
+
+	// Without the re-entry detection these would recurse forever.
+	for i := 0; i < 2; i++ {
+		if _, err := a.Load(context.Background()); err != nil {
+			t.Errorf("a.Load #%d got error: %v", i, err)
+		}
+		if _, err := b.Load(context.Background()); err != nil {
+			t.Errorf("b.Load #%d got error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BatchLoader defines the callback to load the values of multiple keys from
+// external source in a single call.
+//
+// Keys missing from the returned map are treated as failed loads of those keys.
+type BatchLoader[K comparable, T any] func(ctx context.Context, keys []K) (map[K]*T, error)
+
+// WithBatchLoader is an Option for Map to coalesce the loads of different keys
+// into batched loader calls.
+//
+// When set, the MapLoader passed into NewMap is not used,
+// instead the keys need to be loaded within the same window are collected and
+// passed into a single loader call,
+// which is useful to turn N+1 queries into a single query with IN clause.
+// The window starts from the first key of a batch,
+// so a key waits at most window before its batch is loaded.
+//
+// The ctx passed into loader carries the values of the ctx that starts the
+// batch, but it's never canceled.
+// When the loader returns an error, all keys in the batch fail with it.
+// It's ignored by New and other non-Map usages.
+func WithBatchLoader[K comparable, T any](loader BatchLoader[K, T], window time.Duration) Option[T] {
+	b := &batcher[K, T]{
+		loader: loader,
+		window: window,
+	}
+	return func(o *opt[T]) {
+		o.batcher = b
+	}
+}
+
+type batcher[K comparable, T any] struct {
+	loader BatchLoader[K, T]
+	window time.Duration
+
+	mu      sync.Mutex
+	pending *batch[K, T]
+}
+
+type batch[K comparable, T any] struct {
+	keys map[K]struct{}
+	done chan struct{}
+
+	// only available after done is closed.
+	results map[K]*T
+	err     error
+}
+
+func (b *batcher[K, T]) load(ctx context.Context, key K) (*T, error) {
+	b.mu.Lock()
+	curr := b.pending
+	if curr == nil {
+		curr = &batch[K, T]{
+			keys: make(map[K]struct{}),
+			done: make(chan struct{}),
+		}
+		b.pending = curr
+		loaderCtx := context.WithoutCancel(ctx)
+		time.AfterFunc(b.window, func() {
+			b.fire(loaderCtx, curr)
+		})
+	}
+	curr.keys[key] = struct{}{}
+	b.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, &CacheError{Code: CodeCanceled, Err: ctx.Err()}
+	case <-curr.done:
+	}
+	if curr.err != nil {
+		return nil, curr.err
+	}
+	data, ok := curr.results[key]
+	if !ok {
+		return nil, fmt.Errorf("stalecache: key %v not returned by batch loader", key)
+	}
+	return data, nil
+}
+
+func (b *batcher[K, T]) fire(ctx context.Context, curr *batch[K, T]) {
+	b.mu.Lock()
+	if b.pending == curr {
+		b.pending = nil
+	}
+	keys := make([]K, 0, len(curr.keys))
+	for key := range curr.keys {
+		keys = append(keys, key)
+	}
+	b.mu.Unlock()
+
+	defer close(curr.done)
+	curr.results, curr.err = b.loader(ctx, keys)
+}
@@ -0,0 +1,89 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestBatchLoader(t *testing.T) {
+	const window = 10 * time.Millisecond
+	var batches atomic.Int64
+	var lastKeys []int
+	var mu sync.Mutex
+	m := stalecache.NewMap[int, int](
+		nil,
+		stalecache.WithBatchLoader(func(_ context.Context, keys []int) (map[int]*int, error) {
+			batches.Add(1)
+			mu.Lock()
+			lastKeys = slices.Clone(keys)
+			mu.Unlock()
+			result := make(map[int]*int, len(keys))
+			for _, key := range keys {
+				if key < 0 {
+					continue
+				}
+				data := key * 2
+				result[key] = &data
+			}
+			return result, nil
+		}, window),
+	)
+
+	keys := []int{1, 2, 3}
+	var wg sync.WaitGroup
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key int) {
+			defer wg.Done()
+			data, err := m.Load(context.Background(), key)
+			if err != nil {
+				t.Errorf("Load(%d) got error: %v", key, err)
+				return
+			}
+			if *data != key*2 {
+				t.Errorf("Load(%d) got %d, want %d", key, *data, key*2)
+			}
+		}(key)
+	}
+	wg.Wait()
+	if got := batches.Load(); got != 1 {
+		t.Errorf("Got %d batch loader calls, want 1", got)
+	}
+	slices.Sort(lastKeys)
+	if want := []int{1, 2, 3}; !slices.Equal(lastKeys, want) {
+		t.Errorf("Batch loader got keys %v, want %v", lastKeys, want)
+	}
+
+	// Cached keys don't trigger new batches.
+	if _, err := m.Load(context.Background(), 1); err != nil {
+		t.Errorf("Load(1) got error: %v", err)
+	}
+	if got := batches.Load(); got != 1 {
+		t.Errorf("Got %d batch loader calls after cached Load, want 1", got)
+	}
+
+	// Keys missing from the result fail.
+	if data, err := m.Load(context.Background(), -1); err == nil {
+		t.Errorf("Load(-1) got %d, want error", *data)
+	}
+}
+
+func TestBatchLoaderError(t *testing.T) {
+	want := errors.New("foo")
+	m := stalecache.NewMap[string, int](
+		nil,
+		stalecache.WithBatchLoader(func(context.Context, []string) (map[string]*int, error) {
+			return nil, want
+		}, time.Millisecond),
+	)
+	if _, err := m.Load(context.Background(), "foo"); !errors.Is(err, want) {
+		t.Errorf("Load got error %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,82 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+// benchData is returned by all the loaders in the benchmarks,
+// so the loader cost is not measured.
+var benchData = new(int)
+
+func benchLoader(context.Context) (*int, error) {
+	return benchData, nil
+}
+
+func BenchmarkCacheLoadHit(b *testing.B) {
+	ctx := context.Background()
+	cache := stalecache.New(benchLoader, stalecache.WithTTL[int](time.Hour))
+	cache.Load(ctx)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Load(ctx)
+	}
+}
+
+func BenchmarkCacheLoadMiss(b *testing.B) {
+	const ttl = time.Millisecond
+	ctx := context.Background()
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		benchLoader,
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+	cache.Load(ctx)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clock.Advance(ttl)
+		cache.Load(ctx)
+	}
+}
+
+func BenchmarkCacheLoadContention(b *testing.B) {
+	ctx := context.Background()
+	cache := stalecache.New(benchLoader, stalecache.WithTTL[int](time.Hour))
+	cache.Load(ctx)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			cache.Load(ctx)
+		}
+	})
+}
+
+func BenchmarkAtomicCache(b *testing.B) {
+	ctx := context.Background()
+	cache := stalecache.NewAtomic(benchLoader, stalecache.WithTTL[int](time.Hour))
+	cache.Load(ctx)
+
+	b.Run("hit", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			cache.Load(ctx)
+		}
+	})
+
+	b.Run("contention", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				cache.Load(ctx)
+			}
+		})
+	})
+}
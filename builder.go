@@ -0,0 +1,70 @@
+package stalecache
+
+import (
+	"context"
+	"time"
+)
+
+// Builder is a chainable alternative to passing options to New.
+//
+// Every method returns a new Builder with the option appended,
+// so a Builder can be safely reused as the base of different caches.
+// The zero value is not usable, use NewBuilder instead.
+type Builder[T any] struct {
+	loader  Loader[T]
+	options []Option[T]
+}
+
+// NewBuilder starts a Builder with loader.
+func NewBuilder[T any](loader Loader[T]) Builder[T] {
+	return Builder[T]{loader: loader}
+}
+
+// With returns the Builder with options appended,
+// for the options without a dedicated Builder method.
+func (b Builder[T]) With(options ...Option[T]) Builder[T] {
+	// 3-index slice so that branches of the same Builder never share the
+	// appended options.
+	b.options = append(b.options[:len(b.options):len(b.options)], options...)
+	return b
+}
+
+// TTL is the same as WithTTL.
+func (b Builder[T]) TTL(ttl time.Duration) Builder[T] {
+	return b.With(WithTTL[T](ttl))
+}
+
+// Validator is the same as WithValidator.
+func (b Builder[T]) Validator(validator func(ctx context.Context, data *T, loaded time.Time) (fresh bool)) Builder[T] {
+	return b.With(WithValidator(validator))
+}
+
+// Hooks is the same as WithHooks.
+func (b Builder[T]) Hooks(hooks Hooks[T]) Builder[T] {
+	return b.With(WithHooks(hooks))
+}
+
+// Name is the same as WithName.
+func (b Builder[T]) Name(name string) Builder[T] {
+	return b.With(WithName[T](name))
+}
+
+// Options returns the options accumulated in the Builder,
+// in the order they are added.
+func (b Builder[T]) Options() []Option[T] {
+	return b.options[:len(b.options):len(b.options)]
+}
+
+// Build is the same as calling New with the loader and the options
+// accumulated in the Builder.
+//
+// It panics if the options are invalid, use BuildE to get the error instead.
+func (b Builder[T]) Build() *Cache[T] {
+	return New(b.loader, b.options...)
+}
+
+// BuildE is the same as calling NewE with the loader and the options
+// accumulated in the Builder.
+func (b Builder[T]) BuildE() (*Cache[T], error) {
+	return NewE(b.loader, b.options...)
+}
@@ -0,0 +1,61 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestBuilder(t *testing.T) {
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls int
+	var hookLoads int
+	base := stalecache.NewBuilder(func(context.Context) (*int, error) {
+		calls++
+		data := calls
+		return &data, nil
+	}).With(stalecache.WithClock[int](clock))
+	cache := base.
+		TTL(time.Minute).
+		Name("builder").
+		Hooks(stalecache.Hooks[int]{
+			OnLoadEnd: func(*int, error, time.Duration) {
+				hookLoads++
+			},
+		}).
+		Build()
+	if got, want := cache.Name(), "builder"; got != want {
+		t.Errorf("Name got %q, want %q", got, want)
+	}
+
+	ctx := context.Background()
+	load := func(t *testing.T, want int) {
+		t.Helper()
+		got, err := cache.Load(ctx)
+		if err != nil {
+			t.Fatalf("Load returned error: %v", err)
+		}
+		if *got != want {
+			t.Errorf("Load got %d, want %d", *got, want)
+		}
+	}
+	load(t, 1)
+	clock.Advance(time.Minute - time.Second)
+	load(t, 1)
+	clock.Advance(time.Second)
+	load(t, 2)
+	if hookLoads != 2 {
+		t.Errorf("OnLoadEnd called %d times, want 2", hookLoads)
+	}
+
+	// Branching from base must not be affected by the chain above.
+	if got, want := len(base.Options()), 1; got != want {
+		t.Errorf("base has %d options, want %d", got, want)
+	}
+	if _, err := base.TTL(-time.Second).BuildE(); err == nil {
+		t.Error("BuildE with negative TTL returned nil error")
+	}
+}
@@ -0,0 +1,150 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// Bundle2 bundles 2 caches of related values loaded together.
+type Bundle2[A, B any] struct {
+	a *Cache[A]
+	b *Cache[B]
+}
+
+// NewBundle2 creates a new Bundle2 with loaders and options of both values.
+//
+// optionsA and optionsB are applied to the cache of the first and second value
+// respectively, and can be nil.
+// There are no options shared by both caches (and no scoping helpers to apply
+// options to only one of them),
+// as an Option[A] cannot be applied to a Cache[B],
+// so each cache takes its own typed options instead.
+// Usually the same ttl should be used for both caches so they are reloaded
+// together.
+func NewBundle2[A, B any](
+	loaderA Loader[A],
+	loaderB Loader[B],
+	optionsA []Option[A],
+	optionsB []Option[B],
+) *Bundle2[A, B] {
+	return &Bundle2[A, B]{
+		a: New(loaderA, optionsA...),
+		b: New(loaderB, optionsB...),
+	}
+}
+
+// Load loads both values concurrently.
+//
+// Each value is loaded by its own cache with the stale fallback,
+// and the returned error joins the errors from both.
+func (b *Bundle2[A, B]) Load(ctx context.Context) (*A, *B, error) {
+	return Load2(ctx, b.a, b.b)
+}
+
+// CacheA returns the cache of the first value.
+func (b *Bundle2[A, B]) CacheA() *Cache[A] {
+	return b.a
+}
+
+// CacheB returns the cache of the second value.
+func (b *Bundle2[A, B]) CacheB() *Cache[B] {
+	return b.b
+}
+
+// Bundle3 bundles 3 caches of related values loaded together.
+type Bundle3[A, B, C any] struct {
+	a *Cache[A]
+	b *Cache[B]
+	c *Cache[C]
+}
+
+// NewBundle3 is NewBundle2 with 3 values,
+// and optionsC is applied to the cache of the third value.
+func NewBundle3[A, B, C any](
+	loaderA Loader[A],
+	loaderB Loader[B],
+	loaderC Loader[C],
+	optionsA []Option[A],
+	optionsB []Option[B],
+	optionsC []Option[C],
+) *Bundle3[A, B, C] {
+	return &Bundle3[A, B, C]{
+		a: New(loaderA, optionsA...),
+		b: New(loaderB, optionsB...),
+		c: New(loaderC, optionsC...),
+	}
+}
+
+// Load loads all 3 values concurrently,
+// the same as Bundle2.Load.
+func (b *Bundle3[A, B, C]) Load(ctx context.Context) (*A, *B, *C, error) {
+	return Load3(ctx, b.a, b.b, b.c)
+}
+
+// CacheA returns the cache of the first value.
+func (b *Bundle3[A, B, C]) CacheA() *Cache[A] {
+	return b.a
+}
+
+// CacheB returns the cache of the second value.
+func (b *Bundle3[A, B, C]) CacheB() *Cache[B] {
+	return b.b
+}
+
+// CacheC returns the cache of the third value.
+func (b *Bundle3[A, B, C]) CacheC() *Cache[C] {
+	return b.c
+}
+
+// Bundle4 bundles 4 caches of related values loaded together.
+type Bundle4[A, B, C, D any] struct {
+	a *Cache[A]
+	b *Cache[B]
+	c *Cache[C]
+	d *Cache[D]
+}
+
+// NewBundle4 is NewBundle3 with 4 values,
+// and optionsD is applied to the cache of the fourth value.
+func NewBundle4[A, B, C, D any](
+	loaderA Loader[A],
+	loaderB Loader[B],
+	loaderC Loader[C],
+	loaderD Loader[D],
+	optionsA []Option[A],
+	optionsB []Option[B],
+	optionsC []Option[C],
+	optionsD []Option[D],
+) *Bundle4[A, B, C, D] {
+	return &Bundle4[A, B, C, D]{
+		a: New(loaderA, optionsA...),
+		b: New(loaderB, optionsB...),
+		c: New(loaderC, optionsC...),
+		d: New(loaderD, optionsD...),
+	}
+}
+
+// Load loads all 4 values concurrently,
+// the same as Bundle2.Load.
+func (b *Bundle4[A, B, C, D]) Load(ctx context.Context) (*A, *B, *C, *D, error) {
+	return Load4(ctx, b.a, b.b, b.c, b.d)
+}
+
+// CacheA returns the cache of the first value.
+func (b *Bundle4[A, B, C, D]) CacheA() *Cache[A] {
+	return b.a
+}
+
+// CacheB returns the cache of the second value.
+func (b *Bundle4[A, B, C, D]) CacheB() *Cache[B] {
+	return b.b
+}
+
+// CacheC returns the cache of the third value.
+func (b *Bundle4[A, B, C, D]) CacheC() *Cache[C] {
+	return b.c
+}
+
+// CacheD returns the cache of the fourth value.
+func (b *Bundle4[A, B, C, D]) CacheD() *Cache[D] {
+	return b.d
+}
@@ -0,0 +1,148 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestBundle2(t *testing.T) {
+	const ttl = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var callsA, callsB int
+	errB := errors.New("foo")
+	failB := false
+	bundle := stalecache.NewBundle2(
+		func(context.Context) (*int, error) {
+			callsA++
+			return &callsA, nil
+		},
+		func(context.Context) (*string, error) {
+			callsB++
+			if failB {
+				return nil, errB
+			}
+			data := "b"
+			return &data, nil
+		},
+		[]stalecache.Option[int]{
+			stalecache.WithTTL[int](ttl),
+			stalecache.WithClock[int](clock),
+		},
+		[]stalecache.Option[string]{
+			stalecache.WithTTL[string](ttl),
+			stalecache.WithClock[string](clock),
+		},
+	)
+
+	for i := 0; i < 2; i++ {
+		a, b, err := bundle.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load #%d got error: %v", i, err)
+		}
+		if *a != 1 || *b != "b" {
+			t.Errorf("Load #%d got %d, %q, want 1, \"b\"", i, *a, *b)
+		}
+	}
+	if callsA != 1 || callsB != 1 {
+		t.Errorf("Got %d, %d loader calls, want 1, 1", callsA, callsB)
+	}
+
+	clock.Advance(ttl)
+	failB = true
+	a, b, err := bundle.Load(context.Background())
+	if !errors.Is(err, errB) {
+		t.Errorf("Load got error %v, want %v", err, errB)
+	}
+	if *a != 2 {
+		t.Errorf("Load got a %d, want 2", *a)
+	}
+	if b == nil || *b != "b" {
+		t.Errorf("Load got b %v, want stale \"b\"", b)
+	}
+}
+
+func TestBundle4(t *testing.T) {
+	errD := errors.New("foo")
+	bundle := stalecache.NewBundle4(
+		func(context.Context) (*int, error) {
+			data := 1
+			return &data, nil
+		},
+		func(context.Context) (*string, error) {
+			data := "b"
+			return &data, nil
+		},
+		func(context.Context) (*bool, error) {
+			data := true
+			return &data, nil
+		},
+		func(context.Context) (*float64, error) {
+			return nil, errD
+		},
+		nil,
+		nil,
+		nil,
+		nil,
+	)
+	a, b, c, d, err := bundle.Load(context.Background())
+	if !errors.Is(err, errD) {
+		t.Errorf("Load got error %v, want %v", err, errD)
+	}
+	if *a != 1 || *b != "b" || !*c {
+		t.Errorf("Load got %d, %q, %v, want 1, \"b\", true", *a, *b, *c)
+	}
+	if d != nil {
+		t.Errorf("Load got d %v, want nil", *d)
+	}
+	if _, _, err := bundle.CacheD().Peek(); !errors.Is(err, errD) {
+		t.Errorf("CacheD().Peek got error %v, want %v", err, errD)
+	}
+}
+
+func TestBundleOptions(t *testing.T) {
+	loader := func(data int) stalecache.Loader[int] {
+		return func(context.Context) (*int, error) {
+			return &data, nil
+		}
+	}
+
+	var hits [3]atomic.Int64
+	hooks := func(i int) []stalecache.Option[int] {
+		return []stalecache.Option[int]{
+			stalecache.WithHooks(stalecache.Hooks[int]{
+				OnHit: func(*int, time.Time) {
+					hits[i].Add(1)
+				},
+			}),
+		}
+	}
+	bundle := stalecache.NewBundle3(
+		loader(1),
+		loader(2),
+		loader(3),
+		hooks(0),
+		nil,
+		hooks(2),
+	)
+	for i := 0; i < 2; i++ {
+		a, b, c, err := bundle.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *a != 1 || *b != 2 || *c != 3 {
+			t.Errorf("Load got %d, %d, %d, want 1, 2, 3", *a, *b, *c)
+		}
+	}
+	// every hook is only called by the cache it's passed to.
+	for i, want := range []int64{1, 0, 1} {
+		if got := hits[i].Load(); got != want {
+			t.Errorf("Got %d hits from the hook #%d, want %d", got, i, want)
+		}
+	}
+}
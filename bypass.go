@@ -0,0 +1,31 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// bypassKey is the context key used by BypassContext.
+type bypassKey struct{}
+
+// BypassContext returns a ctx that causes Load (and its variants) to bypass
+// the cache.
+//
+// A Load call with the returned ctx (or a ctx derived from it) behaves the
+// same as ForceReload:
+// it ignores the ttl and validator and reloads the cache from the loader
+// (with all the loader related Options like WithLoadTimeout and WithRetry
+// still applied),
+// and the result is stored into the cache for other Load calls.
+// It's useful for tests and admin endpoints.
+//
+// The ctx is also passed into the loader,
+// which can use IsBypassed to tell.
+func BypassContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// IsBypassed returns true if ctx is from BypassContext.
+func IsBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(bypassKey{}).(bool)
+	return bypassed
+}
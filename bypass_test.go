@@ -0,0 +1,46 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestBypassContext(t *testing.T) {
+	var loaderCalls, bypassedCalls atomic.Int64
+	cache := stalecache.New(
+		func(ctx context.Context) (*int64, error) {
+			if stalecache.IsBypassed(ctx) {
+				bypassedCalls.Add(1)
+			}
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](time.Hour),
+	)
+	cache.Load(context.Background())
+
+	check := func(t *testing.T, ctx context.Context, want int64) {
+		t.Helper()
+		data, err := cache.Load(ctx)
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	t.Run("bypass", func(t *testing.T) {
+		check(t, stalecache.BypassContext(context.Background()), 2)
+	})
+	t.Run("cached", func(t *testing.T) {
+		check(t, context.Background(), 2)
+	})
+	if got := bypassedCalls.Load(); got != 1 {
+		t.Errorf("Got %d bypassed loader calls, want 1", got)
+	}
+}
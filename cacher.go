@@ -0,0 +1,21 @@
+package stalecache
+
+import (
+	"context"
+	"time"
+)
+
+// Cacher is the common interface of Cache and its test doubles,
+// for example stalecachetest.FakeCache.
+//
+// Code depending on a Cache can accept a Cacher instead to be tested without
+// calling the real loader.
+type Cacher[T any] interface {
+	Load(ctx context.Context) (*T, error)
+	Peek() (*T, time.Time, error)
+	Update(ctx context.Context, data *T) error
+	Subscribe(ctx context.Context) <-chan *T
+	Stats() Stats
+}
+
+var _ Cacher[int] = (*Cache[int])(nil)
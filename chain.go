@@ -0,0 +1,98 @@
+package stalecache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ChainError is the error from one level of the caches of NewChain.
+type ChainError struct {
+	// Level is the index of the cache in the caches passed into NewChain.
+	Level int
+	// Name is the name of the cache set by WithName, if any.
+	Name string
+	Err  error
+}
+
+func (e *ChainError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("stalecache chain[%d]: %v", e.Level, e.Err)
+	}
+	return fmt.Sprintf("stalecache chain[%d] %q: %v", e.Level, e.Name, e.Err)
+}
+
+func (e *ChainError) Unwrap() error {
+	return e.Err
+}
+
+// NewChain creates a new Cache backed by multiple levels of caches.
+//
+// The loader of the returned Cache calls Load on every cache in caches in
+// order, and returns the first successful result,
+// which is also propagated back to the caches before it via Update.
+// If all of them failed, the errors from all of them are returned joined,
+// each wrapped in a *ChainError.
+// errors.As finds the ChainError of the first level,
+// use the Unwrap() []error method of the joined error to get all of them.
+//
+// For example, with caches of an in-process cache (L1) and a redis backed
+// cache (L2), a reload first tries L1, then L2, and updates L1 when L2
+// succeeded.
+//
+// The options apply to the returned Cache (for example,
+// WithTTL and WithValidator decide when the chain is reloaded),
+// while every cache in caches still uses its own options.
+// caches is a slice instead of variadic,
+// as only the last parameter can be variadic and it's taken by the options,
+// same as New.
+//
+// It panics if caches is empty.
+func NewChain[T any](caches []*Cache[T], options ...Option[T]) *Cache[T] {
+	if len(caches) == 0 {
+		panic("stalecache.NewChain: no caches")
+	}
+	return New(func(ctx context.Context) (*T, error) {
+		errs := make([]error, 0, len(caches))
+		for i, c := range caches {
+			data, err := c.Load(ctx)
+			if err != nil {
+				errs = append(errs, &ChainError{
+					Level: i,
+					Name:  c.Name(),
+					Err:   err,
+				})
+				continue
+			}
+			for _, prev := range caches[:i] {
+				prev.Update(ctx, data)
+			}
+			return data, nil
+		}
+		return nil, errors.Join(errs...)
+	}, options...)
+}
+
+// ErrReadOnly is the error returned by Update and UpdateIfVersion on a
+// read-only Cache created by NewReplica.
+var ErrReadOnly error = &CacheError{Code: CodeReadOnly}
+
+// NewReplica creates a new read-only Cache backed by primary.
+//
+// The loader of the returned Cache is primary.Load,
+// so a reload of the replica gets the cached value of primary,
+// and only calls the loader of primary when primary is also stale.
+// The options apply to the replica only (for example, WithTTL decides when the
+// replica reloads from primary),
+// while primary still uses its own options.
+//
+// The replica is read-only:
+// Update and UpdateIfVersion return ErrReadOnly,
+// UpdateFunc and TryUpdate do nothing,
+// and LoadOrUpdate ignores the new value.
+// Updates should go to primary instead.
+func NewReplica[T any](primary *Cache[T], options ...Option[T]) *Cache[T] {
+	return New(primary.Load, append(options[:len(options):len(options)], func(o *opt[T]) {
+		o.readOnly = true
+	})...)
+}
@@ -0,0 +1,155 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestChain(t *testing.T) {
+	const source = "source"
+	l1Err := errors.New("l1")
+	l2Err := errors.New("l2")
+	var l1Calls, l2Calls, sourceCalls atomic.Int64
+	var sourceFail atomic.Bool
+	l1 := stalecache.New(func(context.Context) (*string, error) {
+		l1Calls.Add(1)
+		return nil, l1Err
+	})
+	l2 := stalecache.New(
+		func(context.Context) (*string, error) {
+			l2Calls.Add(1)
+			return nil, l2Err
+		},
+		stalecache.WithName[string]("l2"),
+	)
+	src := stalecache.New(func(context.Context) (*string, error) {
+		sourceCalls.Add(1)
+		if sourceFail.Load() {
+			return nil, errors.New("source")
+		}
+		s := source
+		return &s, nil
+	})
+	chain := stalecache.NewChain([]*stalecache.Cache[string]{l1, l2, src})
+
+	t.Run("load", func(t *testing.T) {
+		data, err := chain.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != source {
+			t.Errorf("Load got %q, want %q", *data, source)
+		}
+		if got := sourceCalls.Load(); got != 1 {
+			t.Errorf("Got %d source loader calls, want 1", got)
+		}
+	})
+
+	t.Run("propagated", func(t *testing.T) {
+		for _, c := range []*stalecache.Cache[string]{l1, l2} {
+			data, err := c.Load(context.Background())
+			if err != nil {
+				t.Fatalf("Load got error: %v", err)
+			}
+			if *data != source {
+				t.Errorf("Load got %q, want %q", *data, source)
+			}
+		}
+	})
+
+	t.Run("l1", func(t *testing.T) {
+		sourceFail.Store(true)
+		data, err := chain.ForceReload(context.Background())
+		if err != nil {
+			t.Fatalf("ForceReload got error: %v", err)
+		}
+		if *data != source {
+			t.Errorf("ForceReload got %q, want %q", *data, source)
+		}
+		if got := sourceCalls.Load(); got != 1 {
+			t.Errorf("Got %d source loader calls, want 1", got)
+		}
+	})
+
+	t.Run("all-failed", func(t *testing.T) {
+		l1.Reset()
+		l2.Reset()
+		src.Reset()
+		_, err := chain.ForceReload(context.Background())
+		for _, want := range []error{l1Err, l2Err} {
+			if !errors.Is(err, want) {
+				t.Errorf("ForceReload got error %v, want %v", err, want)
+			}
+		}
+		var ce *stalecache.ChainError
+		if !errors.As(err, &ce) || ce.Level != 0 || !errors.Is(ce, l1Err) {
+			t.Errorf("errors.As got %#v, want level 0", ce)
+		}
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("ForceReload got error %v, want joined errors", err)
+		}
+		errs := joined.Unwrap()
+		if len(errs) != 3 {
+			t.Fatalf("Got %d errors, want 3: %v", len(errs), errs)
+		}
+		if !errors.As(errs[1], &ce) || ce.Level != 1 || ce.Name != "l2" || !errors.Is(ce, l2Err) {
+			t.Errorf("errors.As got %#v, want level 1 named l2", ce)
+		}
+	})
+}
+
+func TestChainEmpty(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewChain with no caches did not panic")
+		}
+	}()
+	stalecache.NewChain[int](nil)
+}
+
+func TestReplica(t *testing.T) {
+	var calls atomic.Int64
+	primary := stalecache.New(func(context.Context) (*int64, error) {
+		data := calls.Add(1)
+		return &data, nil
+	})
+	replica := stalecache.NewReplica(primary)
+
+	load := func(t *testing.T, want int64) {
+		t.Helper()
+		data, err := replica.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	load(t, 1)
+	if _, err := replica.ForceReload(context.Background()); err != nil {
+		t.Fatalf("ForceReload got error: %v", err)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Got %d loader calls after ForceReload, want 1", got)
+	}
+
+	data := int64(100)
+	if err := replica.Update(context.Background(), &data); !errors.Is(err, stalecache.ErrReadOnly) {
+		t.Errorf("Update got error %v, want %v", err, stalecache.ErrReadOnly)
+	}
+	load(t, 1)
+
+	primary.Update(context.Background(), &data)
+	load(t, 1)
+	replica.ForceReload(context.Background())
+	load(t, 100)
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Got %d loader calls, want 1", got)
+	}
+}
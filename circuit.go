@@ -0,0 +1,81 @@
+package stalecache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCircuitOpen is the error returned by Load with WithCircuitBreaker when
+// the loader is not called because the circuit is open.
+var ErrCircuitOpen error = &CacheError{Code: CodeCircuitOpen}
+
+// WithCircuitBreaker is an Option to stop calling the loader after it keeps
+// failing.
+//
+// Default threshold is 0, means no circuit breaker.
+// When threshold is positive,
+// after threshold consecutive loader failures the circuit opens,
+// and for cooldown the loader is not called at all,
+// Load returns the stale data (if any) with ErrCircuitOpen instead.
+// After cooldown the circuit goes half-open and the next reload is allowed to
+// call the loader as a probe:
+// if it succeeds the circuit closes,
+// otherwise it opens again for another cooldown.
+func WithCircuitBreaker[T any](threshold int, cooldown time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.circuitThreshold = threshold
+		o.circuitCooldown = cooldown
+	}
+}
+
+type circuitBreaker struct {
+	threshold int64
+	cooldown  time.Duration
+	now       func() time.Time
+	onOpen    func()
+
+	failures atomic.Int64
+	// openedAt is the time the circuit opened in unix nanoseconds,
+	// 0 means closed.
+	openedAt atomic.Int64
+	probing  atomic.Bool
+}
+
+// allow returns whether the loader is allowed to be called.
+func (b *circuitBreaker) allow() bool {
+	opened := b.openedAt.Load()
+	if opened == 0 {
+		return true
+	}
+	if b.now().Before(time.Unix(0, opened).Add(b.cooldown)) {
+		return false
+	}
+	// half-open, only allow one probe
+	return b.probing.CompareAndSwap(false, true)
+}
+
+// record records the result of a loader call allowed by allow.
+func (b *circuitBreaker) record(err error) {
+	if err == nil {
+		b.failures.Store(0)
+		b.openedAt.Store(0)
+		b.probing.Store(false)
+		return
+	}
+	if b.probing.CompareAndSwap(true, false) || b.failures.Add(1) >= b.threshold {
+		b.openedAt.Store(b.now().UnixNano())
+		b.onOpen()
+	}
+}
+
+func circuitBreakerLoader[T any](b *circuitBreaker, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		if !b.allow() {
+			return nil, ErrCircuitOpen
+		}
+		data, err := loader(ctx)
+		b.record(err)
+		return data, err
+	}
+}
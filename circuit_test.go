@@ -0,0 +1,79 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const (
+		ttl       = 10 * time.Millisecond
+		cooldown  = 50 * time.Millisecond
+		threshold = 3
+	)
+	wantErr := errors.New("foo")
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, wantErr
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithCircuitBreaker[int](threshold, cooldown),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	clock.Advance(ttl)
+	fail.Store(true)
+
+	check := func(t *testing.T, wantErr error, wantCalls int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if !errors.Is(err, wantErr) {
+			t.Errorf("Load got error %v, want %v", err, wantErr)
+		}
+		if data == nil {
+			t.Error("Load did not return stale data")
+		}
+		if calls := loaderCalls.Load(); calls != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", calls, wantCalls)
+		}
+	}
+
+	t.Run("closed", func(t *testing.T) {
+		for i := 0; i < threshold; i++ {
+			check(t, wantErr, int64(i+2))
+		}
+	})
+	t.Run("open", func(t *testing.T) {
+		check(t, stalecache.ErrCircuitOpen, threshold+1)
+		check(t, stalecache.ErrCircuitOpen, threshold+1)
+	})
+	t.Run("half-open-failure", func(t *testing.T) {
+		clock.Advance(cooldown)
+		check(t, wantErr, threshold+2)
+		check(t, stalecache.ErrCircuitOpen, threshold+2)
+	})
+	t.Run("half-open-success", func(t *testing.T) {
+		clock.Advance(cooldown)
+		fail.Store(false)
+		check(t, nil, threshold+3)
+		clock.Advance(ttl)
+		fail.Store(true)
+		check(t, wantErr, threshold+4)
+	})
+}
@@ -0,0 +1,33 @@
+package stalecache
+
+import (
+	"time"
+)
+
+// Clock defines the source of current time used by the cache.
+//
+// go.yhsif.com/stalecache/stalecachetest provides a fake implementation
+// for tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock is an Option to set the Clock used by the cache for the load
+// timestamps and ttl checks.
+//
+// Default is nil, means time.Now.
+// Durations measured for metrics (for example loader latencies) always use the
+// real time.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(o *opt[T]) {
+		o.clock = clock
+	}
+}
+
+// now returns the current time according to the set Clock.
+func (o *opt[T]) now() time.Time {
+	if o.clock == nil {
+		return time.Now()
+	}
+	return o.clock.Now()
+}
@@ -0,0 +1,49 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// ErrContention is the error returned by CompareAndLoad when all its attempts
+// lost to concurrent modifications.
+var ErrContention error = &CacheError{Code: CodeContention}
+
+// compareAndLoadAttempts is the max number of attempts of CompareAndLoad.
+const compareAndLoadAttempts = 16
+
+// CompareAndLoad atomically reads the current data and replaces it with the
+// one computed by fn, without external locking.
+//
+// It loads the current data the same as Load,
+// then calls fn with it and stores the result back the same as TryUpdate.
+// If the cached data is changed by others in between,
+// it retries from Load,
+// and returns ErrContention after 16 failed attempts.
+// fn could be called multiple times, so it should not have side effects,
+// and it must not modify current (see TryUpdate).
+//
+// It returns the data stored by the successful attempt,
+// copied by WithCopyFunc, if set.
+// On a read-only Cache (see NewReplica) it returns ErrReadOnly.
+func (c *Cache[T]) CompareAndLoad(ctx context.Context, fn func(current *T) *T) (*T, error) {
+	if c.opt.readOnly {
+		return nil, c.named(ErrReadOnly)
+	}
+	for i := 0; i < compareAndLoadAttempts; i++ {
+		current, _, err := c.loadShared(ctx, nil, freshness{})
+		if err != nil {
+			return nil, c.named(err)
+		}
+		data := fn(current)
+		if c.TryUpdate(current, data) {
+			if data != nil && c.opt.copyFn != nil {
+				data = c.opt.copyFn(data)
+			}
+			return data, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, c.named(&CacheError{Code: CodeCanceled, Err: err})
+		}
+	}
+	return nil, c.named(ErrContention)
+}
@@ -0,0 +1,71 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestCompareAndLoad(t *testing.T) {
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	})
+	ctx := context.Background()
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.CompareAndLoad(ctx, func(current *int) *int {
+				next := *current + 1
+				return &next
+			})
+			if err != nil && !errors.Is(err, stalecache.ErrContention) {
+				t.Errorf("CompareAndLoad returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := cache.CompareAndLoad(ctx, func(current *int) *int {
+		next := *current + 1
+		return &next
+	})
+	if err != nil {
+		t.Fatalf("CompareAndLoad returned error: %v", err)
+	}
+	if *got < 1 || *got > n+1 {
+		t.Errorf("CompareAndLoad got %d, want in [1, %d]", *got, n+1)
+	}
+	if peek, _, _ := cache.Peek(); peek != got {
+		t.Errorf("Peek got %p, want %p", peek, got)
+	}
+}
+
+func TestCompareAndLoadContention(t *testing.T) {
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	})
+	var calls int
+	_, err := cache.CompareAndLoad(context.Background(), func(current *int) *int {
+		calls++
+		// Concurrent modification on every attempt.
+		other := *current
+		cache.Update(context.Background(), &other)
+		next := *current + 1
+		return &next
+	})
+	if !errors.Is(err, stalecache.ErrContention) {
+		t.Errorf("CompareAndLoad got error %v, want %v", err, stalecache.ErrContention)
+	}
+	if calls != 16 {
+		t.Errorf("fn called %d times, want 16", calls)
+	}
+}
@@ -0,0 +1,60 @@
+package stalecache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithConcurrencyMetrics is an Option to collect metrics of the internal
+// synchronization primitives.
+//
+// Default is false.
+// When set to true, the metrics are available via ConcurrencyStats.
+// They are useful to find out whether the internal synchronization is
+// becoming a contention bottleneck under load.
+func WithConcurrencyMetrics[T any](enabled bool) Option[T] {
+	return func(o *opt[T]) {
+		o.concurrencyMetrics = enabled
+	}
+}
+
+// ConcurrencyMetrics are the metrics collected when WithConcurrencyMetrics is
+// set.
+type ConcurrencyMetrics struct {
+	// Number of times a Load failed to swap in a new entry for reloading
+	// because another goroutine already did it.
+	CASFailures uint64
+
+	// Number of times a Load had to wait for a loader call from another
+	// goroutine, and the total time spent on those waits.
+	OnceWaits    uint64
+	OnceWaitTime time.Duration
+
+	// Number of get and put calls to the internal pool.
+	PoolGets uint64
+	PoolPuts uint64
+}
+
+type concurrencyCounters struct {
+	casFailures  atomic.Uint64
+	onceWaits    atomic.Uint64
+	onceWaitTime atomic.Int64
+	poolGets     atomic.Uint64
+	poolPuts     atomic.Uint64
+}
+
+// ConcurrencyStats returns the metrics collected so far.
+//
+// It returns zero value if WithConcurrencyMetrics is not set.
+func (c *Cache[T]) ConcurrencyStats() ConcurrencyMetrics {
+	if c.concurrency == nil {
+		return ConcurrencyMetrics{}
+	}
+	return ConcurrencyMetrics{
+		CASFailures:  c.concurrency.casFailures.Load(),
+		OnceWaits:    c.concurrency.onceWaits.Load(),
+		OnceWaitTime: time.Duration(c.concurrency.onceWaitTime.Load()),
+		PoolGets:     c.concurrency.poolGets.Load(),
+		PoolPuts:     c.concurrency.poolPuts.Load(),
+	}
+}
@@ -0,0 +1,54 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestConcurrencyMetrics(t *testing.T) {
+	const (
+		sleep = 10 * time.Millisecond
+		n     = 5
+	)
+	loader := func(context.Context) (*int, error) {
+		time.Sleep(sleep)
+		var data int
+		return &data, nil
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cache := stalecache.New(loader)
+		cache.Load(context.Background())
+		if got := cache.ConcurrencyStats(); got != (stalecache.ConcurrencyMetrics{}) {
+			t.Errorf("ConcurrencyStats got %+v, want zero value", got)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cache := stalecache.New(loader, stalecache.WithConcurrencyMetrics[int](true))
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cache.Load(context.Background())
+			}()
+		}
+		wg.Wait()
+		got := cache.ConcurrencyStats()
+		t.Logf("ConcurrencyStats: %+v", got)
+		if got.PoolGets != 1 {
+			t.Errorf("PoolGets got %d, want 1", got.PoolGets)
+		}
+		if got.OnceWaits > n-1 {
+			t.Errorf("OnceWaits got %d, want <= %d", got.OnceWaits, n-1)
+		}
+		if got.OnceWaits > 0 && got.OnceWaitTime <= 0 {
+			t.Errorf("OnceWaitTime got %v with %d waits", got.OnceWaitTime, got.OnceWaits)
+		}
+	})
+}
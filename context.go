@@ -0,0 +1,38 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// contextCacheKey is the context key used by WithCache,
+// one per type T.
+type contextCacheKey[T any] struct{}
+
+// WithCache returns a ctx carrying c,
+// to be used by CacheFromContext and LoadFromContext.
+//
+// It's useful for middlewares to inject a per-request (or per-tenant) Cache,
+// or for tests to inject a test Cache,
+// without passing it through the whole call stack.
+// Different types of caches can be carried by the same ctx independently.
+//
+// Note that unlike most other With functions in this package,
+// it's not an Option.
+func WithCache[T any](ctx context.Context, c *Cache[T]) context.Context {
+	return context.WithValue(ctx, contextCacheKey[T]{}, c)
+}
+
+// CacheFromContext returns the Cache of type T carried by ctx from WithCache.
+func CacheFromContext[T any](ctx context.Context) (*Cache[T], bool) {
+	c, _ := ctx.Value(contextCacheKey[T]{}).(*Cache[T])
+	return c, c != nil
+}
+
+// LoadFromContext calls Load on the Cache carried by ctx from WithCache,
+// or fallback if ctx doesn't carry one.
+func LoadFromContext[T any](ctx context.Context, fallback *Cache[T]) (*T, error) {
+	if c, ok := CacheFromContext[T](ctx); ok {
+		return c.Load(ctx)
+	}
+	return fallback.Load(ctx)
+}
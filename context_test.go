@@ -0,0 +1,37 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestCacheFromContext(t *testing.T) {
+	newCache := func(value int) *stalecache.Cache[int] {
+		return stalecache.New(func(context.Context) (*int, error) {
+			return &value, nil
+		})
+	}
+	global := newCache(1)
+	override := newCache(2)
+
+	ctx := context.Background()
+	if c, ok := stalecache.CacheFromContext[int](ctx); ok {
+		t.Errorf("CacheFromContext got %p from empty ctx", c)
+	}
+	if data, err := stalecache.LoadFromContext(ctx, global); err != nil || *data != 1 {
+		t.Errorf("LoadFromContext got %v, %v, want 1", data, err)
+	}
+
+	ctx = stalecache.WithCache(ctx, override)
+	if c, ok := stalecache.CacheFromContext[int](ctx); !ok || c != override {
+		t.Errorf("CacheFromContext got %p, %v, want %p, true", c, ok, override)
+	}
+	if c, ok := stalecache.CacheFromContext[string](ctx); ok {
+		t.Errorf("CacheFromContext[string] got %p", c)
+	}
+	if data, err := stalecache.LoadFromContext(ctx, global); err != nil || *data != 2 {
+		t.Errorf("LoadFromContext got %v, %v, want 2", data, err)
+	}
+}
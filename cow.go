@@ -0,0 +1,63 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// Cloner can be implemented by *T to be used by COW.Clone instead of
+// ShallowCopy, for example when T has slices or maps.
+type Cloner[T any] interface {
+	Clone() *T
+}
+
+// WithCOW is an Option to use copy-on-write semantics for the cached data.
+//
+// Default is false, means callers either must not modify the data returned by
+// Load, or use WithCopyFunc to copy it on every Load.
+// When set, Load and its variants still return the cached pointer shared by
+// all callers without copying,
+// and callers that need to modify the data use LoadCOW and COW.Clone instead,
+// so only them pay for the copy.
+//
+// It's mutually exclusive with WithCopyFunc,
+// NewE returns an error when both are set.
+func WithCOW[T any]() Option[T] {
+	return func(o *opt[T]) {
+		o.cow = true
+	}
+}
+
+// COW is the result of LoadCOW.
+type COW[T any] struct {
+	ptr *T
+}
+
+// LoadCOW is the same as Load, but returns the result as a COW.
+//
+// It's meant to be used with WithCOW.
+func (c *Cache[T]) LoadCOW(ctx context.Context) (COW[T], error) {
+	ptr, err := c.Load(ctx)
+	return COW[T]{ptr: ptr}, err
+}
+
+// Get returns the cached data, which could be nil.
+//
+// It's shared by all callers and must not be modified, use Clone instead.
+func (v COW[T]) Get() *T {
+	return v.ptr
+}
+
+// Clone returns a copy of the cached data that can be modified freely,
+// or nil if the cached data is nil.
+//
+// It uses the Clone method if *T implements Cloner,
+// otherwise ShallowCopy.
+func (v COW[T]) Clone() *T {
+	if v.ptr == nil {
+		return nil
+	}
+	if cloner, ok := any(v.ptr).(Cloner[T]); ok {
+		return cloner.Clone()
+	}
+	return ShallowCopy(v.ptr)
+}
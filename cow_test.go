@@ -0,0 +1,79 @@
+package stalecache_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+type cowData struct {
+	N     int
+	Items []string
+}
+
+func (d *cowData) Clone() *cowData {
+	return &cowData{N: d.N, Items: slices.Clone(d.Items)}
+}
+
+func TestCOW(t *testing.T) {
+	cache := stalecache.New(
+		func(context.Context) (*cowData, error) {
+			return &cowData{N: 1, Items: []string{"a"}}, nil
+		},
+		stalecache.WithCOW[cowData](),
+	)
+	ctx := context.Background()
+
+	v, err := cache.LoadCOW(ctx)
+	if err != nil {
+		t.Fatalf("LoadCOW returned error: %v", err)
+	}
+	shared, err := cache.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if v.Get() != shared {
+		t.Errorf("Get got %p, want shared %p", v.Get(), shared)
+	}
+
+	clone := v.Clone()
+	if clone == shared {
+		t.Fatal("Clone returned the shared pointer")
+	}
+	clone.N = 2
+	clone.Items[0] = "b"
+	if got, _ := cache.Load(ctx); got.N != 1 || got.Items[0] != "a" {
+		t.Errorf("Cached data modified by clone: %+v", got)
+	}
+}
+
+func TestCOWShallowCopy(t *testing.T) {
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			data := 1
+			return &data, nil
+		},
+		stalecache.WithCOW[int](),
+	)
+	v, err := cache.LoadCOW(context.Background())
+	if err != nil {
+		t.Fatalf("LoadCOW returned error: %v", err)
+	}
+	clone := v.Clone()
+	*clone = 2
+	if got := *v.Get(); got != 1 {
+		t.Errorf("Cached data got %d, want 1", got)
+	}
+}
+
+func TestCOWCopyFuncConflict(t *testing.T) {
+	err := stalecache.ValidateOptions(
+		stalecache.WithCOW[int](),
+		stalecache.WithCopyFunc(stalecache.ShallowCopy[int]),
+	)
+	if err == nil {
+		t.Error("Expected error for WithCOW with WithCopyFunc")
+	}
+}
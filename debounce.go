@@ -0,0 +1,69 @@
+package stalecache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithDebounce is an Option to coalesce rapid Update calls.
+//
+// Default is 0, means every Update updates the cache immediately.
+// When set, Update does not update the cache right away,
+// instead the value is kept as pending until there's no new Update for
+// window, then the last pending value is stored into the cache
+// (and published to WithPubSub with the ctx from WithContextFunc).
+// Load calls during the window still return the value before the pending
+// Updates.
+//
+// It only affects Update.
+// The writer set by WithWriteBack is still called by every Update,
+// before the value becomes pending.
+func WithDebounce[T any](window time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.debounce = window
+	}
+}
+
+type debouncer[T any] struct {
+	mu      sync.Mutex
+	timer   *time.Timer
+	pending *T
+	ctx     context.Context
+	// gen is increased by every debounced Update,
+	// so a timer already fired but replaced is ignored.
+	gen uint64
+}
+
+func (c *Cache[T]) debounceUpdate(ctx context.Context, data *T) {
+	d := &c.debounced
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	gen := d.gen
+	d.pending = data
+	d.ctx = ctx
+	d.timer = time.AfterFunc(c.opt.debounce, func() {
+		c.flushDebounced(gen)
+	})
+}
+
+func (c *Cache[T]) flushDebounced(gen uint64) {
+	d := &c.debounced
+	d.mu.Lock()
+	if gen != d.gen || d.pending == nil {
+		d.mu.Unlock()
+		return
+	}
+	data, ctx := d.pending, d.ctx
+	d.pending, d.ctx, d.timer = nil, nil, nil
+	d.mu.Unlock()
+
+	if c.update(data) {
+		c.publish(c.opt.backgroundContext(ctx), data)
+	}
+}
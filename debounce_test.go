@@ -0,0 +1,52 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestDebounce(t *testing.T) {
+	const window = 20 * time.Millisecond
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			var data int
+			return &data, nil
+		},
+		stalecache.WithDebounce[int](window),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	updates := cache.Subscribe(context.Background())
+
+	for i := 1; i <= 5; i++ {
+		data := i
+		if err := cache.Update(context.Background(), &data); err != nil {
+			t.Fatalf("Update #%d got error: %v", i, err)
+		}
+		if data, _ := cache.Load(context.Background()); *data != 0 {
+			t.Errorf("Load during debounce window got %d, want 0", *data)
+		}
+		time.Sleep(window / 4)
+	}
+
+	select {
+	case data := <-updates:
+		if *data != 5 {
+			t.Errorf("Got update %d, want 5", *data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Debounced update not stored")
+	}
+	if data, _ := cache.Load(context.Background()); *data != 5 {
+		t.Errorf("Load after debounce window got %d, want 5", *data)
+	}
+	select {
+	case data := <-updates:
+		t.Errorf("Got unexpected update %d", *data)
+	case <-time.After(2 * window):
+	}
+}
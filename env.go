@@ -0,0 +1,69 @@
+package stalecache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewFromEnv is NewE with some of the options read from environment variables.
+//
+// The environment variables supported are (with prefix "CACHE" as an example):
+//
+//   - CACHE_TTL for WithTTL
+//   - CACHE_ERROR_TTL for WithErrorTTL
+//   - CACHE_MAX_STALE for WithMaxStale
+//
+// Their values are parsed by time.ParseDuration,
+// and they are applied before options,
+// so the ones explicitly passed in options take precedence.
+//
+// It returns an error if any of them fails to parse,
+// or there's an unknown environment variable starting with prefix and "_",
+// to catch typos.
+func NewFromEnv[T any](loader Loader[T], prefix string, options ...Option[T]) (*Cache[T], error) {
+	envOptions, err := optionsFromEnv[T](prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewE(loader, append(envOptions, options...)...)
+}
+
+func optionsFromEnv[T any](prefix string) ([]Option[T], error) {
+	prefix += "_"
+	supported := []struct {
+		name   string
+		option func(time.Duration) Option[T]
+	}{
+		{"TTL", WithTTL[T]},
+		{"ERROR_TTL", WithErrorTTL[T]},
+		{"MAX_STALE", WithMaxStale[T]},
+	}
+
+	var errs []error
+	var options []Option[T]
+	known := make(map[string]bool, len(supported))
+	for _, s := range supported {
+		name := prefix + s.name
+		known[name] = true
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stalecache: invalid environment variable %s: %w", name, err))
+			continue
+		}
+		options = append(options, s.option(d))
+	}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, prefix) && !known[name] {
+			errs = append(errs, fmt.Errorf("stalecache: unknown environment variable %s", name))
+		}
+	}
+	return options, errors.Join(errs...)
+}
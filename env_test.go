@@ -0,0 +1,70 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	const prefix = "STALECACHE_TEST"
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls int
+	loader := func(context.Context) (*int, error) {
+		loaderCalls++
+		return &loaderCalls, nil
+	}
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv(prefix+"_TTL", "1m")
+		t.Setenv(prefix+"_ERROR_TTL", "1s")
+		loaderCalls = 0
+		cache, err := stalecache.NewFromEnv(loader, prefix, stalecache.WithClock[int](clock))
+		if err != nil {
+			t.Fatalf("NewFromEnv got error: %v", err)
+		}
+		cache.Load(context.Background())
+		clock.Advance(30 * time.Second)
+		cache.Load(context.Background())
+		if loaderCalls != 1 {
+			t.Errorf("Got %d loader calls within ttl from env, want 1", loaderCalls)
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		t.Setenv(prefix+"_TTL", "1m")
+		loaderCalls = 0
+		cache, err := stalecache.NewFromEnv(
+			loader,
+			prefix,
+			stalecache.WithClock[int](clock),
+			stalecache.WithTTL[int](time.Second),
+		)
+		if err != nil {
+			t.Fatalf("NewFromEnv got error: %v", err)
+		}
+		cache.Load(context.Background())
+		clock.Advance(30 * time.Second)
+		cache.Load(context.Background())
+		if loaderCalls != 2 {
+			t.Errorf("Got %d loader calls after overridden ttl, want 2", loaderCalls)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Setenv(prefix+"_MAX_STALE", "foo")
+		if _, err := stalecache.NewFromEnv(loader, prefix); err == nil {
+			t.Error("NewFromEnv with invalid duration got no error")
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Setenv(prefix+"_TLL", "1m")
+		if _, err := stalecache.NewFromEnv(loader, prefix); err == nil {
+			t.Error("NewFromEnv with unknown environment variable got no error")
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package stalecache
+
+import (
+	"fmt"
+)
+
+// Code is the category of a CacheError.
+type Code int
+
+// Code values.
+const (
+	// The loader is not called because the circuit is open,
+	// see WithCircuitBreaker.
+	CodeCircuitOpen Code = iota + 1
+	// The loader failed and the stale data is too old to be returned,
+	// see WithMaxStale.
+	CodeMaxStaleExceeded
+	// The loader timed out, see WithLoadTimeout and WithMutualExclusionTimeout.
+	CodeTimeout
+	// The ctx is canceled while waiting for the loader.
+	CodeCanceled
+	// The first load has not finished yet, see WithAsyncLoad.
+	CodeNotYetLoaded
+	// Load failed, only used by the panics from MustLoad and Result.Must.
+	CodeLoadFailed
+	// The Cache has no loader, see CacheOf.
+	CodeNoLoader
+	// The loader is skipped and there's no stale data, see WithSkipCondition.
+	CodeSkipped
+	// The Cache is read-only, see NewReplica.
+	CodeReadOnly
+	// Concurrent modifications keep winning, see CompareAndLoad.
+	CodeContention
+	// The Cache has no codec to persist the value, see WithCodec.
+	CodeNoCodec
+)
+
+var codeNames = map[Code]string{
+	CodeCircuitOpen:      "circuit open",
+	CodeMaxStaleExceeded: "max stale exceeded",
+	CodeTimeout:          "timeout",
+	CodeCanceled:         "canceled",
+	CodeNotYetLoaded:     "not yet loaded",
+	CodeLoadFailed:       "load failed",
+	CodeNoLoader:         "no loader",
+	CodeSkipped:          "skipped",
+	CodeReadOnly:         "read-only",
+	CodeContention:       "contention",
+	CodeNoCodec:          "no codec",
+}
+
+func (c Code) String() string {
+	if name, ok := codeNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// CacheError is the error caused by the mechanisms of this package,
+// instead of returned by the loader directly.
+//
+// Use errors.As to tell them apart from the loader errors.
+// The wrapped error is still available to errors.Is,
+// for example a CacheError with CodeMaxStaleExceeded wraps the error returned
+// by the loader,
+// and a CacheError with CodeCanceled wraps the ctx error.
+//
+// When the Cache has a name set by WithName,
+// the CacheError returned by it also has the name.
+type CacheError struct {
+	Code Code
+	Name string
+	Err  error
+
+	// orig is the CacheError this one is copied from to add Name,
+	// so errors.Is still matches the sentinel errors like ErrCircuitOpen.
+	orig *CacheError
+}
+
+func (e *CacheError) Error() string {
+	prefix := "stalecache"
+	if e.Name != "" {
+		prefix = fmt.Sprintf("stalecache %q", e.Name)
+	}
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %v", prefix, e.Code)
+	}
+	return fmt.Sprintf("%s: %v: %v", prefix, e.Code, e.Err)
+}
+
+func (e *CacheError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the CacheError e is copied from to add Name.
+func (e *CacheError) Is(target error) bool {
+	return e.orig != nil && target == error(e.orig)
+}
+
+// named adds the name set by WithName to err if it's a CacheError without a
+// name.
+func (c *Cache[T]) named(err error) error {
+	if c.opt.name == "" {
+		return err
+	}
+	ce, ok := err.(*CacheError)
+	if !ok || ce.Name != "" {
+		return err
+	}
+	orig := ce
+	if ce.orig != nil {
+		orig = ce.orig
+	}
+	return &CacheError{
+		Code: ce.Code,
+		Name: c.opt.name,
+		Err:  ce.Err,
+		orig: orig,
+	}
+}
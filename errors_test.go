@@ -0,0 +1,116 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestCacheError(t *testing.T) {
+	const (
+		ttl     = 10 * time.Millisecond
+		timeout = 5 * time.Millisecond
+	)
+	wantErr := errors.New("foo")
+	failing := func(context.Context) (*int, error) {
+		return nil, wantErr
+	}
+	blocking := func(ctx context.Context) (*int, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	for _, c := range []struct {
+		label    string
+		run      func(t *testing.T) error
+		wantCode stalecache.Code
+		wantIs   error
+	}{
+		{
+			label: "loader",
+			run: func(t *testing.T) error {
+				_, err := stalecache.New(failing).Load(context.Background())
+				return err
+			},
+			wantIs: wantErr,
+		},
+		{
+			label: "circuit-open",
+			run: func(t *testing.T) error {
+				cache := stalecache.New(failing, stalecache.WithCircuitBreaker[int](1, time.Hour))
+				_, err := cache.Load(context.Background())
+				return err
+			},
+			wantCode: stalecache.CodeCircuitOpen,
+			wantIs:   stalecache.ErrCircuitOpen,
+		},
+		{
+			label: "max-stale-exceeded",
+			run: func(t *testing.T) error {
+				clock := stalecachetest.NewFakeClock(time.Now())
+				cache := stalecache.New(
+					failing,
+					stalecache.WithTTL[int](ttl),
+					stalecache.WithMaxStale[int](ttl),
+					stalecache.WithClock[int](clock),
+				)
+				var data int
+				cache.Update(context.Background(), &data)
+				clock.Advance(ttl)
+				_, err := cache.Load(context.Background())
+				return err
+			},
+			wantCode: stalecache.CodeMaxStaleExceeded,
+			wantIs:   wantErr,
+		},
+		{
+			label: "timeout",
+			run: func(t *testing.T) error {
+				cache := stalecache.New(blocking, stalecache.WithLoadTimeout[int](timeout))
+				_, err := cache.ForceReload(context.Background())
+				return err
+			},
+			wantCode: stalecache.CodeTimeout,
+			wantIs:   context.DeadlineExceeded,
+		},
+		{
+			label: "canceled",
+			run: func(t *testing.T) error {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				_, err := stalecache.New(blocking).Load(ctx)
+				return err
+			},
+			wantCode: stalecache.CodeCanceled,
+			wantIs:   context.DeadlineExceeded,
+		},
+		{
+			label: "not-yet-loaded",
+			run: func(t *testing.T) error {
+				cache := stalecache.New(failing, stalecache.WithAsyncLoad[int](true))
+				_, err := cache.Load(context.Background())
+				return err
+			},
+			wantCode: stalecache.CodeNotYetLoaded,
+			wantIs:   stalecache.ErrNotYetLoaded,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := c.run(t)
+			if !errors.Is(err, c.wantIs) {
+				t.Errorf("Got error %v, want %v", err, c.wantIs)
+			}
+			var ce *stalecache.CacheError
+			if ok := errors.As(err, &ce); ok != (c.wantCode != 0) {
+				t.Fatalf("errors.As(%v) got %v", err, ok)
+			}
+			if ce != nil && ce.Code != c.wantCode {
+				t.Errorf("Got code %v, want %v", ce.Code, c.wantCode)
+			}
+		})
+	}
+}
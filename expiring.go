@@ -0,0 +1,45 @@
+package stalecache
+
+import (
+	"context"
+	"time"
+)
+
+// ExpiringValue is a value with its own expiry,
+// for example an API token with its expires_at.
+type ExpiringValue[T any] struct {
+	Value     *T
+	ExpiresAt time.Time
+}
+
+// ExpiringLoader defines the callback to load value with its own expiry from
+// external source.
+type ExpiringLoader[T any] func(context.Context) (*ExpiringValue[T], error)
+
+// expiresAtKey is the context key to pass the expiry from the loader of
+// NewExpiring back to fill.
+type expiresAtKey struct{}
+
+// NewExpiring creates a new Cache with loader returning values with their own
+// expiry.
+//
+// Every value loaded is fresh until its ExpiresAt,
+// overriding the ttl related options (WithTTL, WithDynamicTTL, etc.).
+// ExpiresAt not after the time the loader returned means the value is stale
+// immediately.
+// Zero ExpiresAt (including nil ExpiringValue) and values set by Update (and
+// its variants) fallback to the ttl related options.
+func NewExpiring[T any](loader ExpiringLoader[T], options ...Option[T]) *Cache[T] {
+	return New(func(ctx context.Context) (*T, error) {
+		v, err := loader(ctx)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		if expiresAt, ok := ctx.Value(expiresAtKey{}).(*time.Time); ok {
+			*expiresAt = v.ExpiresAt
+		}
+		return v.Value, nil
+	}, append(options[:len(options):len(options)], func(o *opt[T]) {
+		o.expiring = true
+	})...)
+}
@@ -0,0 +1,65 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestNewExpiring(t *testing.T) {
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls int
+	var expiresIn time.Duration
+	cache := stalecache.NewExpiring(
+		func(context.Context) (*stalecache.ExpiringValue[int], error) {
+			calls++
+			data := calls
+			v := &stalecache.ExpiringValue[int]{Value: &data}
+			if expiresIn != 0 {
+				v.ExpiresAt = clock.Now().Add(expiresIn)
+			}
+			return v, nil
+		},
+		stalecache.WithTTL[int](time.Hour),
+		stalecache.WithClock[int](clock),
+	)
+	ctx := context.Background()
+
+	load := func(t *testing.T, want int) {
+		t.Helper()
+		got, err := cache.Load(ctx)
+		if err != nil {
+			t.Fatalf("Load returned error: %v", err)
+		}
+		if *got != want {
+			t.Errorf("Load got %d, want %d", *got, want)
+		}
+	}
+
+	expiresIn = time.Minute
+	load(t, 1)
+	clock.Advance(time.Minute - time.Second)
+	load(t, 1)
+	if ttl := cache.PeekRemainingTTL(); ttl != time.Second {
+		t.Errorf("PeekRemainingTTL got %v, want %v", ttl, time.Second)
+	}
+	clock.Advance(time.Second)
+	load(t, 2)
+
+	// Already expired means stale immediately.
+	expiresIn = -time.Second
+	clock.Advance(time.Minute)
+	load(t, 3)
+	load(t, 4)
+
+	// Zero ExpiresAt fallbacks to WithTTL.
+	expiresIn = 0
+	load(t, 5)
+	clock.Advance(time.Hour - time.Second)
+	load(t, 5)
+	clock.Advance(time.Second)
+	load(t, 6)
+}
@@ -1,3 +1,8 @@
 module go.yhsif.com/stalecache
 
-go 1.19
+go 1.21
+
+require (
+	golang.org/x/sync v0.11.0
+	golang.org/x/time v0.9.0
+)
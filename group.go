@@ -0,0 +1,172 @@
+package stalecache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// resetter is satisfied by *Cache[T] of any T.
+type resetter interface {
+	Reset()
+}
+
+// CacheGroup is a group of caches (could be of different types) to be
+// invalidated together.
+//
+// The zero value is an empty group ready to use.
+// It's safe for concurrent use.
+type CacheGroup struct {
+	mu     sync.Mutex
+	caches []resetter
+}
+
+// Add adds a cache (for example, *Cache[T]) to the group.
+//
+// It returns g so calls can be chained.
+func (g *CacheGroup) Add(c resetter) *CacheGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.caches = append(g.caches, c)
+	return g
+}
+
+// InvalidateAll calls Reset on every cache in the group.
+func (g *CacheGroup) InvalidateAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, c := range g.caches {
+		c.Reset()
+	}
+}
+
+// Fetcher is a member of SyncGroup.
+//
+// It's implemented by *Cache[T] of any T,
+// and can't be implemented outside of this package.
+type Fetcher interface {
+	// fetch calls the loader without storing the loaded data,
+	// and returns the function to store it.
+	fetch(ctx context.Context) (commit func(), err error)
+}
+
+// SyncGroup is a group of caches (could be of different types) to be
+// refreshed together,
+// so they either all have the newly loaded data or all keep the old data.
+//
+// It's safe for concurrent use.
+type SyncGroup struct {
+	caches []Fetcher
+}
+
+// NewSyncGroup creates a SyncGroup of caches (*Cache[T] of any T).
+func NewSyncGroup(caches ...Fetcher) *SyncGroup {
+	return &SyncGroup{
+		caches: caches,
+	}
+}
+
+// RefreshAll refreshes all the caches in the group in two phases.
+//
+// In the first phase, the loaders of all the caches are called concurrently,
+// and the loaded data is held by RefreshAll instead of stored in the caches.
+// The loader calls are the same as the ones from Load (with WithPartialUpdate,
+// WithSizeLimit, etc.),
+// and they never overlap with the other loader calls of the same cache:
+// an in-flight load is waited for before the one of RefreshAll starts,
+// and the Load calls found the cache stale in the meantime wait for the one
+// of RefreshAll before starting their own.
+// If any of the loaders failed,
+// RefreshAll returns the errors from all the failed loaders joined by
+// errors.Join, and none of the caches are changed,
+// so there's nothing to roll back.
+//
+// Otherwise in the second phase,
+// the loaded data is stored into all the caches the same as Update,
+// except that WithWriteBack is not used as the data is from the source
+// already.
+// The second phase never fails,
+// but it's not a transaction for the readers:
+// a Load concurrent with the second phase could see some of the caches
+// updated and some not yet,
+// and the caches can still be reloaded individually by their own Load calls.
+func (g *SyncGroup) RefreshAll(ctx context.Context) error {
+	commits := make([]func(), len(g.caches))
+	errs := make([]error, len(g.caches))
+	var wg sync.WaitGroup
+	for i, c := range g.caches {
+		wg.Add(1)
+		go func(i int, c Fetcher) {
+			defer wg.Done()
+			commits[i], errs[i] = c.fetch(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		commit()
+	}
+	return nil
+}
+
+func (c *Cache[T]) fetch(ctx context.Context) (commit func(), err error) {
+	for {
+		curr := c.current()
+		if !curr.done.Load() {
+			// wait for the in-flight load before starting a new one.
+			if !c.await(ctx, curr) {
+				return nil, canceledError(ctx)
+			}
+			continue
+		}
+		if next := curr.next.Load(); next != nil {
+			// wait for the in-flight background refresh, or the other
+			// RefreshAll.
+			if !c.await(ctx, next) {
+				return nil, canceledError(ctx)
+			}
+			if next.held || next.err != nil {
+				curr.next.CompareAndSwap(next, nil)
+			}
+			continue
+		}
+
+		next := c.poolGet()
+		next.prev.Store(curr.lastGood())
+		next.held = true
+		if !curr.next.CompareAndSwap(nil, next) {
+			c.poolPut(next)
+			continue
+		}
+		filled := c.await(ctx, next)
+		curr.next.CompareAndSwap(next, nil)
+		if !filled {
+			return nil, canceledError(ctx)
+		}
+		if next.err != nil {
+			return nil, next.err
+		}
+		return func() {
+			c.storeFetched(ctx, next)
+		}, nil
+	}
+}
+
+// storeFetched stores the data of the entry d loaded by fetch,
+// the same as Update.
+func (c *Cache[T]) storeFetched(ctx context.Context, d *cached[T]) {
+	curr := c.current()
+	if c.unchanged(curr, d.data) {
+		return
+	}
+	// d is linked to curr, use a new entry instead.
+	entry := new(cached[T])
+	entry.version = c.version.Add(1)
+	entry.ttl = d.ttl
+	entry.expiresAt = d.expiresAt
+	entry.loadDuration = d.loadDuration
+	entry.set(d.data, d.loaded, nil)
+	c.committed(ctx, entry, c.cached.Swap(entry))
+}
@@ -0,0 +1,140 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestCacheGroup(t *testing.T) {
+	var intCalls, stringCalls atomic.Int64
+	ints := stalecache.New(func(context.Context) (*int, error) {
+		intCalls.Add(1)
+		var data int
+		return &data, nil
+	})
+	strings := stalecache.New(func(context.Context) (*string, error) {
+		stringCalls.Add(1)
+		var data string
+		return &data, nil
+	})
+	var group stalecache.CacheGroup
+	group.Add(ints).Add(strings)
+
+	load := func() {
+		ints.Load(context.Background())
+		strings.Load(context.Background())
+	}
+	check := func(t *testing.T, want int64) {
+		t.Helper()
+		if got := intCalls.Load(); got != want {
+			t.Errorf("Got %d int loader calls, want %d", got, want)
+		}
+		if got := stringCalls.Load(); got != want {
+			t.Errorf("Got %d string loader calls, want %d", got, want)
+		}
+	}
+
+	load()
+	load()
+	check(t, 1)
+	group.InvalidateAll()
+	load()
+	check(t, 2)
+}
+
+func TestSyncGroup(t *testing.T) {
+	var version atomic.Int64
+	var fail atomic.Bool
+	ints := stalecache.New(func(context.Context) (*int64, error) {
+		data := version.Load()
+		return &data, nil
+	})
+	strings := stalecache.New(func(context.Context) (*string, error) {
+		if fail.Load() {
+			return nil, errors.New("foo")
+		}
+		data := fmt.Sprintf("v%d", version.Load())
+		return &data, nil
+	})
+	group := stalecache.NewSyncGroup(ints, strings)
+
+	check := func(t *testing.T, wantInt int64, wantString string) {
+		t.Helper()
+		if data, _, _ := ints.Peek(); data == nil || *data != wantInt {
+			t.Errorf("Got int %v, want %d", data, wantInt)
+		}
+		if data, _, _ := strings.Peek(); data == nil || *data != wantString {
+			t.Errorf("Got string %v, want %q", data, wantString)
+		}
+	}
+
+	version.Store(1)
+	if err := group.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("RefreshAll got error: %v", err)
+	}
+	check(t, 1, "v1")
+
+	version.Store(2)
+	fail.Store(true)
+	if err := group.RefreshAll(context.Background()); err == nil {
+		t.Error("RefreshAll got nil error, want error")
+	}
+	check(t, 1, "v1")
+
+	fail.Store(false)
+	if err := group.RefreshAll(context.Background()); err != nil {
+		t.Fatalf("RefreshAll got error: %v", err)
+	}
+	check(t, 2, "v2")
+}
+
+func TestSyncGroupNoOverlap(t *testing.T) {
+	const sleep = 10 * time.Millisecond
+	var concurrent, loaderCalls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			defer concurrent.Add(-1)
+			if n := concurrent.Add(1); n != 1 {
+				t.Errorf("Got %d concurrent loader calls, want 1", n)
+			}
+			time.Sleep(sleep)
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](time.Nanosecond),
+	)
+	group := stalecache.NewSyncGroup(cache)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Errorf("Load got error: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := group.RefreshAll(context.Background()); err != nil {
+				t.Errorf("RefreshAll got error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, version, err := cache.LoadWithVersion(context.Background())
+	if err != nil {
+		t.Fatalf("LoadWithVersion got error: %v", err)
+	}
+	if version == 0 {
+		t.Error("Got version 0 after RefreshAll")
+	}
+}
@@ -0,0 +1,173 @@
+// Package grpc provides a gRPC client interceptor caching unary RPC responses
+// with stalecache.
+//
+// It's a separate module so the core stalecache module does not depend on
+// gRPC.
+package grpc // import "go.yhsif.com/stalecache/grpc"
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"go.yhsif.com/stalecache"
+)
+
+// TTLField is the conventional name of the response field holding the ttl in
+// seconds, to be used with WithTTLField.
+const TTLField = "cache_ttl_seconds"
+
+// invocation is the RPC call passed into the loader via ctx.
+type invocation struct {
+	method  string
+	req     any
+	cc      *grpc.ClientConn
+	invoker grpc.UnaryInvoker
+	opts    []grpc.CallOption
+}
+
+// invocationKey is the context key to pass the invocation into the loader.
+type invocationKey struct{}
+
+// ErrNoInvocation is the error of the loader calls without the RPC call from
+// the interceptor in their ctx.
+//
+// It happens when the loader is called in background (for example by
+// WithBackgroundRefresh, WithAsyncLoad, or WithRateLimit) with the default
+// WithContextFunc of NewUnaryClientInterceptor overridden by one not keeping
+// the values.
+// The stale response is still returned when there's one.
+var ErrNoInvocation = errors.New("stalecache/grpc: no RPC call in the ctx of the loader")
+
+// NewUnaryClientInterceptor creates a grpc.UnaryClientInterceptor caching the
+// responses of the RPCs with request type Req and response type Resp.
+//
+// Req is the type of the request as passed into the RPC (for example
+// *pb.GetUserRequest),
+// and Resp is the message type of the response (for example pb.User, not
+// *pb.User).
+// RPCs with other request or response types are not cached.
+//
+// The responses are cached by the RPC method and keyFn(req),
+// so keyFn only needs to tell the requests of the same method apart,
+// with at most capacity keys (which must be positive) cached,
+// the least recently used key is evicted when there are more,
+// and the options are applied to the cache of each key,
+// after the WithContextFunc using context.WithoutCancel,
+// so the loader calls in background still have the RPC call to make.
+// Cache misses call through to the actual RPC,
+// and when a reload fails the stale response is returned if there's one.
+// Cached responses are copied into the reply of every RPC,
+// with proto.Merge for protobuf messages.
+// Other responses are only copied shallowly,
+// so their pointer, slice and map fields are shared with the cached response
+// and must not be modified,
+// unless stalecache.WithCopyFunc is used to deep copy them.
+//
+// Same as stalecache.NewLRUMap,
+// it panics with the Options not supported by stalecache.Map,
+// for example stalecache.WithPubSub.
+func NewUnaryClientInterceptor[Req, Resp any](capacity int, keyFn func(Req) string, opts ...stalecache.Option[Resp]) grpc.UnaryClientInterceptor {
+	cache := stalecache.NewLRUMap(
+		capacity,
+		func(ctx context.Context, _ string) (*Resp, error) {
+			inv, ok := ctx.Value(invocationKey{}).(*invocation)
+			if !ok {
+				return nil, ErrNoInvocation
+			}
+			resp := new(Resp)
+			if err := inv.invoker(ctx, inv.method, inv.req, resp, inv.cc, inv.opts...); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		},
+		append([]stalecache.Option[Resp]{
+			stalecache.WithContextFunc[Resp](context.WithoutCancel),
+		}, opts...)...,
+	)
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		callOpts ...grpc.CallOption,
+	) error {
+		r, ok := req.(Req)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		dst, ok := reply.(*Resp)
+		if !ok {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		ctx = context.WithValue(ctx, invocationKey{}, &invocation{
+			method:  method,
+			req:     req,
+			cc:      cc,
+			invoker: invoker,
+			opts:    callOpts,
+		})
+		data, err := cache.Load(ctx, method+"\x00"+keyFn(r))
+		if data == nil {
+			return err
+		}
+		// data is the stale response when err is not nil.
+		copyResp(dst, data)
+		return nil
+	}
+}
+
+// copyResp copies src into dst,
+// which is a shallow copy unless Resp is a protobuf message.
+func copyResp[Resp any](dst, src *Resp) {
+	if m, ok := any(dst).(proto.Message); ok {
+		proto.Reset(m)
+		proto.Merge(m, any(src).(proto.Message))
+		return
+	}
+	*dst = *src
+}
+
+// WithTTLField is an Option to use the numeric field name (for example
+// TTLField) of the protobuf response as its ttl in seconds,
+// via stalecache.WithDynamicTTL.
+//
+// Responses without such a field (or not protobuf messages) have zero ttl,
+// which means they are stale immediately.
+func WithTTLField[Resp any](name string) stalecache.Option[Resp] {
+	return stalecache.WithDynamicTTL(func(resp *Resp) time.Duration {
+		m, ok := any(resp).(proto.Message)
+		if !ok {
+			return 0
+		}
+		msg := m.ProtoReflect()
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil || fd.IsList() || fd.IsMap() {
+			return 0
+		}
+		v := msg.Get(fd)
+		switch fd.Kind() {
+		case protoreflect.Int32Kind,
+			protoreflect.Int64Kind,
+			protoreflect.Sint32Kind,
+			protoreflect.Sint64Kind,
+			protoreflect.Sfixed32Kind,
+			protoreflect.Sfixed64Kind:
+			return time.Duration(v.Int()) * time.Second
+		case protoreflect.Uint32Kind,
+			protoreflect.Uint64Kind,
+			protoreflect.Fixed32Kind,
+			protoreflect.Fixed64Kind:
+			return time.Duration(v.Uint()) * time.Second
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			return time.Duration(v.Float() * float64(time.Second))
+		default:
+			return 0
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"go.yhsif.com/stalecache"
+	stalecachegrpc "go.yhsif.com/stalecache/grpc"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	const (
+		method = "/test.Service/Get"
+		ttl    = 10
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	interceptor := stalecachegrpc.NewUnaryClientInterceptor(
+		10,
+		func(req *wrapperspb.StringValue) string {
+			return req.GetValue()
+		},
+		stalecachegrpc.WithTTLField[durationpb.Duration]("seconds"),
+		stalecache.WithClock[durationpb.Duration](clock),
+	)
+	var calls int32
+	invoker := func(_ context.Context, _ string, req, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		if req.(*wrapperspb.StringValue).GetValue() == "" {
+			return errors.New("empty")
+		}
+		resp := reply.(*durationpb.Duration)
+		resp.Seconds = ttl
+		resp.Nanos = calls
+		return nil
+	}
+	call := func(t *testing.T, key string, wantNanos int32) {
+		t.Helper()
+		reply := new(durationpb.Duration)
+		if err := interceptor(
+			context.Background(),
+			method,
+			wrapperspb.String(key),
+			reply,
+			nil,
+			invoker,
+		); err != nil {
+			t.Fatalf("RPC(%q) got error: %v", key, err)
+		}
+		if reply.GetNanos() != wantNanos {
+			t.Errorf("RPC(%q) got nanos %d, want %d", key, reply.GetNanos(), wantNanos)
+		}
+	}
+
+	call(t, "foo", 1)
+	call(t, "foo", 1)
+	call(t, "bar", 2)
+
+	clock.Advance(ttl * time.Second)
+	call(t, "foo", 3)
+	call(t, "bar", 4)
+
+	t.Run("error", func(t *testing.T) {
+		err := interceptor(
+			context.Background(),
+			method,
+			wrapperspb.String(""),
+			new(durationpb.Duration),
+			nil,
+			invoker,
+		)
+		if err == nil {
+			t.Error("RPC got nil error")
+		}
+	})
+
+	t.Run("other-types", func(t *testing.T) {
+		before := calls
+		for i := 0; i < 2; i++ {
+			if err := interceptor(
+				context.Background(),
+				method,
+				wrapperspb.Int64(1),
+				new(durationpb.Duration),
+				nil,
+				func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+					calls++
+					return nil
+				},
+			); err != nil {
+				t.Fatalf("RPC got error: %v", err)
+			}
+		}
+		if got := calls - before; got != 2 {
+			t.Errorf("Got %d calls, want 2", got)
+		}
+	})
+}
+
+func TestUnaryClientInterceptorCapacity(t *testing.T) {
+	interceptor := stalecachegrpc.NewUnaryClientInterceptor(
+		1,
+		func(req *wrapperspb.StringValue) string {
+			return req.GetValue()
+		},
+		stalecachegrpc.WithTTLField[durationpb.Duration]("seconds"),
+	)
+	var calls int
+	invoker := func(_ context.Context, _ string, _, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		calls++
+		reply.(*durationpb.Duration).Seconds = 60
+		return nil
+	}
+	for _, key := range []string{"foo", "foo", "bar", "foo"} {
+		if err := interceptor(
+			context.Background(),
+			"/test.Service/Get",
+			wrapperspb.String(key),
+			new(durationpb.Duration),
+			nil,
+			invoker,
+		); err != nil {
+			t.Fatalf("RPC(%q) got error: %v", key, err)
+		}
+	}
+	// foo is evicted by bar and called again.
+	if calls != 3 {
+		t.Errorf("Got %d calls, want 3", calls)
+	}
+}
+
+func TestUnaryClientInterceptorStale(t *testing.T) {
+	const ttl = 10
+	clock := stalecachetest.NewFakeClock(time.Now())
+	interceptor := stalecachegrpc.NewUnaryClientInterceptor(
+		10,
+		func(req *wrapperspb.StringValue) string {
+			return req.GetValue()
+		},
+		stalecachegrpc.WithTTLField[durationpb.Duration]("seconds"),
+		stalecache.WithClock[durationpb.Duration](clock),
+	)
+	fail := false
+	invoker := func(_ context.Context, _ string, _, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		if fail {
+			return errors.New("unavailable")
+		}
+		reply.(*durationpb.Duration).Seconds = ttl
+		return nil
+	}
+	call := func(t *testing.T) {
+		t.Helper()
+		reply := new(durationpb.Duration)
+		if err := interceptor(
+			context.Background(),
+			"/test.Service/Get",
+			wrapperspb.String("foo"),
+			reply,
+			nil,
+			invoker,
+		); err != nil {
+			t.Fatalf("RPC got error: %v", err)
+		}
+		if reply.GetSeconds() != ttl {
+			t.Errorf("RPC got seconds %d, want %d", reply.GetSeconds(), ttl)
+		}
+	}
+
+	call(t)
+	fail = true
+	clock.Advance(ttl * time.Second)
+	// The reload fails, the stale response is returned without error.
+	call(t)
+}
+
+func TestUnaryClientInterceptorBackgroundLoad(t *testing.T) {
+	interceptor := stalecachegrpc.NewUnaryClientInterceptor(
+		10,
+		func(req *wrapperspb.StringValue) string {
+			return req.GetValue()
+		},
+		// The first load is called in background without the RPC call.
+		stalecache.WithAsyncLoad[durationpb.Duration](true),
+	)
+	invoker := func(_ context.Context, _ string, _, reply any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		reply.(*durationpb.Duration).Seconds = 1
+		return nil
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		err := interceptor(
+			context.Background(),
+			"/test.Service/Get",
+			wrapperspb.String("foo"),
+			new(durationpb.Duration),
+			nil,
+			invoker,
+		)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("RPC got error: %v", err)
+		}
+	}
+}
+
+func TestUnaryClientInterceptorNoInvocation(t *testing.T) {
+	interceptor := stalecachegrpc.NewUnaryClientInterceptor(
+		10,
+		func(req *wrapperspb.StringValue) string {
+			return req.GetValue()
+		},
+		stalecache.WithAsyncLoad[durationpb.Duration](true),
+		stalecache.WithContextFunc[durationpb.Duration](func(context.Context) context.Context {
+			return context.Background()
+		}),
+	)
+	invoker := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return nil
+	}
+	// The background loads fail with ErrNoInvocation instead of panicking.
+	for i := 0; i < 2; i++ {
+		if err := interceptor(
+			context.Background(),
+			"/test.Service/Get",
+			wrapperspb.String("foo"),
+			new(durationpb.Duration),
+			nil,
+			invoker,
+		); err == nil {
+			t.Error("RPC got nil error, want not yet loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
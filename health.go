@@ -0,0 +1,181 @@
+package stalecache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HealthOption is an option of HealthHandler.
+type HealthOption func(*healthOpt)
+
+type healthOpt struct {
+	maxAge         time.Duration
+	minAge         time.Duration
+	requireSuccess bool
+}
+
+// WithHealthMaxAge is a HealthOption to report unhealthy when the last
+// successfully loaded value is older than maxAge.
+//
+// Default is 0, means the age of the value is not checked.
+func WithHealthMaxAge(maxAge time.Duration) HealthOption {
+	return func(o *healthOpt) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithHealthRequireSuccess is a HealthOption to report unhealthy when the last
+// load failed, even if there's a previously loaded value to fall back to.
+//
+// Default is false.
+func WithHealthRequireSuccess(require bool) HealthOption {
+	return func(o *healthOpt) {
+		o.requireSuccess = require
+	}
+}
+
+// Health statuses used in the body of HealthHandler.
+const (
+	healthPass = "pass"
+	healthFail = "fail"
+)
+
+// healthBody is the json body of HealthHandler,
+// following the field names of the IETF draft "Health Check Response Format
+// for HTTP APIs".
+type healthBody struct {
+	Status      string `json:"status"`
+	Output      string `json:"output,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	LoadedAt   *time.Time `json:"loadedAt,omitempty"`
+	AgeSeconds *float64   `json:"ageSeconds,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+}
+
+// HealthHandler returns a http.Handler reporting the health of c,
+// suitable for endpoints like /healthz and /readyz.
+//
+// It responds 200 when c has a successfully loaded value satisfying the
+// HealthOptions, and 503 otherwise.
+// The body is always json (with content type "application/health+json"),
+// with status "pass" or "fail",
+// description being the name set by WithName,
+// output describing the issue when failed,
+// and the time and age of the last successfully loaded value as well as the
+// last load error when available.
+//
+// It only reads the current state of c like Peek,
+// it never triggers a reload.
+func (c *Cache[T]) HealthHandler(options ...HealthOption) http.Handler {
+	var o healthOpt
+	for _, option := range options {
+		option(&o)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, c.health(o))
+	})
+}
+
+// ReadinessHandler returns a http.Handler suitable for readiness probes (for
+// example Kubernetes readinessProbe.httpGet),
+// so traffic is only routed after c is warmed up.
+//
+// It responds the same as HealthHandler,
+// except that a value loaded less than minAge ago also fails with output
+// "too fresh",
+// to give the first loaded value time to be validated.
+// minAge only applies until the handler responded OK for the first time,
+// so the reloads after that don't fail the readiness.
+// Non-positive minAge means any successfully loaded value passes.
+//
+// Unlike HealthHandler,
+// when c has no successfully loaded value it calls WarmUp in a background
+// goroutine (with only the values from the request context),
+// at most one at a time,
+// and responds without waiting for it.
+//
+// It supports GET and HEAD,
+// other methods get 405 Method Not Allowed.
+func (c *Cache[T]) ReadinessHandler(minAge time.Duration) http.Handler {
+	var warming, ready atomic.Bool
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		var o healthOpt
+		if !ready.Load() {
+			o.minAge = minAge
+		}
+		body := c.health(o)
+		if body.Status == healthPass {
+			ready.Store(true)
+		}
+		if body.LoadedAt == nil && warming.CompareAndSwap(false, true) {
+			ctx := context.WithoutCancel(r.Context())
+			go func() {
+				defer warming.Store(false)
+				c.WarmUp(ctx)
+			}()
+		}
+		writeHealth(w, body)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, body healthBody) {
+	code := http.StatusOK
+	if body.Status != healthPass {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/health+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (c *Cache[T]) health(o healthOpt) healthBody {
+	curr := c.current()
+	var good *cached[T]
+	var lastErr error
+	if curr.done.Load() {
+		good = curr.lastGood()
+		lastErr = curr.err
+	} else {
+		// being loaded.
+		good = curr.prev.Load()
+	}
+
+	body := healthBody{
+		Status:      healthPass,
+		Description: c.opt.name,
+	}
+	if lastErr != nil {
+		body.LastError = lastErr.Error()
+	}
+	if good == nil {
+		body.Status = healthFail
+		body.Output = "not loaded"
+		return body
+	}
+	age := c.opt.now().Sub(good.loaded)
+	ageSeconds := age.Seconds()
+	body.LoadedAt = &good.loaded
+	body.AgeSeconds = &ageSeconds
+	switch {
+	case o.requireSuccess && lastErr != nil:
+		body.Status = healthFail
+		body.Output = "last load failed"
+	case o.maxAge > 0 && age > o.maxAge:
+		body.Status = healthFail
+		body.Output = "stale"
+	case o.minAge > 0 && age < o.minAge:
+		body.Status = healthFail
+		body.Output = "too fresh"
+	}
+	return body
+}
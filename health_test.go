@@ -0,0 +1,136 @@
+package stalecache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestHealthHandler(t *testing.T) {
+	const maxAge = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var fail bool
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail {
+				return nil, errors.New("foo")
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithClock[int](clock),
+		stalecache.WithName[int]("users"),
+	)
+
+	check := func(t *testing.T, h http.Handler, wantCode int, wantStatus string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		if w.Code != wantCode {
+			t.Errorf("Got code %d, want %d", w.Code, wantCode)
+		}
+		var body struct {
+			Status      string `json:"status"`
+			Output      string `json:"output"`
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Failed to decode body %q: %v", w.Body.String(), err)
+		}
+		if body.Status != wantStatus {
+			t.Errorf("Got status %q (output %q), want %q", body.Status, body.Output, wantStatus)
+		}
+		if body.Description != "users" {
+			t.Errorf("Got description %q, want %q", body.Description, "users")
+		}
+	}
+
+	h := cache.HealthHandler(stalecache.WithHealthMaxAge(maxAge))
+	strict := cache.HealthHandler(stalecache.WithHealthRequireSuccess(true))
+
+	t.Run("not-loaded", func(t *testing.T) {
+		check(t, h, http.StatusServiceUnavailable, "fail")
+	})
+
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	t.Run("loaded", func(t *testing.T) {
+		check(t, h, http.StatusOK, "pass")
+		check(t, strict, http.StatusOK, "pass")
+	})
+
+	fail = true
+	cache.ForceReload(context.Background())
+	t.Run("failed", func(t *testing.T) {
+		check(t, h, http.StatusOK, "pass")
+		check(t, strict, http.StatusServiceUnavailable, "fail")
+	})
+
+	clock.Advance(maxAge + time.Second)
+	t.Run("stale", func(t *testing.T) {
+		check(t, h, http.StatusServiceUnavailable, "fail")
+	})
+}
+
+func TestReadinessHandler(t *testing.T) {
+	const minAge = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			var data int
+			return &data, nil
+		},
+		stalecache.WithClock[int](clock),
+	)
+	h := cache.ReadinessHandler(minAge)
+
+	check := func(t *testing.T, method string, wantCode int, wantOutput string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/readyz", nil))
+		if w.Code != wantCode {
+			t.Errorf("Got code %d, want %d", w.Code, wantCode)
+		}
+		if wantOutput == "" {
+			return
+		}
+		var body struct {
+			Output string `json:"output"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode body: %v", err)
+		}
+		if body.Output != wantOutput {
+			t.Errorf("Got output %q, want %q", body.Output, wantOutput)
+		}
+	}
+
+	check(t, http.MethodGet, http.StatusServiceUnavailable, "not loaded")
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if data, _, _ := cache.Peek(); data != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("WarmUp not called")
+		}
+	}
+	check(t, http.MethodGet, http.StatusServiceUnavailable, "too fresh")
+	clock.Advance(minAge)
+	check(t, http.MethodGet, http.StatusOK, "")
+	check(t, http.MethodHead, http.StatusOK, "")
+	check(t, http.MethodPost, http.StatusMethodNotAllowed, "")
+
+	// minAge only applies before it's ready for the first time.
+	if _, err := cache.ForceReload(context.Background()); err != nil {
+		t.Fatalf("ForceReload got error: %v", err)
+	}
+	check(t, http.MethodGet, http.StatusOK, "")
+}
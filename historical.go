@@ -0,0 +1,88 @@
+package stalecache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// HistoricalCache is a Cache that also keeps the results of the last few
+// loader calls, for auditing and debugging.
+type HistoricalCache[T any] struct {
+	*Cache[T]
+
+	now func() time.Time
+
+	mu sync.RWMutex
+	// ring buffer, next is the index of the next entry to write.
+	ring []CacheEntry[T]
+	next int
+	full bool
+}
+
+// NewHistorical creates a new HistoricalCache keeping the results of the last
+// history loader calls.
+//
+// Only the results of the loader calls are kept,
+// Update and other ways of updating the value directly are not.
+// The hook set by WithPostRefreshHook, if any, is still called.
+//
+// It panics if history is not positive.
+func NewHistorical[T any](history int, loader Loader[T], options ...Option[T]) *HistoricalCache[T] {
+	if history <= 0 {
+		panic("stalecache: NewHistorical called with non-positive history")
+	}
+	h := &HistoricalCache[T]{
+		ring: make([]CacheEntry[T], history),
+	}
+	options = append(options[:len(options):len(options)], func(o *opt[T]) {
+		h.now = o.now
+		hook := o.postRefresh
+		o.postRefresh = func(ctx context.Context, old, new *T, err error) {
+			if hook != nil {
+				hook(ctx, old, new, err)
+			}
+			h.push(new, err)
+		}
+	})
+	h.Cache = New(loader, options...)
+	return h
+}
+
+func (h *HistoricalCache[T]) push(data *T, err error) {
+	if err != nil {
+		data = nil
+	}
+	entry := CacheEntry[T]{
+		Data:     data,
+		LoadedAt: h.now(),
+		Err:      err,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ring[h.next] = entry
+	h.next++
+	if h.next == len(h.ring) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// History returns the results of the last loader calls, newest first.
+//
+// LoadedAt of failed entries is the time the loader returned the error.
+func (h *HistoricalCache[T]) History() []CacheEntry[T] {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	n := h.next
+	if h.full {
+		n = len(h.ring)
+	}
+	history := make([]CacheEntry[T], 0, n)
+	for i := 1; i <= n; i++ {
+		history = append(history, h.ring[(h.next-i+len(h.ring))%len(h.ring)])
+	}
+	return history
+}
@@ -0,0 +1,62 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestHistoricalCache(t *testing.T) {
+	const history = 3
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls int
+	var hookCalls int
+	cache := stalecache.NewHistorical(
+		history,
+		func(context.Context) (*int, error) {
+			calls++
+			if calls == 2 {
+				return nil, errors.New("foo")
+			}
+			data := calls
+			return &data, nil
+		},
+		stalecache.WithClock[int](clock),
+		stalecache.WithPostRefreshHook(func(context.Context, *int, *int, error) {
+			hookCalls++
+		}),
+	)
+
+	if got := cache.History(); len(got) != 0 {
+		t.Errorf("History before Load got %v, want empty", got)
+	}
+
+	for i := 0; i < 4; i++ {
+		cache.ForceReload(context.Background())
+		clock.Advance(time.Second)
+	}
+	if hookCalls != 4 {
+		t.Errorf("Got %d post refresh hook calls, want 4", hookCalls)
+	}
+
+	got := cache.History()
+	if len(got) != history {
+		t.Fatalf("History got %d entries, want %d: %v", len(got), history, got)
+	}
+	// newest first: 4, 3, error.
+	for i, want := range []int{4, 3} {
+		if got[i].Err != nil || got[i].Data == nil || *got[i].Data != want {
+			t.Errorf("History[%d] got %v, %v, want %d", i, got[i].Data, got[i].Err, want)
+		}
+	}
+	if got[2].Err == nil || got[2].Data != nil {
+		t.Errorf("History[2] got %v, %v, want error", got[2].Data, got[2].Err)
+	}
+	if !got[0].LoadedAt.After(got[1].LoadedAt) {
+		t.Errorf("History[0] loaded at %v, not after History[1] at %v", got[0].LoadedAt, got[1].LoadedAt)
+	}
+}
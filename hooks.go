@@ -0,0 +1,261 @@
+package stalecache
+
+import (
+	"context"
+	"time"
+)
+
+// Hooks are callbacks to observe the cache.
+//
+// All fields are optional.
+type Hooks[T any] struct {
+	// OnHit is called when Load returns cached data without calling the loader,
+	// with the data and the time it's loaded.
+	OnHit func(data *T, loaded time.Time)
+
+	// OnMiss is called when Load found the cached data stale,
+	// either by the ttl or by the validator.
+	OnMiss func()
+
+	// OnLoadStart and OnLoadEnd are called right before and after every loader
+	// call, from the goroutine calling the loader.
+	// With WithRetry they are called for every attempt.
+	// OnLoadEnd is called with the returned data and error from the loader,
+	// and how long the loader call took.
+	OnLoadStart func()
+	OnLoadEnd   func(data *T, err error, took time.Duration)
+
+	// OnOversized is called when the loaded data is larger than the limit set
+	// by WithSizeLimit, with the data and its size.
+	OnOversized func(data *T, size int64)
+
+	// OnCircuitOpen is called when the circuit opens (or opens again after a
+	// failed probe), see WithCircuitBreaker.
+	OnCircuitOpen func()
+
+	// OnPubSubError is called with the errors from the PubSubAdapter set by
+	// WithPubSub, when Subscribe or Publish fails.
+	OnPubSubError func(err error)
+}
+
+// WithHooks is an Option to add Hooks to the cache.
+//
+// It can be used multiple times,
+// and the hooks from all of them are called in the order they are added.
+func WithHooks[T any](hooks Hooks[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+// WithCacheKey is an Option to set the key of the cache,
+// to tell multiple Cache instances apart in logs and metrics.
+//
+// Default is empty.
+// It's passed into the functions added by WithKeyedHooks,
+// and returned by Cache.Key.
+func WithCacheKey[T any](key string) Option[T] {
+	return func(o *opt[T]) {
+		o.key = key
+	}
+}
+
+// WithKeyedHooks is the same as WithHooks,
+// except that the Hooks are created by f with the key set by WithCacheKey,
+// regardless of the order of the Options.
+//
+// It's mainly for integrations (for example, sloghandler) to include the key
+// in their Hooks.
+// The Hooks created by f are called after the ones added by WithHooks.
+func WithKeyedHooks[T any](f func(key string) Hooks[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.keyedHooks = append(o.keyedHooks, func(_, key string) Hooks[T] {
+			return f(key)
+		})
+	}
+}
+
+// WithName is an Option to set the name of the cache,
+// to identify the Cache instance in logs, metrics, and errors.
+//
+// Default is empty.
+// Unlike WithCacheKey, which is usually set per key (for example by the
+// caller of a Map),
+// the name is meant to identify what the cache is for.
+// It's passed into the functions added by WithNamedHooks,
+// added to the CacheErrors returned by the cache,
+// reported by HealthHandler, and returned by Cache.Name.
+func WithName[T any](name string) Option[T] {
+	return func(o *opt[T]) {
+		o.name = name
+	}
+}
+
+// WithNamedHooks is the same as WithKeyedHooks,
+// except that f is called with both the name set by WithName and the key set
+// by WithCacheKey.
+func WithNamedHooks[T any](f func(name, key string) Hooks[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.keyedHooks = append(o.keyedHooks, f)
+	}
+}
+
+// Key returns the key set by WithCacheKey.
+func (c *Cache[T]) Key() string {
+	return c.opt.key
+}
+
+// Name returns the name set by WithName.
+func (c *Cache[T]) Name() string {
+	return c.opt.name
+}
+
+func (o *opt[T]) onHit(data *T, loaded time.Time) {
+	for _, h := range o.hooks {
+		if h.OnHit != nil {
+			h.OnHit(data, loaded)
+		}
+	}
+}
+
+func (o *opt[T]) onMiss() {
+	for _, h := range o.hooks {
+		if h.OnMiss != nil {
+			h.OnMiss()
+		}
+	}
+}
+
+func (o *opt[T]) onLoadStart() {
+	for _, h := range o.hooks {
+		if h.OnLoadStart != nil {
+			h.OnLoadStart()
+		}
+	}
+}
+
+func (o *opt[T]) onLoadEnd(data *T, err error, took time.Duration) {
+	for _, h := range o.hooks {
+		if h.OnLoadEnd != nil {
+			h.OnLoadEnd(data, err, took)
+		}
+	}
+}
+
+func (o *opt[T]) onCircuitOpen() {
+	for _, h := range o.hooks {
+		if h.OnCircuitOpen != nil {
+			h.OnCircuitOpen()
+		}
+	}
+}
+
+func (o *opt[T]) onPubSubError(err error) {
+	for _, h := range o.hooks {
+		if h.OnPubSubError != nil {
+			h.OnPubSubError(err)
+		}
+	}
+}
+
+func (o *opt[T]) onOversized(data *T, size int64) {
+	for _, h := range o.hooks {
+		if h.OnOversized != nil {
+			h.OnOversized(data, size)
+		}
+	}
+}
+
+func hooksLoader[T any](o *opt[T], loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		o.onLoadStart()
+		start := time.Now()
+		data, err := loader(ctx)
+		o.onLoadEnd(data, err, time.Since(start))
+		return data, err
+	}
+}
+
+// WithPreRefreshHook is an Option to set a hook called right before the
+// loader is called to refresh the cache,
+// with the current (last successfully loaded) data, or nil if there's none.
+//
+// The hook is called synchronously from the goroutine calling the loader,
+// with no internal locks held,
+// which can be a background goroutine (for example with
+// WithBackgroundRefresh or WithAsyncLoad).
+// With WithRetry it's only called once before all the attempts.
+func WithPreRefreshHook[T any](hook func(ctx context.Context, current *T)) Option[T] {
+	return func(o *opt[T]) {
+		o.preRefresh = hook
+	}
+}
+
+// WithPostRefreshHook is an Option to set a hook called right after the
+// loader returned to refresh the cache,
+// with the old data (same as the one passed into the hook set by
+// WithPreRefreshHook), and the new data and error.
+//
+// The hook is called synchronously from the goroutine calling the loader,
+// with no internal locks held,
+// which can be a background goroutine (for example with
+// WithBackgroundRefresh or WithAsyncLoad).
+func WithPostRefreshHook[T any](hook func(ctx context.Context, old, new *T, err error)) Option[T] {
+	return func(o *opt[T]) {
+		o.postRefresh = hook
+	}
+}
+
+// WithOnStale is an Option to set a callback called when Load finds a
+// successfully loaded value stale (by the ttl or the validator) for the first
+// time, before the loader is called to reload it.
+//
+// It's called at most once per loaded value,
+// not on every Load while the reload is in flight,
+// and it's called in a new goroutine so it never blocks Load,
+// with the ctx from WithContextFunc (or context.Background()),
+// the stale value, and the time it's loaded.
+func WithOnStale[T any](callback func(ctx context.Context, data *T, loaded time.Time)) Option[T] {
+	return func(o *opt[T]) {
+		o.onStale = callback
+	}
+}
+
+// WithOnEvict is an Option to set a callback called when a successfully
+// loaded (or updated) value is dropped by the cache,
+// for example to release the resources held by the value.
+//
+// A value is dropped when it's replaced by a newer value (from the loader,
+// Update, etc.) and it can no longer be restored by Rollback,
+// or when it's removed by Reset or replaced by Rollback.
+// As Rollback keeps one previous value,
+// a value replaced by a newer one is usually dropped when the newer one is
+// replaced.
+// Failed loads have no value so they never trigger the callback.
+//
+// It's called at most once per value,
+// in a new goroutine so it never blocks Load,
+// with context.Background() and the dropped value.
+func WithOnEvict[T any](callback func(ctx context.Context, old *T)) Option[T] {
+	return func(o *opt[T]) {
+		o.onEvict = callback
+	}
+}
+
+// evict calls the callback set by WithOnEvict with the data of old,
+// unless old is nil, already evicted,
+// or its data is still used by the current or previous entry.
+func (c *Cache[T]) evict(old *cached[T]) {
+	if c.opt.onEvict == nil || old == nil || old.data == nil {
+		return
+	}
+	if curr := c.cached.Load().good(); curr != nil && curr.data == old.data {
+		return
+	}
+	if prev := c.previous.Load(); prev != nil && prev.data == old.data {
+		return
+	}
+	if old.evicted.CompareAndSwap(false, true) {
+		go c.opt.onEvict(context.Background(), old.data)
+	}
+}
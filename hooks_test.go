@@ -0,0 +1,339 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestHooks(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	wantErr := errors.New("foo")
+	var fail atomic.Bool
+	var hits, misses, starts, ends, errs, others atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail.Load() {
+				return nil, wantErr
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithHooks(stalecache.Hooks[int]{
+			OnHit: func(data *int, _ time.Time) {
+				if data == nil {
+					t.Error("OnHit called with nil data")
+				}
+				hits.Add(1)
+			},
+			OnMiss:      func() { misses.Add(1) },
+			OnLoadStart: func() { starts.Add(1) },
+			OnLoadEnd: func(_ *int, err error, _ time.Duration) {
+				ends.Add(1)
+				if err != nil {
+					errs.Add(1)
+				}
+			},
+		}),
+		// Adding more Hooks with nil fields does not replace the previous ones.
+		stalecache.WithHooks(stalecache.Hooks[int]{
+			OnMiss: func() { others.Add(1) },
+		}),
+	)
+
+	check := func(t *testing.T, wantHits, wantMisses, wantLoads, wantErrs int64) {
+		t.Helper()
+		if got := hits.Load(); got != wantHits {
+			t.Errorf("Got %d hits, want %d", got, wantHits)
+		}
+		if got := misses.Load(); got != wantMisses {
+			t.Errorf("Got %d misses, want %d", got, wantMisses)
+		}
+		if got := others.Load(); got != wantMisses {
+			t.Errorf("Got %d misses from the other hooks, want %d", got, wantMisses)
+		}
+		if got := starts.Load(); got != wantLoads {
+			t.Errorf("Got %d load starts, want %d", got, wantLoads)
+		}
+		if got := ends.Load(); got != wantLoads {
+			t.Errorf("Got %d load ends, want %d", got, wantLoads)
+		}
+		if got := errs.Load(); got != wantErrs {
+			t.Errorf("Got %d load errors, want %d", got, wantErrs)
+		}
+	}
+
+	t.Run("first-load", func(t *testing.T) {
+		cache.Load(context.Background())
+		check(t, 0, 0, 1, 0)
+	})
+	t.Run("hit", func(t *testing.T) {
+		cache.Load(context.Background())
+		check(t, 1, 0, 1, 0)
+	})
+	t.Run("miss", func(t *testing.T) {
+		clock.Advance(ttl)
+		fail.Store(true)
+		cache.Load(context.Background())
+		check(t, 1, 1, 2, 1)
+	})
+}
+
+func TestRefreshHooks(t *testing.T) {
+	wantErr := errors.New("foo")
+	var n atomic.Int64
+	type call struct {
+		old, new int
+		err      error
+	}
+	var pre []int
+	var post []call
+	deref := func(p *int) int {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			i := int(n.Add(1))
+			if i == 2 {
+				return nil, wantErr
+			}
+			return &i, nil
+		},
+		stalecache.WithPreRefreshHook(func(_ context.Context, current *int) {
+			pre = append(pre, deref(current))
+		}),
+		stalecache.WithPostRefreshHook(func(_ context.Context, old, new *int, err error) {
+			post = append(post, call{old: deref(old), new: deref(new), err: err})
+		}),
+	)
+	for i := 0; i < 3; i++ {
+		cache.ForceReload(context.Background())
+	}
+
+	wantPre := []int{0, 1, 1}
+	if len(pre) != len(wantPre) {
+		t.Fatalf("Pre-refresh hook got %v, want %v", pre, wantPre)
+	}
+	for i, want := range wantPre {
+		if pre[i] != want {
+			t.Errorf("Pre-refresh hook #%d got %d, want %d", i, pre[i], want)
+		}
+	}
+	wantPost := []call{
+		{old: 0, new: 1},
+		{old: 1, new: 0, err: wantErr},
+		{old: 1, new: 3},
+	}
+	if len(post) != len(wantPost) {
+		t.Fatalf("Post-refresh hook got %+v, want %+v", post, wantPost)
+	}
+	for i, want := range wantPost {
+		if post[i] != want {
+			t.Errorf("Post-refresh hook #%d got %+v, want %+v", i, post[i], want)
+		}
+	}
+}
+
+func TestKeyedHooks(t *testing.T) {
+	const key = "foo"
+	var gotKey string
+	var opened atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			return nil, errors.New("foo")
+		},
+		stalecache.WithKeyedHooks(func(key string) stalecache.Hooks[int] {
+			gotKey = key
+			return stalecache.Hooks[int]{
+				OnCircuitOpen: func() {
+					opened.Add(1)
+				},
+			}
+		}),
+		stalecache.WithCacheKey[int](key),
+		stalecache.WithCircuitBreaker[int](1, time.Hour),
+	)
+	if gotKey != key {
+		t.Errorf("WithKeyedHooks got key %q, want %q", gotKey, key)
+	}
+	if got := cache.Key(); got != key {
+		t.Errorf("Key got %q, want %q", got, key)
+	}
+	cache.ForceReload(context.Background())
+	if got := opened.Load(); got != 1 {
+		t.Errorf("OnCircuitOpen called %d times, want 1", got)
+	}
+}
+
+func TestNamedHooks(t *testing.T) {
+	const (
+		name = "users"
+		key  = "foo"
+	)
+	var gotName, gotKey string
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			return nil, errors.New("foo")
+		},
+		stalecache.WithNamedHooks(func(name, key string) stalecache.Hooks[int] {
+			gotName, gotKey = name, key
+			return stalecache.Hooks[int]{}
+		}),
+		stalecache.WithName[int](name),
+		stalecache.WithCacheKey[int](key),
+		stalecache.WithCircuitBreaker[int](1, time.Hour),
+	)
+	if gotName != name || gotKey != key {
+		t.Errorf("WithNamedHooks got %q, %q, want %q, %q", gotName, gotKey, name, key)
+	}
+	if got := cache.Name(); got != name {
+		t.Errorf("Name got %q, want %q", got, name)
+	}
+
+	cache.ForceReload(context.Background())
+	_, err := cache.Load(context.Background())
+	var ce *stalecache.CacheError
+	if !errors.As(err, &ce) {
+		t.Fatalf("Load got error %v, want CacheError", err)
+	}
+	if ce.Name != name {
+		t.Errorf("CacheError.Name got %q, want %q", ce.Name, name)
+	}
+	if !errors.Is(err, stalecache.ErrCircuitOpen) {
+		t.Errorf("Load got error %v, want %v", err, stalecache.ErrCircuitOpen)
+	}
+	if want := `stalecache "users": circuit open`; err.Error() != want {
+		t.Errorf("Load got error %q, want %q", err.Error(), want)
+	}
+}
+
+func TestOnStale(t *testing.T) {
+	const (
+		ttl = time.Minute
+		n   = 5
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	type staleCall struct {
+		data   int64
+		loaded time.Time
+	}
+	calls := make(chan staleCall, n)
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			time.Sleep(time.Millisecond)
+			data := loaderCalls.Add(1)
+			return &data, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		stalecache.WithOnStale(func(_ context.Context, data *int64, loaded time.Time) {
+			calls <- staleCall{data: *data, loaded: loaded}
+		}),
+	)
+
+	loadAll := func(t *testing.T) {
+		t.Helper()
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if _, err := cache.Load(context.Background()); err != nil {
+					t.Errorf("Load #%d got error: %v", i, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	loadAll(t)
+	loadedAt := clock.Now()
+	clock.Advance(ttl)
+	for i := int64(1); i <= 2; i++ {
+		loadAll(t)
+		select {
+		case call := <-calls:
+			if call.data != i || !call.loaded.Equal(loadedAt) {
+				t.Errorf("OnStale #%d got %d, %v, want %d, %v", i, call.data, call.loaded, i, loadedAt)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("OnStale #%d not called", i)
+		}
+		loadedAt = clock.Now()
+		clock.Advance(ttl)
+	}
+	select {
+	case call := <-calls:
+		t.Errorf("Unexpected OnStale call: %+v", call)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	var mu sync.Mutex
+	evicted := make(map[int]int)
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			return nil, errors.New("foo")
+		},
+		stalecache.WithOnEvict(func(_ context.Context, old *int) {
+			mu.Lock()
+			defer mu.Unlock()
+			evicted[*old]++
+		}),
+	)
+	update := func(v int) {
+		cache.Update(context.Background(), &v)
+	}
+	check := func(t *testing.T, want map[int]int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			mu.Lock()
+			got := fmt.Sprint(evicted)
+			mu.Unlock()
+			if got == fmt.Sprint(want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Got evicted %s, want %v", got, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	update(1)
+	update(2)
+	// 1 is still kept for Rollback.
+	check(t, map[int]int{})
+
+	update(3)
+	check(t, map[int]int{1: 1})
+
+	cache.ForceReload(context.Background())
+	check(t, map[int]int{1: 1})
+
+	cache.Reset()
+	check(t, map[int]int{1: 1, 3: 1})
+
+	if !cache.Rollback() {
+		t.Fatal("Rollback got false")
+	}
+	update(4)
+	update(5)
+	check(t, map[int]int{1: 1, 2: 1, 3: 1})
+}
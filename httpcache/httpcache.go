@@ -0,0 +1,167 @@
+// Package httpcache provides an http.RoundTripper caching responses with
+// stalecache.
+package httpcache // import "go.yhsif.com/stalecache/httpcache"
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+// Response is a cached HTTP response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// MaxAge is the max-age from the Cache-Control header of the response,
+	// which is used as the ttl.
+	// It's 0 when there's no max-age, or with no-cache or no-store.
+	MaxAge time.Duration
+
+	hash [sha256.Size]byte
+}
+
+// CachingTransport is an http.RoundTripper caching the responses of GET
+// requests by URL.
+//
+// The responses are cached regardless of their status codes,
+// the ttl of each response is its Cache-Control max-age,
+// and when a reload fails the stale response is returned if there's one.
+//
+// At most capacity URLs are cached,
+// the least recently used URL is evicted when there are more.
+//
+// As responses are keyed only by URL,
+// it should not be used for requests with per-user responses,
+// for example ones with different Authorization headers.
+type CachingTransport struct {
+	base  http.RoundTripper
+	cache *stalecache.LRUMap[string, Response]
+}
+
+// requestKey is the context key to pass the request into the loader.
+type requestKey struct{}
+
+// ErrNoRequest is the error of the loader calls without the request from
+// RoundTrip in their ctx.
+//
+// It happens when the loader is called in background (for example by
+// WithBackgroundRefresh, WithAsyncLoad, or WithRateLimit) with the default
+// WithContextFunc of NewCachingTransport overridden by one not keeping the
+// values.
+// The stale response is still returned by RoundTrip when there's one.
+var ErrNoRequest = errors.New("stalecache/httpcache: no request in the ctx of the loader")
+
+// NewCachingTransport creates a new CachingTransport sending the requests
+// through base, or http.DefaultTransport if base is nil,
+// and caching the responses of at most capacity URLs,
+// which must be positive.
+//
+// The options are applied to the cache of each URL,
+// after the WithDynamicTTL to use the max-age as the ttl,
+// the WithEqualFunc to compare responses by status code and body hash,
+// so unchanged responses are not redelivered to the subscribers,
+// and the WithContextFunc using context.WithoutCancel,
+// so the loader calls in background still have the request to send.
+// Same as stalecache.NewLRUMap,
+// it panics with the Options not supported by stalecache.Map,
+// for example stalecache.WithPubSub.
+func NewCachingTransport(base http.RoundTripper, capacity int, options ...stalecache.Option[Response]) *CachingTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &CachingTransport{
+		base: base,
+	}
+	t.cache = stalecache.NewLRUMap(
+		capacity,
+		t.load,
+		append([]stalecache.Option[Response]{
+			stalecache.WithDynamicTTL(func(r *Response) time.Duration {
+				return r.MaxAge
+			}),
+			stalecache.WithEqualFunc(func(a, b *Response) bool {
+				return a.StatusCode == b.StatusCode && a.hash == b.hash
+			}),
+			stalecache.WithContextFunc[Response](context.WithoutCancel),
+		}, options...)...,
+	)
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+//
+// Requests other than GET are sent through the base transport directly.
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	ctx := context.WithValue(req.Context(), requestKey{}, req)
+	cached, err := t.cache.Load(ctx, req.URL.String())
+	if cached == nil {
+		return nil, err
+	}
+	// err is ignored when there's stale response to return.
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (t *CachingTransport) load(ctx context.Context, _ string) (*Response, error) {
+	req, ok := ctx.Value(requestKey{}).(*http.Request)
+	if !ok {
+		return nil, ErrNoRequest
+	}
+	resp, err := t.base.RoundTrip(req.Clone(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		MaxAge:     maxAge(resp.Header.Get("Cache-Control")),
+		hash:       sha256.Sum256(body),
+	}, nil
+}
+
+// maxAge parses the max-age from the value of a Cache-Control header.
+func maxAge(cacheControl string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache", directive == "no-store":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.ParseInt(strings.TrimPrefix(directive, "max-age="), 10, 64)
+			if err == nil && seconds > 0 {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
+}
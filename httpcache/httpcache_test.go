@@ -0,0 +1,182 @@
+package httpcache_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/httpcache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestCachingTransport(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		switch r.URL.Path {
+		case "/cached":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			io.WriteString(w, "cached")
+		default:
+			w.Header().Set("Cache-Control", "no-store")
+			fmt.Fprintf(w, "uncached %d", n)
+		}
+	}))
+	defer server.Close()
+
+	clock := stalecachetest.NewFakeClock(time.Now())
+	client := &http.Client{
+		Transport: httpcache.NewCachingTransport(
+			nil,
+			10,
+			stalecache.WithClock[httpcache.Response](clock),
+		),
+	}
+	get := func(t *testing.T, path string) string {
+		t.Helper()
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s got error: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s got status %d", path, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("GET %s failed to read body: %v", path, err)
+		}
+		return string(body)
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := get(t, "/cached"); got != "cached" {
+			t.Errorf("GET /cached got %q", got)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Got %d server calls for cached url, want 1", got)
+	}
+
+	clock.Advance(time.Minute)
+	get(t, "/cached")
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Got %d server calls after max-age, want 2", got)
+	}
+
+	clock.Advance(time.Nanosecond)
+	first := get(t, "/uncached")
+	clock.Advance(time.Nanosecond)
+	if second := get(t, "/uncached"); second == first {
+		t.Errorf("GET /uncached got the same body %q twice", first)
+	}
+}
+
+func TestCachingTransportCapacity(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: httpcache.NewCachingTransport(nil, 1),
+	}
+	for _, path := range []string{"/a", "/a", "/b", "/a"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s got error: %v", path, err)
+		}
+		resp.Body.Close()
+	}
+	// /a is evicted by /b and loaded again.
+	if got := calls.Load(); got != 3 {
+		t.Errorf("Got %d server calls, want 3", got)
+	}
+}
+
+func TestCachingTransportNotGet(t *testing.T) {
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: httpcache.NewCachingTransport(nil, 10),
+	}
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("POST got error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Got %d server calls for POST, want 2", got)
+	}
+}
+
+func TestCachingTransportBackgroundLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "body")
+	}))
+	defer server.Close()
+
+	transport := httpcache.NewCachingTransport(
+		nil,
+		10,
+		// The first load is called in background without the request.
+		stalecache.WithAsyncLoad[httpcache.Response](true),
+	)
+	client := &http.Client{Transport: transport}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET got error: %v", err)
+		}
+	}
+}
+
+func TestCachingTransportNoRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "body")
+	}))
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: httpcache.NewCachingTransport(
+			nil,
+			10,
+			stalecache.WithAsyncLoad[httpcache.Response](true),
+			stalecache.WithContextFunc[httpcache.Response](func(context.Context) context.Context {
+				return context.Background()
+			}),
+		),
+	}
+	// The background loads fail with ErrNoRequest instead of panicking.
+	for i := 0; i < 2; i++ {
+		if resp, err := client.Get(server.URL); err == nil {
+			resp.Body.Close()
+			t.Error("GET got nil error, want not yet loaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,338 @@
+// Package httpmw provides an HTTP middleware backed by stalecache.
+package httpmw // import "go.yhsif.com/stalecache/httpmw"
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"go.yhsif.com/stalecache"
+)
+
+type config struct {
+	pattern *regexp.Regexp
+	keyFn   func(*http.Request) string
+}
+
+// Option defines Middleware options.
+type Option func(*config)
+
+// WithPattern is an Option to only cache requests with URL paths matching
+// pattern.
+//
+// Default is nil, means all GET requests are cached.
+func WithPattern(pattern *regexp.Regexp) Option {
+	return func(c *config) {
+		c.pattern = pattern
+	}
+}
+
+// WithKeyFunc is an Option to set the key of the cached responses.
+//
+// Default is nil, means the request URL (path and query) is used.
+// When set, the responses are cached by keyFn(r) instead,
+// for example to only keep the query parameters known to change the response,
+// so that random query strings don't create new keys.
+// Requests with empty keys are not cached.
+func WithKeyFunc(keyFn func(r *http.Request) string) Option {
+	return func(c *config) {
+		c.keyFn = keyFn
+	}
+}
+
+// Response is a response cached by Middleware.
+type Response[T any] struct {
+	// StatusCode is the status code of the response.
+	// 0 means http.StatusOK.
+	StatusCode int
+	// Header is the header of the response.
+	Header http.Header
+	// Data is the deserialized body of the response.
+	Data *T
+}
+
+// store is where the middleware looks up and stores the responses.
+type store[T any] interface {
+	// peek returns the fresh response of key without calling the loader,
+	// or nil if there's none.
+	peek(key string) *Response[T]
+
+	update(ctx context.Context, key string, resp *Response[T])
+}
+
+// cacheStore stores a single response in a Cache,
+// with the key and header of the response next to it.
+type cacheStore[T any] struct {
+	cache *stalecache.Cache[T]
+
+	mu     sync.Mutex
+	key    string
+	header http.Header
+}
+
+func (s *cacheStore[T]) peek(key string) *Response[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key != s.key {
+		return nil
+	}
+	data, _, stale := s.cache.PeekStale()
+	if data == nil || stale {
+		return nil
+	}
+	return &Response[T]{
+		Header: s.header,
+		Data:   data,
+	}
+}
+
+func (s *cacheStore[T]) update(ctx context.Context, key string, resp *Response[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.cache.Update(ctx, resp.Data); err == nil {
+		s.key = key
+		s.header = resp.Header
+	}
+}
+
+// lruStore stores the responses in a LRUMap by their keys.
+type lruStore[T any] struct {
+	m *stalecache.LRUMap[string, Response[T]]
+}
+
+func (s lruStore[T]) peek(key string) *Response[T] {
+	resp, _, stale := s.m.PeekStale(key)
+	if resp == nil || resp.Data == nil || stale {
+		return nil
+	}
+	return resp
+}
+
+func (s lruStore[T]) update(ctx context.Context, key string, resp *Response[T]) {
+	s.m.Update(ctx, key, resp)
+}
+
+// Middleware returns an HTTP middleware caching the response in c.
+//
+// c holds a single response,
+// which is only replayed to the requests with the same key as the request it's
+// captured from,
+// keyed by the request URL (path and query), or the key set by WithKeyFunc.
+// A request with a different key replaces it,
+// so it's best used with WithPattern matching a single resource.
+// Use MapMiddleware to cache the responses of multiple keys.
+// c should only be updated by the middleware,
+// as the header of the response is kept by the middleware instead of c.
+//
+// Other than that it has the same semantics as MapMiddleware.
+func Middleware[T any](
+	c *stalecache.Cache[T],
+	serialize func(*T) []byte,
+	deserialize func([]byte) (*T, error),
+	options ...Option,
+) func(http.Handler) http.Handler {
+	return middleware[T](&cacheStore[T]{cache: c}, serialize, deserialize, options)
+}
+
+// MapMiddleware returns an HTTP middleware caching the responses in m,
+// keyed by the request URL (path and query), or the key set by WithKeyFunc.
+// m is an LRUMap so the number of cached responses is bounded.
+//
+// For GET requests matching the configured pattern and without an
+// Authorization header,
+// the middleware peeks the cached response of the key first,
+// and replays the status code, header and serialized data if it's not stale
+// according to the ttl.
+// Otherwise the request is delegated to the next handler,
+// and a successful (200) response is captured with its header,
+// deserialized, and stored into m via Update.
+//
+// The loader of m is never called by the middleware,
+// and the validators of m are not checked,
+// the ttl of m decides when the next handler is called again.
+//
+// Responses that are specific to the user are not stored,
+// which are the ones with Set-Cookie or Vary headers,
+// or with private or no-store in their Cache-Control header.
+// Whether a response is stored is decided when its header is written,
+// and the body of the ones not stored is not buffered.
+// The hop-by-hop and cookie headers are never replayed.
+func MapMiddleware[T any](
+	m *stalecache.LRUMap[string, Response[T]],
+	serialize func(*T) []byte,
+	deserialize func([]byte) (*T, error),
+	options ...Option,
+) func(http.Handler) http.Handler {
+	return middleware[T](lruStore[T]{m: m}, serialize, deserialize, options)
+}
+
+func middleware[T any](
+	s store[T],
+	serialize func(*T) []byte,
+	deserialize func([]byte) (*T, error),
+	options []Option,
+) func(http.Handler) http.Handler {
+	var cfg config
+	for _, option := range options {
+		option(&cfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet ||
+				r.Header.Get("Authorization") != "" ||
+				(cfg.pattern != nil && !cfg.pattern.MatchString(r.URL.Path)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := r.URL.String()
+			if cfg.keyFn != nil {
+				key = cfg.keyFn(r)
+			}
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if resp := s.peek(key); resp != nil {
+				resp.write(w, serialize)
+				return
+			}
+			rec := &recorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if !rec.wroteHeader {
+				rec.record(http.StatusOK)
+			}
+			if !rec.store {
+				return
+			}
+			if data, err := deserialize(rec.body.Bytes()); err == nil {
+				s.update(r.Context(), key, &Response[T]{
+					StatusCode: http.StatusOK,
+					Header:     stripHeader(rec.sent),
+					Data:       data,
+				})
+			}
+		})
+	}
+}
+
+// storable returns true if the response with header can be served to other
+// users.
+func storable(header http.Header) bool {
+	if len(header.Values("Set-Cookie")) > 0 || len(header.Values("Vary")) > 0 {
+		return false
+	}
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			directive, _, _ = strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(directive, "private") || strings.EqualFold(directive, "no-store") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hopByHop are the headers only meaningful for a single connection,
+// plus the cookie headers.
+var hopByHop = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	"Set-Cookie",
+}
+
+// stripHeader returns a copy of header without the hop-by-hop and cookie
+// headers.
+func stripHeader(header http.Header) http.Header {
+	header = header.Clone()
+	for _, v := range header.Values("Connection") {
+		for _, k := range strings.Split(v, ",") {
+			header.Del(strings.TrimSpace(k))
+		}
+	}
+	for _, k := range hopByHop {
+		header.Del(k)
+	}
+	return header
+}
+
+func (resp *Response[T]) write(w http.ResponseWriter, serialize func(*T) []byte) {
+	header := w.Header()
+	for k, v := range stripHeader(resp.Header) {
+		header[k] = v
+	}
+	// The serialized data is not necessarily the same as the original body.
+	header.Del("Content-Length")
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(serialize(resp.Data))
+}
+
+// recorder writes through to the underlying http.ResponseWriter while keeping
+// a copy of the response if it's to be stored.
+type recorder struct {
+	http.ResponseWriter
+
+	wroteHeader bool
+	sent        http.Header
+	// store is whether the response is to be stored,
+	// decided when the header is written.
+	store bool
+	body  bytes.Buffer
+}
+
+// record records the status and header sent with the response.
+func (r *recorder) record(status int) {
+	r.wroteHeader = true
+	r.sent = r.Header().Clone()
+	r.store = status == http.StatusOK && storable(r.sent)
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.record(status)
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if !r.wroteHeader {
+		r.record(http.StatusOK)
+	}
+	if r.store {
+		r.body.Write(p)
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher,
+// which is a no-op if the underlying http.ResponseWriter is not an
+// http.Flusher.
+func (r *recorder) Flush() {
+	if !r.wroteHeader {
+		r.record(http.StatusOK)
+	}
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter,
+// for http.ResponseController.
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
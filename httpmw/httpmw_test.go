@@ -0,0 +1,322 @@
+package httpmw_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/httpmw"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+// mapLoader is the loader of the LRUMaps in the tests,
+// which should never be called by the middleware.
+func mapLoader(t *testing.T) stalecache.MapLoader[string, httpmw.Response[string]] {
+	return func(_ context.Context, key string) (*httpmw.Response[string], error) {
+		t.Errorf("Loader called with %q", key)
+		return nil, errors.New("not cached")
+	}
+}
+
+func TestMapMiddleware(t *testing.T) {
+	cache := stalecache.NewLRUMap(10, mapLoader(t))
+	var handlerCalls int
+	handler := httpmw.MapMiddleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+		httpmw.WithPattern(regexp.MustCompile(`^/cached`)),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		w.Header().Set("X-Test", r.URL.String())
+		io.WriteString(w, r.URL.String())
+	}))
+
+	for _, c := range []struct {
+		url       string
+		wantCalls int
+	}{
+		{"/cached", 1},
+		{"/cached", 1},
+		{"/cached/foo", 2},
+		{"/cached?q=foo", 3},
+		{"/cached/foo", 3},
+		{"/cached?q=foo", 3},
+		{"/other", 4},
+		{"/other", 5},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.url, nil))
+		if got := w.Code; got != http.StatusOK {
+			t.Errorf("%s got status %d, want %d", c.url, got, http.StatusOK)
+		}
+		if got := w.Header().Get("X-Test"); got != c.url {
+			t.Errorf("%s got header X-Test %q, want %q", c.url, got, c.url)
+		}
+		if got := w.Body.String(); got != c.url {
+			t.Errorf("%s got body %q, want %q", c.url, got, c.url)
+		}
+		if handlerCalls != c.wantCalls {
+			t.Errorf("%s got %d handler calls, want %d", c.url, handlerCalls, c.wantCalls)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	const ttl = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			t.Error("Loader called")
+			return nil, errors.New("not cached")
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+	)
+	var handlerCalls int
+	handler := httpmw.Middleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		w.Header().Set("X-Test", r.URL.String())
+		io.WriteString(w, r.URL.String())
+	}))
+
+	for _, c := range []struct {
+		url       string
+		advance   time.Duration
+		wantCalls int
+	}{
+		{"/foo", 0, 1},
+		{"/foo", 0, 1},
+		// replaces /foo
+		{"/bar", 0, 2},
+		{"/foo", 0, 3},
+		{"/foo", ttl, 4},
+	} {
+		clock.Advance(c.advance)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.url, nil))
+		if got := w.Header().Get("X-Test"); got != c.url {
+			t.Errorf("%s got header X-Test %q, want %q", c.url, got, c.url)
+		}
+		if got := w.Body.String(); got != c.url {
+			t.Errorf("%s got body %q, want %q", c.url, got, c.url)
+		}
+		if handlerCalls != c.wantCalls {
+			t.Errorf("%s got %d handler calls, want %d", c.url, handlerCalls, c.wantCalls)
+		}
+	}
+}
+
+func TestMiddlewareFlush(t *testing.T) {
+	cache := stalecache.NewLRUMap(10, mapLoader(t))
+	handler := httpmw.MapMiddleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "foo")
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("ResponseWriter is not a http.Flusher")
+		}
+		f.Flush()
+		io.WriteString(w, "bar")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !w.Flushed {
+		t.Error("Response is not flushed")
+	}
+	resp, _, _ := cache.PeekStale("/")
+	if resp == nil || *resp.Data != "foobar" {
+		t.Errorf("Got cached response %+v, want foobar", resp)
+	}
+}
+
+func TestMiddlewareStatus(t *testing.T) {
+	cache := stalecache.NewLRUMap(10, mapLoader(t))
+	data := "created"
+	cache.Update(context.Background(), "/created", &httpmw.Response[string]{
+		StatusCode: http.StatusCreated,
+		Header:     http.Header{"X-Test": {"foo"}},
+		Data:       &data,
+	})
+	var handlerCalls int
+	handler := httpmw.MapMiddleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		http.NotFound(w, r)
+	}))
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/notfound", nil))
+		if got := w.Code; got != http.StatusNotFound {
+			t.Errorf("/notfound got status %d, want %d", got, http.StatusNotFound)
+		}
+	}
+	if handlerCalls != 2 {
+		t.Errorf("Got %d handler calls for not found, want 2", handlerCalls)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/created", nil))
+	if got := w.Code; got != http.StatusCreated {
+		t.Errorf("/created got status %d, want %d", got, http.StatusCreated)
+	}
+	if got := w.Header().Get("X-Test"); got != "foo" {
+		t.Errorf("/created got header X-Test %q, want %q", got, "foo")
+	}
+	if got := w.Body.String(); got != "created" {
+		t.Errorf("/created got body %q, want %q", got, "created")
+	}
+	if handlerCalls != 2 {
+		t.Errorf("Got %d handler calls for cached response, want 2", handlerCalls)
+	}
+}
+
+func TestMiddlewarePrivate(t *testing.T) {
+	cache := stalecache.NewLRUMap(10, mapLoader(t))
+	handlerCalls := make(map[string]int)
+	handler := httpmw.MapMiddleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/cookie":
+			w.Header().Set("Set-Cookie", "session=foo")
+		case "/private":
+			w.Header().Set("Cache-Control", "max-age=60, private")
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/vary":
+			w.Header().Set("Vary", "Accept-Language")
+		case "/public":
+			w.Header().Set("Connection", "X-Hop")
+			w.Header().Set("X-Hop", "foo")
+			w.Header().Set("Keep-Alive", "timeout=5")
+			w.Header().Set("X-Test", "foo")
+		}
+		io.WriteString(w, r.URL.Path)
+	}))
+
+	serve := func(path string, auth bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth {
+			r.Header.Set("Authorization", "Bearer foo")
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for _, path := range []string{"/cookie", "/private", "/no-store", "/vary"} {
+		for i := 0; i < 2; i++ {
+			serve(path, false)
+		}
+		if got := handlerCalls[path]; got != 2 {
+			t.Errorf("%s got %d handler calls, want 2", path, got)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		serve("/auth", true)
+	}
+	if got := handlerCalls["/auth"]; got != 2 {
+		t.Errorf("/auth got %d handler calls, want 2", got)
+	}
+
+	serve("/public", false)
+	w := serve("/public", false)
+	if got := handlerCalls["/public"]; got != 1 {
+		t.Errorf("/public got %d handler calls, want 1", got)
+	}
+	if got := w.Header().Get("X-Test"); got != "foo" {
+		t.Errorf("/public got header X-Test %q, want %q", got, "foo")
+	}
+	for _, k := range []string{"Connection", "X-Hop", "Keep-Alive"} {
+		if got := w.Header().Get(k); got != "" {
+			t.Errorf("/public got header %s %q, want empty", k, got)
+		}
+	}
+}
+
+func TestMiddlewareKeyFunc(t *testing.T) {
+	cache := stalecache.NewLRUMap(10, mapLoader(t))
+	var handlerCalls int
+	handler := httpmw.MapMiddleware(
+		cache,
+		func(s *string) []byte {
+			return []byte(*s)
+		},
+		func(b []byte) (*string, error) {
+			s := string(b)
+			return &s, nil
+		},
+		httpmw.WithKeyFunc(func(r *http.Request) string {
+			return r.URL.Path + "?q=" + r.URL.Query().Get("q")
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalls++
+		io.WriteString(w, r.URL.Query().Get("q"))
+	}))
+
+	for _, c := range []struct {
+		url       string
+		wantCalls int
+	}{
+		{"/?q=foo", 1},
+		{"/?q=foo&random=1", 1},
+		{"/?random=2&q=foo", 1},
+		{"/?q=bar", 2},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, c.url, nil))
+		if handlerCalls != c.wantCalls {
+			t.Errorf("%s got %d handler calls, want %d", c.url, handlerCalls, c.wantCalls)
+		}
+	}
+	if got := cache.Len(); got != 2 {
+		t.Errorf("Got %d keys, want 2", got)
+	}
+}
@@ -0,0 +1,102 @@
+// Package httputil provides HTTP helpers exposing the state of stalecache
+// caches.
+package httputil // import "go.yhsif.com/stalecache/httputil"
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+type httpConfig struct {
+	directives []string
+	authToken  string
+	noAuth     bool
+}
+
+// HTTPOption defines CacheControlMiddleware and InvalidateHandler options.
+type HTTPOption func(*httpConfig)
+
+// WithDirectives is an HTTPOption to add extra directives (for example
+// "private" or "stale-while-revalidate=60") to the Cache-Control header.
+//
+// Default is none, means only max-age is set.
+func WithDirectives(directives ...string) HTTPOption {
+	return func(c *httpConfig) {
+		c.directives = append(c.directives, directives...)
+	}
+}
+
+// CacheControlMiddleware wraps next to set the caching headers according to
+// the current state of c.
+//
+// Before calling next,
+// it calls c.PeekWithVersion (so it never triggers a reload),
+// and when c has a successfully loaded value, it sets:
+//
+//   - Age: seconds since the value was loaded
+//   - Last-Modified: the time the value was loaded
+//   - ETag: the version of the value (see stalecache.Cache.LoadWithVersion)
+//   - Cache-Control: max-age of the remaining ttl, if c has a ttl
+//
+// For GET and HEAD requests,
+// if the If-None-Match header of the request matches the ETag,
+// or (without If-None-Match) the If-Modified-Since header is not older than
+// the time the value was loaded,
+// it responds 304 Not Modified without calling next.
+//
+// When c has no successfully loaded value,
+// next is called without setting any of the headers.
+func CacheControlMiddleware[T any](c *stalecache.Cache[T], next http.Handler, opts ...HTTPOption) http.Handler {
+	var cfg httpConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, loadedAt, version, err := c.PeekWithVersion()
+		if data == nil || err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		etag := fmt.Sprintf(`"%d"`, version)
+		h := w.Header()
+		h.Set("Age", strconv.FormatInt(int64(max(time.Since(loadedAt), 0)/time.Second), 10))
+		h.Set("Last-Modified", loadedAt.UTC().Format(http.TimeFormat))
+		h.Set("ETag", etag)
+		if ttl := c.PeekRemainingTTL(); ttl != 0 {
+			directives := append(
+				[]string{"max-age=" + strconv.FormatInt(int64(max(ttl, 0)/time.Second), 10)},
+				cfg.directives...,
+			)
+			h.Set("Cache-Control", strings.Join(directives, ", "))
+		}
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag, loadedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// notModified returns true if the conditional headers of r match etag and
+// loadedAt.
+func notModified(r *http.Request, etag string, loadedAt time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !loadedAt.Truncate(time.Second).After(t)
+	}
+	return false
+}
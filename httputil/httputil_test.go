@@ -0,0 +1,89 @@
+package httputil_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/httputil"
+)
+
+func TestCacheControlMiddleware(t *testing.T) {
+	const body = "hello"
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			data := body
+			return &data, nil
+		},
+		stalecache.WithTTL[string](time.Hour),
+	)
+	var handlerCalls int
+	handler := httputil.CacheControlMiddleware(
+		cache,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCalls++
+			data, _ := cache.Load(r.Context())
+			io.WriteString(w, *data)
+		}),
+		httputil.WithDirectives("private"),
+	)
+	serve := func(t *testing.T, header http.Header, wantCode, wantCalls int) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != wantCode {
+			t.Errorf("Got code %d, want %d", w.Code, wantCode)
+		}
+		if handlerCalls != wantCalls {
+			t.Errorf("Got %d handler calls, want %d", handlerCalls, wantCalls)
+		}
+		return w
+	}
+
+	t.Run("not-loaded", func(t *testing.T) {
+		w := serve(t, nil, http.StatusOK, 1)
+		if etag := w.Header().Get("ETag"); etag != "" {
+			t.Errorf("Got ETag %q before loaded", etag)
+		}
+	})
+
+	w := serve(t, nil, http.StatusOK, 2)
+	etag := w.Header().Get("ETag")
+	lastModified := w.Header().Get("Last-Modified")
+	t.Run("headers", func(t *testing.T) {
+		if etag != `"1"` {
+			t.Errorf("Got ETag %q, want %q", etag, `"1"`)
+		}
+		if lastModified == "" {
+			t.Error("Got empty Last-Modified")
+		}
+		if got, want := w.Header().Get("Cache-Control"), "max-age=3599, private"; got != want && got != "max-age=3600, private" {
+			t.Errorf("Got Cache-Control %q, want %q", got, want)
+		}
+		if got := w.Header().Get("Age"); got != "0" {
+			t.Errorf("Got Age %q, want 0", got)
+		}
+		if got := w.Body.String(); got != body {
+			t.Errorf("Got body %q, want %q", got, body)
+		}
+	})
+
+	t.Run("if-none-match", func(t *testing.T) {
+		serve(t, http.Header{"If-None-Match": {`"0", ` + etag}}, http.StatusNotModified, 2)
+		serve(t, http.Header{"If-None-Match": {`"0"`}}, http.StatusOK, 3)
+	})
+
+	t.Run("if-modified-since", func(t *testing.T) {
+		serve(t, http.Header{"If-Modified-Since": {lastModified}}, http.StatusNotModified, 3)
+		old := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		serve(t, http.Header{"If-Modified-Since": {old}}, http.StatusOK, 4)
+	})
+}
@@ -0,0 +1,120 @@
+package httputil
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.yhsif.com/stalecache"
+)
+
+// WithAuthToken is an HTTPOption to require the bearer token of the
+// Authorization header for the POST requests of InvalidateHandler.
+//
+// Default is empty, means all POST requests are rejected,
+// unless WithoutAuth is used.
+func WithAuthToken(token string) HTTPOption {
+	return func(c *httpConfig) {
+		c.authToken = token
+	}
+}
+
+// WithoutAuth is an HTTPOption to allow the POST requests of
+// InvalidateHandler without any authorization.
+//
+// It should only be used when the handler is protected by other means,
+// for example only served on an internal port or behind an authenticating
+// middleware.
+// It takes precedence over WithAuthToken.
+func WithoutAuth() HTTPOption {
+	return func(c *httpConfig) {
+		c.noAuth = true
+	}
+}
+
+// invalidateBody is the json body of the POST requests of InvalidateHandler.
+type invalidateBody struct {
+	Version uint64 `json:"version"`
+}
+
+// statsBody is the json body of the GET requests of InvalidateHandler.
+type statsBody struct {
+	Hits       uint64 `json:"hits"`
+	Misses     uint64 `json:"misses"`
+	Loads      uint64 `json:"loads"`
+	LoadErrors uint64 `json:"loadErrors"`
+}
+
+// InvalidateHandler returns a http.Handler to manage c,
+// for example to be served at "/admin/cache/invalidate".
+//
+// On POST requests it calls c.Reset,
+// or c.ForceReload with the request context when the query parameter
+// "reload" is "true",
+// and responds with the version before that as json
+// (for example {"version":42}).
+// If the reload fails it responds 502 Bad Gateway with the error instead.
+// POST requests without the bearer token set by WithAuthToken get 401
+// Unauthorized, and so do all POST requests when neither WithAuthToken nor
+// WithoutAuth is used.
+//
+// On GET and HEAD requests it responds with c.Stats as json
+// (for example {"hits":1,"misses":1,"loads":1,"loadErrors":0}).
+//
+// Other methods get 405 Method Not Allowed.
+func InvalidateHandler[T any](c *stalecache.Cache[T], opts ...HTTPOption) http.Handler {
+	var cfg httpConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		default:
+			w.Header().Set("Allow", "GET, HEAD, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		case http.MethodGet, http.MethodHead:
+			stats := c.Stats()
+			writeJSON(w, statsBody{
+				Hits:       stats.Hits,
+				Misses:     stats.Misses,
+				Loads:      stats.Loads,
+				LoadErrors: stats.LoadErrors,
+			})
+
+		case http.MethodPost:
+			if !cfg.noAuth && !authorized(r, cfg.authToken) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			_, _, version, _ := c.PeekWithVersion()
+			if reload, _ := strconv.ParseBool(r.URL.Query().Get("reload")); reload {
+				if _, err := c.ForceReload(r.Context()); err != nil {
+					http.Error(w, err.Error(), http.StatusBadGateway)
+					return
+				}
+			} else {
+				c.Reset()
+			}
+			writeJSON(w, invalidateBody{Version: version})
+		}
+	})
+}
+
+// authorized returns true if token is not empty,
+// and r has it as the bearer token.
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,116 @@
+package httputil_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/httputil"
+)
+
+func TestInvalidateHandler(t *testing.T) {
+	const token = "secret"
+	var calls int
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		calls++
+		data := calls
+		return &data, nil
+	})
+	ctx := context.Background()
+	handler := httputil.InvalidateHandler(cache, httputil.WithAuthToken(token))
+	serve := func(t *testing.T, method, target, auth string, wantCode int, body any) {
+		t.Helper()
+		r := httptest.NewRequest(method, target, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", "Bearer "+auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != wantCode {
+			t.Fatalf("Got code %d, want %d: %s", w.Code, wantCode, w.Body)
+		}
+		if body != nil {
+			if err := json.NewDecoder(w.Body).Decode(body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+		}
+	}
+
+	if _, err := cache.Load(ctx); err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	_, version, err := cache.LoadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("LoadWithVersion returned error: %v", err)
+	}
+
+	t.Run("stats", func(t *testing.T) {
+		var stats struct {
+			Hits  uint64 `json:"hits"`
+			Loads uint64 `json:"loads"`
+		}
+		serve(t, http.MethodGet, "/", "", http.StatusOK, &stats)
+		if stats.Hits != 1 || stats.Loads != 1 {
+			t.Errorf("Got stats %+v, want 1 hit and 1 load", stats)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		serve(t, http.MethodPost, "/", "", http.StatusUnauthorized, nil)
+		serve(t, http.MethodPost, "/", "wrong", http.StatusUnauthorized, nil)
+		if data, _, _ := cache.Peek(); data == nil {
+			t.Error("Cache reset without authorization")
+		}
+	})
+
+	t.Run("method", func(t *testing.T) {
+		serve(t, http.MethodDelete, "/", token, http.StatusMethodNotAllowed, nil)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		var body struct {
+			Version uint64 `json:"version"`
+		}
+		serve(t, http.MethodPost, "/?reload=true", token, http.StatusOK, &body)
+		if body.Version != version {
+			t.Errorf("Got version %d, want %d", body.Version, version)
+		}
+		if data, _, _ := cache.Peek(); data == nil || *data != 2 {
+			t.Errorf("Got data %v after reload, want 2", data)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		serve(t, http.MethodPost, "/", token, http.StatusOK, nil)
+		if data, _, _ := cache.Peek(); data != nil {
+			t.Errorf("Got data %v after reset, want nil", *data)
+		}
+	})
+}
+
+func TestInvalidateHandlerAuth(t *testing.T) {
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		data := 1
+		return &data, nil
+	})
+	for _, c := range []struct {
+		label    string
+		opts     []httputil.HTTPOption
+		wantCode int
+	}{
+		{"default", nil, http.StatusUnauthorized},
+		{"empty-token", []httputil.HTTPOption{httputil.WithAuthToken("")}, http.StatusUnauthorized},
+		{"without-auth", []httputil.HTTPOption{httputil.WithoutAuth()}, http.StatusOK},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			httputil.InvalidateHandler(cache, c.opts...).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+			if w.Code != c.wantCode {
+				t.Errorf("Got code %d, want %d: %s", w.Code, c.wantCode, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// WithInvalidationChannel is an Option to Reset the cache every time a signal
+// is received from ch,
+// for example from database notifications,
+// so the next Load calls the loader.
+//
+// Default is no channel.
+// It's not supported by Map and LRUMap (NewMap and NewLRUMap panic with it),
+// as every signal would only be received by one of the keys.
+// It can be used multiple times to listen to multiple channels.
+// The background goroutine reading from ch returns when ch is closed or when
+// Close is called.
+func WithInvalidationChannel[T any](ch <-chan struct{}) Option[T] {
+	return func(o *opt[T]) {
+		o.invalidations = append(o.invalidations, ch)
+	}
+}
+
+func (c *Cache[T]) listenInvalidations(ctx context.Context, ch <-chan struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.Reset()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestInvalidationChannel(t *testing.T) {
+	ch := make(chan struct{}, 1)
+	var calls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			data := calls.Add(1)
+			return &data, nil
+		},
+		stalecache.WithInvalidationChannel[int64](ch),
+	)
+	defer cache.Close()
+
+	load := func(t *testing.T, want int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	load(t, 1)
+	load(t, 1)
+
+	ch <- struct{}{}
+	deadline := time.Now().Add(time.Second)
+	for !cache.IsStale(context.Background()) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	load(t, 2)
+	load(t, 2)
+
+	close(ch)
+}
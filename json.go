@@ -0,0 +1,98 @@
+package stalecache
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type jsonSnapshot struct {
+	Data           json.RawMessage `json:"data,omitempty"`
+	LoadedAt       *time.Time      `json:"loaded_at"`
+	TTLRemainingMS *int64          `json:"ttl_remaining_ms"`
+	Error          *string         `json:"error"`
+}
+
+var (
+	_ json.Marshaler   = (*Cache[int])(nil)
+	_ json.Unmarshaler = (*Cache[int])(nil)
+)
+
+// MarshalJSON implements json.Marshaler.
+//
+// It produces a snapshot of the current state of the cache, for example:
+//
+//	{"data":...,"loaded_at":"...","ttl_remaining_ms":4200,"error":null}
+//
+// "ttl_remaining_ms" is null when there's no ttl,
+// and it's negative when the cached value is stale.
+// If the cached value cannot be marshaled to JSON,
+// "data" is omitted and only the metadata is produced.
+//
+// MarshalJSON never calls the loader.
+func (c *Cache[T]) MarshalJSON() ([]byte, error) {
+	var snapshot jsonSnapshot
+	if curr := c.current(); curr.done.Load() {
+		loaded := curr.loaded
+		snapshot.LoadedAt = &loaded
+		if c.ttl(curr) > 0 {
+			remaining := c.expiry(curr).Sub(c.opt.now()).Milliseconds()
+			snapshot.TTLRemainingMS = &remaining
+		}
+		if curr.err != nil {
+			msg := curr.err.Error()
+			snapshot.Error = &msg
+		}
+		if curr.data != nil {
+			if data, err := json.Marshal(curr.data); err == nil {
+				snapshot.Data = data
+			}
+		}
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+//
+// It restores the state of the cache from a snapshot produced by MarshalJSON,
+// including the time the value was loaded,
+// so the ttl is correctly calculated from the original load.
+// A restored error is only kept as its message,
+// and causes the next Load to call the loader.
+// A snapshot of a cache never loaded puts the cache back to the never-loaded
+// state.
+//
+// UnmarshalJSON never calls the loader.
+func (c *Cache[T]) UnmarshalJSON(b []byte) error {
+	var snapshot jsonSnapshot
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.LoadedAt == nil {
+		c.invalidate()
+		return nil
+	}
+	var data *T
+	if len(snapshot.Data) > 0 && string(snapshot.Data) != "null" {
+		data = new(T)
+		if err := json.Unmarshal(snapshot.Data, data); err != nil {
+			return err
+		}
+	}
+	var err error
+	if snapshot.Error != nil {
+		err = errors.New(*snapshot.Error)
+	}
+	entry := new(cached[T])
+	if err == nil {
+		entry.version = c.version.Add(1)
+		entry.ttl = c.dynamicTTL(data)
+	}
+	entry.set(data, *snapshot.LoadedAt, err)
+	c.init()
+	c.evict(c.cached.Swap(entry).good())
+	if err == nil {
+		c.everLoaded.Store(true)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package stalecache_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestCacheJSON(t *testing.T) {
+	type data struct {
+		Foo string `json:"foo"`
+	}
+	const ttl = time.Hour
+	var loaderCalls atomic.Int64
+	newCache := func() *stalecache.Cache[data] {
+		return stalecache.New(
+			func(context.Context) (*data, error) {
+				loaderCalls.Add(1)
+				return &data{Foo: "bar"}, nil
+			},
+			stalecache.WithTTL[data](ttl),
+		)
+	}
+
+	t.Run("never-loaded", func(t *testing.T) {
+		b, err := json.Marshal(newCache())
+		if err != nil {
+			t.Fatalf("Marshal got error: %v", err)
+		}
+		const want = `{"loaded_at":null,"ttl_remaining_ms":null,"error":null}`
+		if string(b) != want {
+			t.Errorf("Marshal got %s, want %s", b, want)
+		}
+	})
+
+	t.Run("round-trip", func(t *testing.T) {
+		src := newCache()
+		src.Load(context.Background())
+		b, err := json.Marshal(src)
+		if err != nil {
+			t.Fatalf("Marshal got error: %v", err)
+		}
+		t.Logf("Marshal: %s", b)
+		if !strings.Contains(string(b), `"data":{"foo":"bar"}`) {
+			t.Errorf("Marshal got %s, want data", b)
+		}
+
+		loaderCalls.Store(0)
+		dst := newCache()
+		if err := json.Unmarshal(b, dst); err != nil {
+			t.Fatalf("Unmarshal got error: %v", err)
+		}
+		got, err := dst.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if got.Foo != "bar" {
+			t.Errorf("Load got %+v, want bar", got)
+		}
+		if calls := loaderCalls.Load(); calls != 0 {
+			t.Errorf("Got %d loader calls after Unmarshal, want 0", calls)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*chan int, error) {
+			ch := make(chan int)
+			return &ch, nil
+		})
+		cache.Load(context.Background())
+		b, err := json.Marshal(cache)
+		if err != nil {
+			t.Fatalf("Marshal got error: %v", err)
+		}
+		if strings.Contains(string(b), `"data"`) {
+			t.Errorf("Marshal got %s, want no data", b)
+		}
+	})
+}
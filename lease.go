@@ -0,0 +1,69 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+)
+
+// lease is the per key lease of a Map,
+// held by the in-flight loader call of the key.
+//
+// It's implemented as a 1-buffered channel so that acquiring it can be
+// canceled.
+type lease struct {
+	ch chan struct{}
+	// refs is the number of loader calls holding or waiting for the lease,
+	// guarded by Map.leaseMu.
+	refs int
+}
+
+// leased wraps the loader of key with the lease of key,
+// so there's at most one loader call of the same key at any time,
+// even across different Cache instances of the same key
+// (for example when the key is deleted or reaped while its loader is still
+// running).
+//
+// A loader call waiting for the lease gives up when its ctx is canceled,
+// which happens after all the Load calls waiting for it gave up.
+func (m *Map[K, T]) leased(key K) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		l := m.acquireLease(key)
+		defer m.releaseLease(key, l)
+		select {
+		case l.ch <- struct{}{}:
+		case <-ctx.Done():
+			return nil, &CacheError{
+				Code: CodeCanceled,
+				Err:  fmt.Errorf("failed to acquire the lease of key %v: %w", key, ctx.Err()),
+			}
+		}
+		defer func() {
+			<-l.ch
+		}()
+		return m.loader(ctx, key)
+	}
+}
+
+func (m *Map[K, T]) acquireLease(key K) *lease {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+	if m.leases == nil {
+		m.leases = make(map[K]*lease)
+	}
+	l := m.leases[key]
+	if l == nil {
+		l = &lease{ch: make(chan struct{}, 1)}
+		m.leases[key] = l
+	}
+	l.refs++
+	return l
+}
+
+func (m *Map[K, T]) releaseLease(key K, l *lease) {
+	m.leaseMu.Lock()
+	defer m.leaseMu.Unlock()
+	l.refs--
+	if l.refs == 0 {
+		delete(m.leases, key)
+	}
+}
@@ -0,0 +1,73 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestMapLease(t *testing.T) {
+	const key = "foo"
+	var concurrent, calls atomic.Int64
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	m := stalecache.NewMap(func(_ context.Context, key string) (*string, error) {
+		defer concurrent.Add(-1)
+		if n := concurrent.Add(1); n != 1 {
+			t.Errorf("Got %d concurrent loader calls, want 1", n)
+		}
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		return &key, nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := m.Load(context.Background(), key); err != nil {
+			t.Errorf("Load returned error: %v", err)
+		}
+	}()
+	<-started
+
+	// The new Cache of key after Delete must wait for the in-flight loader
+	// call of the old one.
+	m.Delete(key)
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+		defer cancel()
+		_, err := m.Load(ctx, key)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Load got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		data, err := m.Load(context.Background(), key)
+		if err != nil {
+			t.Errorf("Load returned error: %v", err)
+			return
+		}
+		if *data != key {
+			t.Errorf("Load got %q, want %q", *data, key)
+		}
+	}()
+	time.Sleep(5 * time.Millisecond)
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Got %d loader calls before release, want 1", got)
+	}
+	close(release)
+	<-done
+	<-started
+	<-loaded
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Got %d loader calls, want 2", got)
+	}
+}
@@ -0,0 +1,130 @@
+package stalecache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRUMap is a Map with a capacity,
+// which evicts the least recently used key when the capacity is exceeded.
+type LRUMap[K comparable, T any] struct {
+	loader   MapLoader[K, T]
+	options  []Option[T]
+	capacity int
+
+	mu    sync.Mutex
+	order *list.List          // of *lruEntry[K, T], most recently used first
+	items map[K]*list.Element // value is *lruEntry[K, T]
+}
+
+type lruEntry[K comparable, T any] struct {
+	key   K
+	cache *Cache[T]
+}
+
+// NewLRUMap creates a new LRUMap with capacity, loader and options.
+//
+// The options are applied to the Cache of every key,
+// same as NewMap,
+// including WithBatchLoader and the panics with the Options not supported by
+// Map.
+// capacity must be positive.
+func NewLRUMap[K comparable, T any](capacity int, loader MapLoader[K, T], options ...Option[T]) *LRUMap[K, T] {
+	if capacity <= 0 {
+		panic("stalecache.NewLRUMap: capacity must be positive")
+	}
+	return &LRUMap[K, T]{
+		loader:   mapLoader(loader, options),
+		options:  append([]Option[T]{WithGlobalPool[T](NewGlobalPool[T]())}, options...),
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[K]*list.Element, capacity),
+	}
+}
+
+// cache returns the Cache of key and marks it as the most recently used,
+// creating it (and evicting the least recently used key) if needed.
+func (m *LRUMap[K, T]) cache(key K) *Cache[T] {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.items[key]; ok {
+		m.order.MoveToFront(e)
+		return e.Value.(*lruEntry[K, T]).cache
+	}
+	c := New(func(ctx context.Context) (*T, error) {
+		return m.loader(ctx, key)
+	}, m.options...)
+	m.items[key] = m.order.PushFront(&lruEntry[K, T]{key: key, cache: c})
+	if m.order.Len() > m.capacity {
+		m.remove(m.order.Back())
+	}
+	return c
+}
+
+// remove removes e from m.
+//
+// m.mu must be held.
+func (m *LRUMap[K, T]) remove(e *list.Element) {
+	entry := m.order.Remove(e).(*lruEntry[K, T])
+	delete(m.items, entry.key)
+	entry.cache.Close()
+}
+
+// Load loads the cached value of key,
+// and marks key as the most recently used.
+//
+// It has the same semantics as Cache.Load.
+func (m *LRUMap[K, T]) Load(ctx context.Context, key K) (*T, error) {
+	return m.cache(key).Load(ctx)
+}
+
+// Update updates the cached value of key with value and current timestamp,
+// and marks key as the most recently used.
+//
+// It has the same semantics as Cache.Update.
+func (m *LRUMap[K, T]) Update(ctx context.Context, key K, value *T) error {
+	return m.cache(key).Update(ctx, value)
+}
+
+// PeekStale returns the cached value of key without triggering a reload,
+// and marks key as the most recently used if it's in the LRUMap.
+//
+// It has the same semantics as Cache.PeekStale,
+// and returns nil, zero time, and false if key is not in the LRUMap,
+// without adding it.
+func (m *LRUMap[K, T]) PeekStale(key K) (data *T, loadedAt time.Time, isStale bool) {
+	m.mu.Lock()
+	e, ok := m.items[key]
+	if ok {
+		m.order.MoveToFront(e)
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	return e.Value.(*lruEntry[K, T]).cache.PeekStale()
+}
+
+// Delete deletes key from the LRUMap.
+//
+// The next Load of key will call the loader.
+func (m *LRUMap[K, T]) Delete(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+}
+
+// Len returns the number of keys currently in the LRUMap.
+func (m *LRUMap[K, T]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.order.Len()
+}
@@ -0,0 +1,139 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestLRUMap(t *testing.T) {
+	const capacity = 2
+	var loaderCalls sync.Map // map[string]*atomic.Int64
+	calls := func(key string) *atomic.Int64 {
+		v, _ := loaderCalls.LoadOrStore(key, new(atomic.Int64))
+		return v.(*atomic.Int64)
+	}
+	m := stalecache.NewLRUMap(capacity, func(_ context.Context, key string) (*string, error) {
+		calls(key).Add(1)
+		return &key, nil
+	})
+
+	load := func(t *testing.T, key string) {
+		t.Helper()
+		data, err := m.Load(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Load(%q) got error: %v", key, err)
+		}
+		if *data != key {
+			t.Errorf("Load(%q) got %q", key, *data)
+		}
+	}
+	check := func(t *testing.T, key string, want int64) {
+		t.Helper()
+		if got := calls(key).Load(); got != want {
+			t.Errorf("Got %d loader calls for %q, want %d", got, key, want)
+		}
+	}
+
+	load(t, "foo")
+	load(t, "bar")
+	// foo is now the most recently used, so bar is evicted by baz.
+	load(t, "foo")
+	load(t, "baz")
+	if got := m.Len(); got != capacity {
+		t.Errorf("Len got %d, want %d", got, capacity)
+	}
+	check(t, "foo", 1)
+	check(t, "bar", 1)
+	check(t, "baz", 1)
+
+	load(t, "bar")
+	check(t, "bar", 2)
+	// foo was evicted by bar
+	load(t, "baz")
+	check(t, "baz", 1)
+	load(t, "foo")
+	check(t, "foo", 2)
+
+	m.Delete("foo")
+	if got := m.Len(); got != capacity-1 {
+		t.Errorf("Len got %d after Delete, want %d", got, capacity-1)
+	}
+}
+
+func TestLRUMapPeekStale(t *testing.T) {
+	const ttl = time.Millisecond
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	m := stalecache.NewLRUMap(
+		2,
+		func(_ context.Context, key string) (*string, error) {
+			loaderCalls.Add(1)
+			return &key, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+	)
+
+	if data, _, _ := m.PeekStale("foo"); data != nil {
+		t.Errorf("PeekStale before Load got %q", *data)
+	}
+	if got := m.Len(); got != 0 {
+		t.Errorf("Len after PeekStale got %d, want 0", got)
+	}
+
+	m.Load(context.Background(), "foo")
+	if data, _, stale := m.PeekStale("foo"); data == nil || *data != "foo" || stale {
+		t.Errorf("PeekStale got %v, %v, want foo, fresh", data, stale)
+	}
+	clock.Advance(ttl)
+	if data, _, stale := m.PeekStale("foo"); data == nil || !stale {
+		t.Errorf("PeekStale after ttl got %v, %v, want stale foo", data, stale)
+	}
+
+	// foo is now the most recently used, so bar is evicted by baz.
+	m.Load(context.Background(), "bar")
+	m.PeekStale("foo")
+	m.Load(context.Background(), "baz")
+	if data, _, _ := m.PeekStale("bar"); data != nil {
+		t.Errorf("PeekStale of evicted key got %q", *data)
+	}
+	if data, _, _ := m.PeekStale("foo"); data == nil {
+		t.Error("PeekStale of foo got nil, want not evicted")
+	}
+	if got := loaderCalls.Load(); got != 3 {
+		t.Errorf("Got %d loader calls, want 3", got)
+	}
+}
+
+func TestLRUMapBatchLoader(t *testing.T) {
+	var batches atomic.Int64
+	m := stalecache.NewLRUMap[int, int](
+		2,
+		nil,
+		stalecache.WithBatchLoader(func(_ context.Context, keys []int) (map[int]*int, error) {
+			batches.Add(1)
+			result := make(map[int]*int, len(keys))
+			for _, key := range keys {
+				data := key * 2
+				result[key] = &data
+			}
+			return result, nil
+		}, time.Millisecond),
+	)
+	data, err := m.Load(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Load(1) got error: %v", err)
+	}
+	if *data != 2 {
+		t.Errorf("Load(1) got %d, want 2", *data)
+	}
+	if got := batches.Load(); got != 1 {
+		t.Errorf("Got %d batch loader calls, want 1", got)
+	}
+}
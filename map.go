@@ -0,0 +1,187 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MapLoader defines the callback to load value of key from external source.
+type MapLoader[K comparable, T any] func(ctx context.Context, key K) (*T, error)
+
+// Map is a cache of keyed values.
+//
+// Every key is cached independently as if it's its own Cache,
+// with its own ttl and coalesced loader calls.
+// There's at most one loader call of the same key at any time,
+// including the ones started before the key is deleted (by Delete or the
+// reaper).
+type Map[K comparable, T any] struct {
+	loader  MapLoader[K, T]
+	options []Option[T]
+
+	caches sync.Map // map[K]*Cache[T]
+
+	leaseMu sync.Mutex
+	leases  map[K]*lease
+
+	reaperMu     sync.Mutex
+	reaperCancel context.CancelFunc
+	reaperDone   chan struct{}
+	reaped       atomic.Uint64
+}
+
+// NewMap creates a new Map with loader and options.
+//
+// The options are applied to the Cache of every key.
+// Unless WithGlobalPool is used, caches of all the keys share the same pool.
+//
+// When WithBatchLoader is used, loader is ignored and can be nil.
+// It panics if the key type of WithBatchLoader is not K,
+// or with the Options not supported by Map:
+// WithPubSub, as the values published by a key are not keyed,
+// WithInvalidationChannel, as a signal would only reach one of the keys,
+// and WithCacheWarming, as the warmed values are not keyed.
+func NewMap[K comparable, T any](loader MapLoader[K, T], options ...Option[T]) *Map[K, T] {
+	return &Map[K, T]{
+		loader: mapLoader(loader, options),
+		// prepended so it can still be overridden by options.
+		options: append([]Option[T]{WithGlobalPool[T](NewGlobalPool[T]())}, options...),
+	}
+}
+
+// mapLoader returns the loader to be used by a Map with loader and options,
+// which is the one from WithBatchLoader if it's used.
+//
+// It panics if the key type of WithBatchLoader is not K,
+// or with the Options not supported by Map.
+func mapLoader[K comparable, T any](loader MapLoader[K, T], options []Option[T]) MapLoader[K, T] {
+	var o opt[T]
+	for _, option := range options {
+		option(&o)
+	}
+	if o.pubsub != nil {
+		// Every key would apply the values published by all the other keys.
+		panic("stalecache: WithPubSub is not supported by Map")
+	}
+	if len(o.invalidations) > 0 {
+		// Every key would read from the same channels.
+		panic("stalecache: WithInvalidationChannel is not supported by Map")
+	}
+	if o.warmer != nil {
+		// Every key would be updated with the same values.
+		panic("stalecache: WithCacheWarming is not supported by Map")
+	}
+	if o.batcher == nil {
+		return loader
+	}
+	b, ok := o.batcher.(*batcher[K, T])
+	if !ok {
+		panic(fmt.Sprintf(
+			"stalecache: WithBatchLoader key type mismatch, want %v",
+			reflect.TypeOf((*K)(nil)).Elem(),
+		))
+	}
+	return b.load
+}
+
+func (m *Map[K, T]) cache(key K) *Cache[T] {
+	if c, ok := m.caches.Load(key); ok {
+		return c.(*Cache[T])
+	}
+	c := New(m.leased(key), m.options...)
+	actual, loaded := m.caches.LoadOrStore(key, c)
+	if loaded {
+		c.Close()
+	}
+	return actual.(*Cache[T])
+}
+
+// Load loads the cached value of key.
+//
+// It has the same semantics as Cache.Load.
+func (m *Map[K, T]) Load(ctx context.Context, key K) (*T, error) {
+	return m.cache(key).Load(ctx)
+}
+
+// LoadWithTTL loads the cached value of key,
+// using ttl instead of the ttl configured for the Map to decide whether the
+// cached value is fresh.
+//
+// The override only applies to this call,
+// other Load calls of the same key still use the configured ttl.
+// A non-positive ttl means no override, same as Load.
+func (m *Map[K, T]) LoadWithTTL(ctx context.Context, key K, ttl time.Duration) (*T, error) {
+	return m.cache(key).loadWithTTL(ctx, ttl)
+}
+
+// Update updates the cached value of key with value and current timestamp.
+//
+// It has the same semantics as Cache.Update.
+func (m *Map[K, T]) Update(ctx context.Context, key K, value *T) error {
+	return m.cache(key).Update(ctx, value)
+}
+
+// CacheEntry is a loaded value,
+// used by Map.Snapshot and HistoricalCache.History.
+type CacheEntry[T any] struct {
+	Data     *T
+	LoadedAt time.Time
+	// Err is the error returned by the loader,
+	// always nil in Map.Snapshot.
+	Err error
+}
+
+// Snapshot returns the successfully loaded values of all keys in the Map,
+// regardless of whether they are stale.
+//
+// Keys never loaded, failed, or being loaded are not included.
+// Combined with Restore,
+// it can be used to warm up a new process with the caller's choice of
+// serialization.
+func (m *Map[K, T]) Snapshot() map[K]CacheEntry[T] {
+	snap := make(map[K]CacheEntry[T])
+	m.caches.Range(func(key, c any) bool {
+		data, loadedAt, _ := c.(*Cache[T]).PeekStale()
+		if data != nil {
+			snap[key.(K)] = CacheEntry[T]{
+				Data:     data,
+				LoadedAt: loadedAt,
+			}
+		}
+		return true
+	})
+	return snap
+}
+
+// Restore restores the values of keys from snap, usually from Snapshot.
+//
+// The time the values were loaded is restored as well,
+// so the ttl is correctly calculated from the original load.
+// Keys already having values loaded at or after the LoadedAt in snap are kept
+// as-is, so concurrent loads during Restore are never overridden by older
+// values.
+// Entries with nil Data are ignored.
+//
+// Restore never calls the loader.
+func (m *Map[K, T]) Restore(snap map[K]CacheEntry[T]) {
+	for key, entry := range snap {
+		if entry.Data == nil {
+			continue
+		}
+		m.cache(key).restore(entry.Data, entry.LoadedAt)
+	}
+}
+
+// Delete deletes key from the Map.
+//
+// The next Load of key will call the loader,
+// after the in-flight loader call of key (if any) returns.
+func (m *Map[K, T]) Delete(key K) {
+	if c, ok := m.caches.LoadAndDelete(key); ok {
+		c.(*Cache[T]).Close()
+	}
+}
@@ -0,0 +1,255 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestMap(t *testing.T) {
+	const (
+		ttl   = 10 * time.Millisecond
+		sleep = 5 * time.Millisecond
+		n     = 5
+	)
+	var loaderCalls sync.Map // map[string]*atomic.Int64
+	calls := func(key string) *atomic.Int64 {
+		v, _ := loaderCalls.LoadOrStore(key, new(atomic.Int64))
+		return v.(*atomic.Int64)
+	}
+	clock := stalecachetest.NewFakeClock(time.Now())
+	m := stalecache.NewMap(
+		func(_ context.Context, key string) (*string, error) {
+			calls(key).Add(1)
+			time.Sleep(sleep)
+			if key == "" {
+				return nil, errors.New("empty key")
+			}
+			return &key, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+	)
+
+	keys := []string{"foo", "bar"}
+	load := func(t *testing.T) {
+		t.Helper()
+		var wg sync.WaitGroup
+		for _, key := range keys {
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func(key string) {
+					defer wg.Done()
+					data, err := m.Load(context.Background(), key)
+					if err != nil {
+						t.Errorf("Load(%q) got error: %v", key, err)
+						return
+					}
+					if *data != key {
+						t.Errorf("Load(%q) got %q", key, *data)
+					}
+				}(key)
+			}
+		}
+		wg.Wait()
+	}
+	checkCalls := func(t *testing.T, want int64) {
+		t.Helper()
+		for _, key := range keys {
+			if got := calls(key).Load(); got != want {
+				t.Errorf("Got %d loader calls for %q, want %d", got, key, want)
+			}
+		}
+	}
+
+	t.Run("load", func(t *testing.T) {
+		load(t)
+		checkCalls(t, 1)
+	})
+	t.Run("fresh", func(t *testing.T) {
+		load(t)
+		checkCalls(t, 1)
+	})
+	t.Run("stale", func(t *testing.T) {
+		clock.Advance(ttl)
+		load(t)
+		checkCalls(t, 2)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		if _, err := m.Load(context.Background(), ""); err == nil {
+			t.Error("Load with empty key got no error")
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		s := "updated"
+		m.Update(context.Background(), "foo", &s)
+		data, err := m.Load(context.Background(), "foo")
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != s {
+			t.Errorf("Load got %q, want %q", *data, s)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		m.Delete("foo")
+		data, err := m.Load(context.Background(), "foo")
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != "foo" {
+			t.Errorf("Load got %q, want %q", *data, "foo")
+		}
+		if got := calls("foo").Load(); got != 3 {
+			t.Errorf("Got %d loader calls after Delete, want 3", got)
+		}
+	})
+}
+
+func TestMapSnapshot(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	clock := stalecachetest.NewFakeClock(time.Now())
+	newMap := func(calls *atomic.Int64) *stalecache.Map[string, string] {
+		return stalecache.NewMap(
+			func(_ context.Context, key string) (*string, error) {
+				calls.Add(1)
+				data := key + "-loaded"
+				return &data, nil
+			},
+			stalecache.WithTTL[string](ttl),
+			stalecache.WithClock[string](clock),
+		)
+	}
+
+	var oldCalls atomic.Int64
+	old := newMap(&oldCalls)
+	for _, key := range []string{"foo", "bar"} {
+		if _, err := old.Load(context.Background(), key); err != nil {
+			t.Fatalf("Load(%q) got error: %v", key, err)
+		}
+	}
+	snap := old.Snapshot()
+	if len(snap) != 2 {
+		t.Fatalf("Snapshot got %d keys, want 2: %v", len(snap), snap)
+	}
+
+	var newCalls atomic.Int64
+	m := newMap(&newCalls)
+	newer := "newer"
+	clock.Advance(ttl / 2)
+	m.Update(context.Background(), "bar", &newer)
+	m.Restore(snap)
+
+	if data, err := m.Load(context.Background(), "foo"); err != nil || *data != "foo-loaded" {
+		t.Errorf("Load(foo) got %v, %v, want foo-loaded", data, err)
+	}
+	if data, err := m.Load(context.Background(), "bar"); err != nil || *data != newer {
+		t.Errorf("Load(bar) got %v, %v, want %q", data, err, newer)
+	}
+	if got := newCalls.Load(); got != 0 {
+		t.Errorf("Got %d loader calls after Restore, want 0", got)
+	}
+
+	// ttl is calculated from the original load.
+	clock.Advance(ttl / 2)
+	if data, err := m.Load(context.Background(), "foo"); err != nil || *data != "foo-loaded" {
+		t.Errorf("Load(foo) got %v, %v, want foo-loaded", data, err)
+	}
+	if got := newCalls.Load(); got != 1 {
+		t.Errorf("Got %d loader calls after ttl, want 1", got)
+	}
+}
+
+func TestMapLoadWithTTL(t *testing.T) {
+	const (
+		ttl   = 10 * time.Millisecond
+		short = 2 * time.Millisecond
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls atomic.Int64
+	m := stalecache.NewMap(
+		func(_ context.Context, key string) (*string, error) {
+			calls.Add(1)
+			return &key, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+	)
+	load := func(t *testing.T, ttl time.Duration, want int64) {
+		t.Helper()
+		data, err := m.LoadWithTTL(context.Background(), "foo", ttl)
+		if err != nil || *data != "foo" {
+			t.Errorf("LoadWithTTL(%v) got %v, %v, want foo", ttl, data, err)
+		}
+		if got := calls.Load(); got != want {
+			t.Errorf("Got %d loader calls after LoadWithTTL(%v), want %d", got, ttl, want)
+		}
+	}
+
+	load(t, short, 1)
+	load(t, short, 1)
+
+	clock.Advance(short)
+	load(t, short, 2)
+
+	// Plain Load still uses the configured ttl.
+	clock.Advance(short)
+	if _, err := m.Load(context.Background(), "foo"); err != nil {
+		t.Errorf("Load got error: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Got %d loader calls after Load, want 2", got)
+	}
+
+	// Longer override keeps the value fresh after the configured ttl.
+	clock.Advance(ttl)
+	load(t, 2*ttl, 2)
+
+	// Non-positive ttl means no override.
+	load(t, 0, 3)
+}
+
+func TestMapUnsupportedOptions(t *testing.T) {
+	loader := func(_ context.Context, key string) (*string, error) {
+		return &key, nil
+	}
+	for _, c := range []struct {
+		label  string
+		option stalecache.Option[string]
+	}{
+		{"pubsub", stalecache.WithPubSub[string](new(bus[string]))},
+		{"invalidation", stalecache.WithInvalidationChannel[string](make(chan struct{}))},
+		{"warming", stalecache.WithCacheWarming(func(context.Context) []*string {
+			return nil
+		}, time.Second)},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			for label, f := range map[string]func(){
+				"NewMap": func() {
+					stalecache.NewMap(loader, c.option)
+				},
+				"NewLRUMap": func() {
+					stalecache.NewLRUMap(10, loader, c.option)
+				},
+			} {
+				func() {
+					defer func() {
+						if recover() == nil {
+							t.Errorf("%s did not panic", label)
+						}
+					}()
+					f()
+				}()
+			}
+		})
+	}
+}
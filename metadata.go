@@ -0,0 +1,58 @@
+package stalecache
+
+import (
+	"time"
+)
+
+// WithMetadataStore is an Option to attach metadata to the cached value.
+//
+// Default is no metadata.
+// After each successful loader call,
+// updateMeta is called with the previous metadata and the newly loaded data
+// to produce the updated metadata,
+// which can be read via Metadata.
+//
+// This enables use cases like tracking which environment the data came from,
+// or which loader version produced the data, without polluting T itself.
+func WithMetadataStore[T, M any](initialMeta M, updateMeta func(prevMeta M, data *T, loadedAt time.Time) M) Option[T] {
+	return func(o *opt[T]) {
+		o.metaInit = metaBox[M]{initialMeta}
+		o.updateMeta = func(prev any, data *T, loaded time.Time) any {
+			// zero M if the previous metadata is from a different
+			// WithMetadataStore.
+			box, _ := prev.(metaBox[M])
+			return metaBox[M]{updateMeta(box.meta, data, loaded)}
+		}
+	}
+}
+
+// metaBox wraps the metadata of type M,
+// so nil metadata of interface types still has the type M.
+type metaBox[M any] struct {
+	meta M
+}
+
+func (c *Cache[T]) updateMetadata(data *T, loaded time.Time) {
+	for {
+		prev := c.meta.Load()
+		meta := c.opt.updateMeta(*prev, data, loaded)
+		if c.meta.CompareAndSwap(prev, &meta) {
+			return
+		}
+	}
+}
+
+// Metadata returns the current metadata of c set by WithMetadataStore.
+//
+// It's a function instead of a method on Cache because methods cannot have
+// extra type parameters in Go.
+// It returns zero value and false if c has no metadata store,
+// or M is not the type used by WithMetadataStore.
+func Metadata[M, T any](c *Cache[T]) (M, bool) {
+	if meta := c.meta.Load(); meta != nil {
+		box, ok := (*meta).(metaBox[M])
+		return box.meta, ok
+	}
+	var m M
+	return m, false
+}
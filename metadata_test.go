@@ -0,0 +1,82 @@
+package stalecache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestMetadata(t *testing.T) {
+	type meta struct {
+		Loads int
+		Last  int
+	}
+	loader := func(context.Context) (*int, error) {
+		data := 42
+		return &data, nil
+	}
+
+	t.Run("none", func(t *testing.T) {
+		cache := stalecache.New(loader)
+		if got, ok := stalecache.Metadata[meta](cache); ok {
+			t.Errorf("Metadata got %+v, want none", got)
+		}
+	})
+
+	t.Run("store", func(t *testing.T) {
+		const ttl = time.Millisecond
+		clock := stalecachetest.NewFakeClock(time.Now())
+		cache := stalecache.New(
+			loader,
+			stalecache.WithTTL[int](ttl),
+			stalecache.WithClock[int](clock),
+			stalecache.WithMetadataStore(meta{}, func(prev meta, data *int, _ time.Time) meta {
+				return meta{
+					Loads: prev.Loads + 1,
+					Last:  *data,
+				}
+			}),
+		)
+		if got, ok := stalecache.Metadata[meta](cache); !ok || got != (meta{}) {
+			t.Errorf("Metadata before Load got %+v, %v, want initial", got, ok)
+		}
+		for i := 0; i < 2; i++ {
+			cache.Load(context.Background())
+			clock.Advance(ttl)
+		}
+		want := meta{Loads: 2, Last: 42}
+		if got, ok := stalecache.Metadata[meta](cache); !ok || got != want {
+			t.Errorf("Metadata got %+v, %v, want %+v", got, ok, want)
+		}
+		if got, ok := stalecache.Metadata[string](cache); ok {
+			t.Errorf("Metadata with wrong type got %q", got)
+		}
+	})
+
+	t.Run("interface", func(t *testing.T) {
+		cache := stalecache.New(
+			loader,
+			stalecache.WithMetadataStore[int, error](nil, func(prev error, _ *int, _ time.Time) error {
+				if prev != nil {
+					t.Errorf("updateMeta got prev %v, want nil", prev)
+				}
+				return nil
+			}),
+		)
+		if got, ok := stalecache.Metadata[error](cache); !ok || got != nil {
+			t.Errorf("Metadata before Load got %v, %v, want nil, true", got, ok)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			cache.Reset()
+		}
+		if got, ok := stalecache.Metadata[error](cache); !ok || got != nil {
+			t.Errorf("Metadata got %v, %v, want nil, true", got, ok)
+		}
+	})
+}
@@ -0,0 +1,34 @@
+package stalecache
+
+// LoaderMiddleware wraps a Loader to add cross-cutting concerns to it,
+// for example logging, metrics, and tracing.
+//
+// The returned Loader should pass the ctx (or a ctx derived from it) into the
+// wrapped Loader,
+// so that the cancellation propagates through the chain.
+type LoaderMiddleware[T any] func(Loader[T]) Loader[T]
+
+// WithMiddleware is an Option to add LoaderMiddlewares to the loader.
+//
+// The middlewares are applied in order,
+// so the first one is the outermost wrapper.
+// It can be used multiple times, and the middlewares added later are inner to
+// the ones added earlier.
+//
+// The middlewares wrap the loader directly,
+// inside other loader related Options like WithRetry and WithLoadTimeout,
+// so with WithRetry they are called for every attempt.
+func WithMiddleware[T any](middlewares ...LoaderMiddleware[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// chainMiddlewares wraps loader with middlewares,
+// with the first one as the outermost wrapper.
+func chainMiddlewares[T any](loader Loader[T], middlewares []LoaderMiddleware[T]) Loader[T] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		loader = middlewares[i](loader)
+	}
+	return loader
+}
@@ -0,0 +1,67 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	named := func(name string) stalecache.LoaderMiddleware[int] {
+		return func(next stalecache.Loader[int]) stalecache.Loader[int] {
+			return func(ctx context.Context) (*int, error) {
+				calls = append(calls, name+"-before")
+				defer func() {
+					calls = append(calls, name+"-after")
+				}()
+				return next(ctx)
+			}
+		}
+	}
+
+	t.Run("order", func(t *testing.T) {
+		calls = nil
+		cache := stalecache.New(
+			func(context.Context) (*int, error) {
+				calls = append(calls, "loader")
+				var data int
+				return &data, nil
+			},
+			stalecache.WithMiddleware(named("a"), named("b")),
+			stalecache.WithMiddleware(named("c")),
+		)
+		if _, err := cache.Load(context.Background()); err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		const want = "a-before,b-before,c-before,loader,c-after,b-after,a-after"
+		if got := strings.Join(calls, ","); got != want {
+			t.Errorf("Got calls %q, want %q", got, want)
+		}
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		calls = nil
+		cache := stalecache.New(
+			func(ctx context.Context) (*int, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			stalecache.WithMiddleware(named("a")),
+			stalecache.WithMiddleware(func(next stalecache.Loader[int]) stalecache.Loader[int] {
+				return func(ctx context.Context) (*int, error) {
+					ctx, cancel := context.WithCancel(ctx)
+					cancel()
+					return next(ctx)
+				}
+			}),
+		)
+		_, err := cache.Load(context.Background())
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Load got error %v, want %v", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// namedMutexes holds the package level mutexes used by WithMutualExclusion,
+// keyed by name.
+//
+// The mutexes are implemented as 1-buffered channels so that acquiring them
+// can be canceled.
+var namedMutexes sync.Map // map[string]chan struct{}
+
+func namedMutex(name string) chan struct{} {
+	if mu, ok := namedMutexes.Load(name); ok {
+		return mu.(chan struct{})
+	}
+	mu, _ := namedMutexes.LoadOrStore(name, make(chan struct{}, 1))
+	return mu.(chan struct{})
+}
+
+// WithMutualExclusion is an Option to serialize loader calls across different
+// Cache instances.
+//
+// Default is empty, means no coordination with other Cache instances.
+// When set, a package level mutex keyed by name is acquired before every
+// loader call and released after the loader returns,
+// so Cache instances sharing the same name never call their loaders at the
+// same time.
+//
+// An usual use case is when multiple caches share the same external quota.
+func WithMutualExclusion[T any](name string) Option[T] {
+	return func(o *opt[T]) {
+		o.mutexName = name
+	}
+}
+
+// WithMutualExclusionTimeout is an Option to set the timeout of acquiring the
+// mutex set by WithMutualExclusion.
+//
+// Default is 0, means wait until the mutex is acquired or the ctx passed into
+// the loader is canceled.
+// When the timeout is reached the loader is not called,
+// and Load returns an error wrapping context.DeadlineExceeded instead.
+func WithMutualExclusionTimeout[T any](timeout time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.mutexTimeout = timeout
+	}
+}
+
+func mutualExclusionLoader[T any](loader Loader[T], name string, timeout time.Duration) Loader[T] {
+	mu := namedMutex(name)
+	return func(ctx context.Context) (*T, error) {
+		lockCtx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			lockCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		select {
+		case mu <- struct{}{}:
+		case <-lockCtx.Done():
+			err := fmt.Errorf("failed to acquire mutual exclusion %q: %w", name, lockCtx.Err())
+			if ctx.Err() == nil {
+				// timed out by WithMutualExclusionTimeout
+				return nil, &CacheError{Code: CodeTimeout, Err: err}
+			}
+			return nil, &CacheError{Code: CodeCanceled, Err: err}
+		}
+		defer func() {
+			<-mu
+		}()
+		return loader(ctx)
+	}
+}
@@ -0,0 +1,88 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestMutualExclusion(t *testing.T) {
+	const (
+		name  = "TestMutualExclusion"
+		sleep = 5 * time.Millisecond
+		n     = 5
+	)
+	var concurrentLoaderCalls atomic.Int64
+	loader := func(context.Context) (*int, error) {
+		defer concurrentLoaderCalls.Add(-1)
+		if calls := concurrentLoaderCalls.Add(1); calls != 1 {
+			t.Errorf("Got %d concurrent loader calls, want 1", calls)
+		}
+		time.Sleep(sleep)
+		var data int
+		return &data, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		cache := stalecache.New(loader, stalecache.WithMutualExclusion[int](name))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Errorf("Load #%d returned error: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMutualExclusionTimeout(t *testing.T) {
+	const (
+		name    = "TestMutualExclusionTimeout"
+		timeout = 5 * time.Millisecond
+	)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := stalecache.New(
+		func(context.Context) (*int, error) {
+			close(started)
+			<-release
+			var data int
+			return &data, nil
+		},
+		stalecache.WithMutualExclusion[int](name),
+	)
+	go slow.Load(context.Background())
+	<-started
+	defer close(release)
+
+	var called atomic.Bool
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			called.Store(true)
+			var data int
+			return &data, nil
+		},
+		stalecache.WithMutualExclusion[int](name),
+		stalecache.WithMutualExclusionTimeout[int](timeout),
+	)
+	for i := 0; i < 2; i++ {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			_, err := cache.Load(context.Background())
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Load got error %v, want %v", err, context.DeadlineExceeded)
+			}
+			if called.Load() {
+				t.Error("Loader called without holding the mutex")
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package stalecache
+
+import (
+	"time"
+)
+
+// WithNegativeCaching is an Option to cache the loader errors meaning the
+// resource is missing (for example HTTP 404 or sql.ErrNoRows) for ttl.
+//
+// Default is off.
+// When set, a loader error satisfying isNegative is treated as a fresh result
+// for ttl:
+// all Load calls within ttl return the same error without calling the loader,
+// and without falling back to the stale data (or WithOnErrorFallback),
+// as the resource is known to be gone.
+// After ttl the loader is called again as usual.
+// Other errors are not affected.
+func WithNegativeCaching[T any](ttl time.Duration, isNegative func(error) bool) Option[T] {
+	return func(o *opt[T]) {
+		o.negTTL = ttl
+		o.isNegative = isNegative
+	}
+}
+
+// negative returns true if err is a negative result according to
+// WithNegativeCaching.
+func (c *Cache[T]) negative(err error) bool {
+	return c.opt.negTTL > 0 && c.opt.isNegative != nil && c.opt.isNegative(err)
+}
+
+// negativeFresh returns true if the failed entry d is a negative result still
+// within the ttl of WithNegativeCaching.
+func (c *Cache[T]) negativeFresh(d *cached[T]) bool {
+	return c.negative(d.err) && d.loaded.Add(c.opt.negTTL).After(c.opt.now())
+}
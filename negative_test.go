@@ -0,0 +1,70 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestNegativeCaching(t *testing.T) {
+	const negTTL = 10 * time.Millisecond
+	errNotFound := errors.New("not found")
+	errOther := errors.New("other")
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls atomic.Int64
+	var loaderErr atomic.Pointer[error]
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls.Add(1)
+			if err := loaderErr.Load(); err != nil {
+				return nil, *err
+			}
+			data := calls.Load()
+			return &data, nil
+		},
+		stalecache.WithNegativeCaching[int64](negTTL, func(err error) bool {
+			return errors.Is(err, errNotFound)
+		}),
+		stalecache.WithClock[int64](clock),
+	)
+	load := func(t *testing.T, wantErr error, wantCalls int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if !errors.Is(err, wantErr) {
+			t.Errorf("Load got error %v, want %v", err, wantErr)
+		}
+		if wantErr != nil && data != nil {
+			t.Errorf("Load got data %d, want nil", *data)
+		}
+		if got := calls.Load(); got != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", got, wantCalls)
+		}
+	}
+
+	load(t, nil, 1)
+
+	cache.Reset()
+	loaderErr.Store(&errNotFound)
+	load(t, errNotFound, 2)
+	load(t, errNotFound, 2)
+
+	clock.Advance(negTTL)
+	load(t, errNotFound, 3)
+
+	clock.Advance(negTTL)
+	loaderErr.Store(&errOther)
+	before := calls.Load()
+	load(t, errOther, before+1)
+	after := calls.Load()
+	load(t, errOther, after+1)
+
+	loaderErr.Store(nil)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Errorf("Load got error: %v", err)
+	}
+}
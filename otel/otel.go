@@ -0,0 +1,70 @@
+// Package otel provides OpenTelemetry tracing for stalecache loaders.
+//
+// It's a separate module so the core stalecache module does not depend on
+// OpenTelemetry.
+package otel // import "go.yhsif.com/stalecache/otel"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"go.yhsif.com/stalecache"
+)
+
+// SpanName is the name of the spans created by WithOTelTracing.
+const SpanName = "stalecache.load"
+
+// Attribute keys set on the spans created by WithOTelTracing.
+const (
+	// HitKey is always false,
+	// as spans are only created for loader calls, which are cache misses.
+	HitKey = attribute.Key("stalecache.hit")
+	// DurationKey is the duration of the loader call, in seconds.
+	DurationKey = attribute.Key("stalecache.loader.duration")
+	// ErrorKey is whether the loader returned an error.
+	ErrorKey = attribute.Key("stalecache.error")
+)
+
+// WithOTelTracing is an Option to create a span from tracer for every loader
+// call, with attrs and the attributes defined in this package set.
+//
+// When the loader returns an error,
+// the error is recorded to the span and the span status is set to
+// codes.Error.
+//
+// It's built on stalecache.WithMiddleware,
+// so with stalecache.WithRetry every attempt has its own span.
+func WithOTelTracing[T any](tracer trace.Tracer, attrs ...attribute.KeyValue) stalecache.Option[T] {
+	return stalecache.WithMiddleware(Middleware[T](tracer, attrs...))
+}
+
+// Middleware returns the stalecache.LoaderMiddleware used by WithOTelTracing.
+func Middleware[T any](tracer trace.Tracer, attrs ...attribute.KeyValue) stalecache.LoaderMiddleware[T] {
+	return func(loader stalecache.Loader[T]) stalecache.Loader[T] {
+		return func(ctx context.Context) (*T, error) {
+			ctx, span := tracer.Start(
+				ctx,
+				SpanName,
+				trace.WithAttributes(attrs...),
+				trace.WithAttributes(HitKey.Bool(false)),
+			)
+			defer span.End()
+
+			start := time.Now()
+			data, err := loader(ctx)
+			span.SetAttributes(
+				DurationKey.Float64(time.Since(start).Seconds()),
+				ErrorKey.Bool(err != nil),
+			)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return data, err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/otel"
+)
+
+func TestWithOTelTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+	extra := attribute.String("cache", "foo")
+
+	fail := false
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail {
+				return nil, errors.New("foo")
+			}
+			var data int
+			return &data, nil
+		},
+		otel.WithOTelTracing[int](tracer, extra),
+	)
+
+	cache.Load(context.Background())
+	// hit, no new span
+	cache.Load(context.Background())
+	fail = true
+	cache.ForceReload(context.Background())
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Got %d spans, want 2", len(spans))
+	}
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+	for i, span := range spans {
+		if span.Name() != otel.SpanName {
+			t.Errorf("Span #%d name got %q, want %q", i, span.Name(), otel.SpanName)
+		}
+		m := attrs(span)
+		if got := m[extra.Key].AsString(); got != extra.Value.AsString() {
+			t.Errorf("Span #%d %v got %q, want %q", i, extra.Key, got, extra.Value.AsString())
+		}
+		if got := m[otel.HitKey].AsBool(); got {
+			t.Errorf("Span #%d %v got true, want false", i, otel.HitKey)
+		}
+		if _, ok := m[otel.DurationKey]; !ok {
+			t.Errorf("Span #%d has no %v", i, otel.DurationKey)
+		}
+	}
+	if got := attrs(spans[0])[otel.ErrorKey].AsBool(); got {
+		t.Errorf("Span #0 %v got true, want false", otel.ErrorKey)
+	}
+	if got := spans[0].Status().Code; got == codes.Error {
+		t.Errorf("Span #0 status got %v", got)
+	}
+	if got := attrs(spans[1])[otel.ErrorKey].AsBool(); !got {
+		t.Errorf("Span #1 %v got false, want true", otel.ErrorKey)
+	}
+	if got := spans[1].Status().Code; got != codes.Error {
+		t.Errorf("Span #1 status got %v, want %v", got, codes.Error)
+	}
+}
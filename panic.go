@@ -0,0 +1,69 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+)
+
+// PanicError is the error stored in the cache when the loader panicked with
+// WithRecoverPanics.
+//
+// Use errors.As to get it from the error returned by Load.
+type PanicError interface {
+	error
+
+	// Unwrap returns the value passed into panic if it's an error,
+	// nil otherwise.
+	Unwrap() error
+
+	// Recovered returns the value passed into panic.
+	Recovered() any
+}
+
+type panicError struct {
+	recovered any
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("loader panicked: %v", e.recovered)
+}
+
+func (e *panicError) Unwrap() error {
+	err, _ := e.recovered.(error)
+	return err
+}
+
+func (e *panicError) Recovered() any {
+	return e.recovered
+}
+
+// WithRecoverPanics is an Option to recover from the panics of the loader.
+//
+// Default is off, means the panics from the loader are re-raised in all the
+// Load calls waiting for it (and the failed load is stored as a PanicError,
+// so the next Load calls the loader again),
+// which usually crashes the process unless they recover.
+// When set, a panic from the loader (or the LoaderMiddlewares added by
+// WithMiddleware) is recovered and treated as the loader returning a
+// PanicError,
+// so it's subject to WithRetry, WithErrorTTL, WithCircuitBreaker, etc. the
+// same as other loader errors.
+// A panic from the other functions called to fill the cache (for example
+// WithTransform, WithPartialUpdate, and the Hooks) is also recovered and returned as a
+// PanicError, but without WithRetry, etc.
+func WithRecoverPanics[T any]() Option[T] {
+	return func(o *opt[T]) {
+		o.recoverPanics = true
+	}
+}
+
+func recoverLoader[T any](loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (data *T, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				data, err = nil, &panicError{recovered: r}
+			}
+		}()
+		return loader(ctx)
+	}
+}
@@ -0,0 +1,77 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	for _, c := range []struct {
+		label     string
+		recovered any
+		wantIs    error
+	}{
+		{
+			label:     "string",
+			recovered: "foo",
+		},
+		{
+			label:     "error",
+			recovered: io.EOF,
+			wantIs:    io.EOF,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cache := stalecache.New(
+				func(context.Context) (*int, error) {
+					panic(c.recovered)
+				},
+				stalecache.WithRecoverPanics[int](),
+				stalecache.WithRetry[int](2, time.Millisecond),
+			)
+			data, err := cache.Load(context.Background())
+			if data != nil {
+				t.Errorf("Load got data %v, want nil", *data)
+			}
+			var pe stalecache.PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Load got error %v, want PanicError", err)
+			}
+			if got := pe.Recovered(); got != c.recovered {
+				t.Errorf("Recovered got %v, want %v", got, c.recovered)
+			}
+			if c.wantIs != nil && !errors.Is(err, c.wantIs) {
+				t.Errorf("Load got error %v, want %v", err, c.wantIs)
+			}
+			if _, _, peekErr := cache.Peek(); peekErr != err {
+				t.Errorf("Peek got error %v, want %v", peekErr, err)
+			}
+		})
+	}
+}
+
+func TestRecoverPanicsTransform(t *testing.T) {
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTransform(func(context.Context, *int) (*int, error) {
+			panic("foo")
+		}),
+		stalecache.WithRecoverPanics[int](),
+	)
+	_, err := cache.Load(context.Background())
+	var pe stalecache.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("Load got error %v, want PanicError", err)
+	}
+	if got := pe.Recovered(); got != "foo" {
+		t.Errorf("Recovered got %v, want foo", got)
+	}
+}
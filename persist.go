@@ -0,0 +1,89 @@
+package stalecache
+
+import (
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+// ErrNoCodec is the error returned by SaveTo and LoadFrom when the Cache is
+// created without WithCodec.
+var ErrNoCodec error = &CacheError{Code: CodeNoCodec}
+
+// WithCodec is an Option to set the codec used by SaveTo and LoadFrom to
+// persist the cached value.
+//
+// Default is nil, means the cache cannot be persisted,
+// and SaveTo and LoadFrom return ErrNoCodec.
+func WithCodec[T any](encode func(*T) ([]byte, error), decode func([]byte) (*T, error)) Option[T] {
+	return func(o *opt[T]) {
+		o.encode = encode
+		o.decode = decode
+	}
+}
+
+type persistSnapshot struct {
+	LoadedAt time.Time
+	Data     []byte
+}
+
+// SaveTo writes the last successfully loaded value and the time it's loaded
+// to w, encoded by the codec set via WithCodec.
+//
+// If nothing has been loaded successfully, it writes an empty snapshot,
+// which puts the cache back to the never-loaded state when it's restored by
+// LoadFrom.
+//
+// SaveTo never calls the loader.
+func (c *Cache[T]) SaveTo(w io.Writer) error {
+	if c.opt.encode == nil {
+		return c.named(ErrNoCodec)
+	}
+	var snapshot persistSnapshot
+	if curr := c.current(); curr.done.Load() {
+		if good := curr.lastGood(); good != nil {
+			data, err := c.opt.encode(good.data)
+			if err != nil {
+				return err
+			}
+			snapshot.LoadedAt = good.loaded
+			snapshot.Data = data
+		}
+	}
+	return gob.NewEncoder(w).Encode(snapshot)
+}
+
+// LoadFrom restores the cache from a snapshot written by SaveTo, decoded by
+// the codec set via WithCodec.
+//
+// The time the value was loaded is restored as well,
+// so the ttl is correctly calculated from the original load,
+// and a value already expired causes the next Load to call the loader
+// (with the restored value as the stale fallback).
+//
+// LoadFrom never calls the loader.
+func (c *Cache[T]) LoadFrom(r io.Reader) error {
+	if c.opt.decode == nil {
+		return c.named(ErrNoCodec)
+	}
+	var snapshot persistSnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return err
+	}
+	if snapshot.LoadedAt.IsZero() {
+		c.invalidate()
+		return nil
+	}
+	data, err := c.opt.decode(snapshot.Data)
+	if err != nil {
+		return err
+	}
+	entry := new(cached[T])
+	entry.version = c.version.Add(1)
+	entry.ttl = c.dynamicTTL(data)
+	entry.set(data, snapshot.LoadedAt, nil)
+	c.init()
+	c.evict(c.cached.Swap(entry).good())
+	c.everLoaded.Store(true)
+	return nil
+}
@@ -0,0 +1,117 @@
+package stalecache_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestCachePersist(t *testing.T) {
+	const ttl = time.Hour
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	newCache := func() *stalecache.Cache[string] {
+		return stalecache.New(
+			func(context.Context) (*string, error) {
+				loaderCalls.Add(1)
+				s := "foo"
+				return &s, nil
+			},
+			stalecache.WithTTL[string](ttl),
+			stalecache.WithClock[string](clock),
+			stalecache.WithCodec(
+				func(s *string) ([]byte, error) {
+					return json.Marshal(s)
+				},
+				func(b []byte) (*string, error) {
+					s := new(string)
+					return s, json.Unmarshal(b, s)
+				},
+			),
+		)
+	}
+
+	t.Run("no-codec", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*string, error) {
+			return nil, nil
+		})
+		if err := cache.SaveTo(new(bytes.Buffer)); !errors.Is(err, stalecache.ErrNoCodec) {
+			t.Errorf("SaveTo got error %v, want %v", err, stalecache.ErrNoCodec)
+		}
+		if err := cache.LoadFrom(new(bytes.Buffer)); !errors.Is(err, stalecache.ErrNoCodec) {
+			t.Errorf("LoadFrom got error %v, want %v", err, stalecache.ErrNoCodec)
+		}
+
+		named := stalecache.New(
+			func(context.Context) (*string, error) {
+				return nil, nil
+			},
+			stalecache.WithName[string]("foo"),
+		)
+		err := named.SaveTo(new(bytes.Buffer))
+		var ce *stalecache.CacheError
+		if !errors.As(err, &ce) || ce.Code != stalecache.CodeNoCodec || ce.Name != "foo" {
+			t.Errorf("SaveTo got error %#v, want CacheError with CodeNoCodec and name", err)
+		}
+		if !errors.Is(err, stalecache.ErrNoCodec) {
+			t.Errorf("SaveTo got error %v, want %v", err, stalecache.ErrNoCodec)
+		}
+	})
+
+	t.Run("never-loaded", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := newCache().SaveTo(&buf); err != nil {
+			t.Fatalf("SaveTo got error: %v", err)
+		}
+		dst := newCache()
+		if err := dst.LoadFrom(&buf); err != nil {
+			t.Fatalf("LoadFrom got error: %v", err)
+		}
+		if data, _, _ := dst.PeekStale(); data != nil {
+			t.Errorf("PeekStale got %q, want nil", *data)
+		}
+	})
+
+	t.Run("round-trip", func(t *testing.T) {
+		src := newCache()
+		src.Load(context.Background())
+		loadedAt := clock.Now()
+		var buf bytes.Buffer
+		if err := src.SaveTo(&buf); err != nil {
+			t.Fatalf("SaveTo got error: %v", err)
+		}
+
+		loaderCalls.Store(0)
+		clock.Advance(ttl / 2)
+		dst := newCache()
+		if err := dst.LoadFrom(&buf); err != nil {
+			t.Fatalf("LoadFrom got error: %v", err)
+		}
+		data, err := dst.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != "foo" {
+			t.Errorf("Load got %q, want %q", *data, "foo")
+		}
+		if got := dst.LoadedAt(); !got.Equal(loadedAt) {
+			t.Errorf("LoadedAt got %v, want %v", got, loadedAt)
+		}
+		if calls := loaderCalls.Load(); calls != 0 {
+			t.Errorf("Got %d loader calls after LoadFrom, want 0", calls)
+		}
+
+		clock.Advance(ttl / 2)
+		dst.Load(context.Background())
+		if calls := loaderCalls.Load(); calls != 1 {
+			t.Errorf("Got %d loader calls after expired, want 1", calls)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package stalecache
+
+import (
+	"context"
+	"sync"
+)
+
+// PubSubAdapter is the interface to wire a Cache into a pub/sub system,
+// to keep the caches from multiple processes in sync.
+//
+// It can be implemented by Redis, NATS, or any other backends.
+type PubSubAdapter[T any] interface {
+	// Publish publishes val to all the replicas.
+	Publish(ctx context.Context, val *T) error
+
+	// Subscribe subscribes to the values published by all the replicas.
+	//
+	// The returned channel should be closed after ctx is canceled.
+	Subscribe(ctx context.Context) (<-chan *T, error)
+}
+
+// WithPubSub is an Option to keep the cache in sync with its replicas from
+// other processes through ps.
+//
+// Default is nil, means no syncing.
+// It's not supported by Map and LRUMap (NewMap and NewLRUMap panic with it),
+// as the values are published without their keys.
+// When set, the Cache subscribes to ps when it's created (until it's closed),
+// and every received value is used to update the cache the same as Update,
+// without publishing it again or calling the writer set by WithWriteBack.
+// After every successful loader call and Update (including UpdateFunc and
+// TryUpdate), the new value is published to ps,
+// synchronously from the goroutine calling the loader or Update.
+//
+// Received nil values are ignored.
+// The values published by the Cache itself (by pointer identity) are dropped
+// when they are received back from ps,
+// so caches sharing an in-process adapter don't apply their own updates
+// twice.
+//
+// Errors from ps are reported to Hooks.OnPubSubError,
+// and when Subscribe fails the cache works without receiving values from its
+// replicas.
+func WithPubSub[T any](ps PubSubAdapter[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.pubsub = ps
+	}
+}
+
+// maxPublished is the number of the values published by a Cache kept to drop
+// them when they are received back.
+const maxPublished = 16
+
+// published keeps the values recently published by a Cache.
+//
+// Adapters delivering across processes never send the same pointers back,
+// so it's bounded instead of being cleared by the received values.
+type published[T any] struct {
+	mu     sync.Mutex
+	values [maxPublished]*T
+	next   int
+}
+
+func (p *published[T]) add(data *T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[p.next] = data
+	p.next = (p.next + 1) % maxPublished
+}
+
+// take removes data and returns true if it's published by the Cache.
+func (p *published[T]) take(data *T) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, v := range p.values {
+		if v == data {
+			p.values[i] = nil
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Cache[T]) publish(ctx context.Context, data *T) {
+	if c.opt.pubsub == nil {
+		return
+	}
+	c.published.add(data)
+	if err := c.opt.pubsub.Publish(ctx, data); err != nil {
+		c.opt.onPubSubError(err)
+	}
+}
+
+func (c *Cache[T]) subscribePubSub(ctx context.Context) {
+	ch, err := c.opt.pubsub.Subscribe(ctx)
+	if err != nil {
+		c.opt.onPubSubError(err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if data == nil || c.published.take(data) {
+				continue
+			}
+			c.update(data)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package pubsub provides stalecache.PubSubAdapter implementations and
+// helpers.
+package pubsub // import "go.yhsif.com/stalecache/pubsub"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.yhsif.com/stalecache"
+)
+
+// ChanAdapter is an in-process stalecache.PubSubAdapter backed by channels,
+// for example to keep multiple caches in the same process (or tests) in sync.
+//
+// It's safe for concurrent use.
+type ChanAdapter[T any] struct {
+	bufSize int
+	dropped atomic.Uint64
+
+	mu   sync.Mutex
+	subs map[chan *T]struct{}
+}
+
+var _ stalecache.PubSubAdapter[int] = (*ChanAdapter[int])(nil)
+
+// NewChanAdapter creates a new ChanAdapter with the subscriber channels
+// buffered by bufSize.
+func NewChanAdapter[T any](bufSize int) *ChanAdapter[T] {
+	return &ChanAdapter[T]{
+		bufSize: bufSize,
+		subs:    make(map[chan *T]struct{}),
+	}
+}
+
+// Publish sends val to all the current subscribers.
+//
+// It never blocks:
+// when the buffer of a subscriber is full, val is dropped for it and counted
+// by Dropped.
+// It always returns nil error.
+func (a *ChanAdapter[T]) Publish(_ context.Context, val *T) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for ch := range a.subs {
+		select {
+		case ch <- val:
+		default:
+			a.dropped.Add(1)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a new subscriber until ctx is canceled,
+// when the returned channel is closed.
+//
+// It always returns nil error.
+func (a *ChanAdapter[T]) Subscribe(ctx context.Context) (<-chan *T, error) {
+	ch := make(chan *T, a.bufSize)
+	a.mu.Lock()
+	a.subs[ch] = struct{}{}
+	a.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.subs, ch)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Dropped returns the number of values dropped by Publish because of slow
+// subscribers.
+func (a *ChanAdapter[T]) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+type fanOut[T any] []stalecache.PubSubAdapter[T]
+
+// NewFanOut combines adapters into one stalecache.PubSubAdapter,
+// for example to publish to both an in-process ChanAdapter and Redis.
+//
+// Publish publishes to all adapters concurrently,
+// and returns the errors from them joined by errors.Join.
+// Subscribe subscribes to all adapters and merges the received values into the
+// returned channel,
+// which is closed after all the channels from adapters are closed.
+// If any of them failed to Subscribe,
+// the subscriptions already made are canceled and the errors are returned.
+func NewFanOut[T any](adapters ...stalecache.PubSubAdapter[T]) stalecache.PubSubAdapter[T] {
+	return fanOut[T](adapters)
+}
+
+func (f fanOut[T]) Publish(ctx context.Context, val *T) error {
+	errs := make([]error, len(f))
+	var wg sync.WaitGroup
+	for i, a := range f {
+		wg.Add(1)
+		go func(i int, a stalecache.PubSubAdapter[T]) {
+			defer wg.Done()
+			errs[i] = a.Publish(ctx, val)
+		}(i, a)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (f fanOut[T]) Subscribe(ctx context.Context) (<-chan *T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	chans := make([]<-chan *T, 0, len(f))
+	var errs []error
+	for _, a := range f {
+		ch, err := a.Subscribe(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		chans = append(chans, ch)
+	}
+	if err := errors.Join(errs...); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	out := make(chan *T)
+	var wg sync.WaitGroup
+	for _, ch := range chans {
+		wg.Add(1)
+		go func(ch <-chan *T) {
+			defer wg.Done()
+			for val := range ch {
+				select {
+				case out <- val:
+				case <-ctx.Done():
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out, nil
+}
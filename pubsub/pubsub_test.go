@@ -0,0 +1,156 @@
+package pubsub_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/pubsub"
+)
+
+func receive[T any](t *testing.T, ch <-chan *T) *T {
+	t.Helper()
+	select {
+	case val, ok := <-ch:
+		if !ok {
+			t.Fatal("Channel closed")
+		}
+		return val
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for value")
+		return nil
+	}
+}
+
+func waitClosed[T any](t *testing.T, ch <-chan *T) {
+	t.Helper()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for channel to close")
+		}
+	}
+}
+
+func TestChanAdapter(t *testing.T) {
+	a := pubsub.NewChanAdapter[int](1)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch1, _ := a.Subscribe(ctx)
+	ch2, _ := a.Subscribe(ctx)
+
+	one, two := 1, 2
+	a.Publish(context.Background(), &one)
+	if got := receive(t, ch1); *got != 1 {
+		t.Errorf("Got %d, want 1", *got)
+	}
+	// ch2 is full
+	a.Publish(context.Background(), &two)
+	if got := a.Dropped(); got != 1 {
+		t.Errorf("Dropped got %d, want 1", got)
+	}
+	if got := receive(t, ch2); *got != 1 {
+		t.Errorf("Got %d, want 1", *got)
+	}
+	if got := receive(t, ch1); *got != 2 {
+		t.Errorf("Got %d, want 2", *got)
+	}
+
+	cancel()
+	waitClosed(t, ch1)
+	waitClosed(t, ch2)
+	// no subscribers
+	a.Publish(context.Background(), &one)
+}
+
+func TestChanAdapterWithPubSub(t *testing.T) {
+	a := pubsub.NewChanAdapter[string](10)
+	newCache := func() *stalecache.Cache[string] {
+		return stalecache.New(
+			func(context.Context) (*string, error) {
+				return nil, errors.New("not loaded")
+			},
+			stalecache.WithPubSub[string](a),
+		)
+	}
+	c1, c2 := newCache(), newCache()
+	defer c1.Close()
+	defer c2.Close()
+
+	data := "foo"
+	// wait for both subscriptions
+	deadline := time.Now().Add(time.Second)
+	for {
+		c1.Update(context.Background(), &data)
+		time.Sleep(time.Millisecond)
+		if got, _, _ := c2.Peek(); got != nil && *got == data {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("c2 never got the update from c1")
+		}
+	}
+}
+
+type failingAdapter[T any] struct {
+	err error
+}
+
+func (f failingAdapter[T]) Publish(context.Context, *T) error {
+	return f.err
+}
+
+func (f failingAdapter[T]) Subscribe(context.Context) (<-chan *T, error) {
+	return nil, f.err
+}
+
+func TestFanOut(t *testing.T) {
+	a := pubsub.NewChanAdapter[int](1)
+	b := pubsub.NewChanAdapter[int](1)
+	ctx, cancel := context.WithCancel(context.Background())
+	chA, _ := a.Subscribe(ctx)
+	chB, _ := b.Subscribe(ctx)
+
+	f := pubsub.NewFanOut[int](a, b)
+	merged, err := f.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe got error: %v", err)
+	}
+
+	one := 1
+	if err := f.Publish(context.Background(), &one); err != nil {
+		t.Errorf("Publish got error: %v", err)
+	}
+	if got := receive(t, chA); *got != 1 {
+		t.Errorf("a got %d, want 1", *got)
+	}
+	if got := receive(t, chB); *got != 1 {
+		t.Errorf("b got %d, want 1", *got)
+	}
+	// merged gets it from both a and b
+	for i := 0; i < 2; i++ {
+		if got := receive(t, merged); *got != 1 {
+			t.Errorf("merged got %d, want 1", *got)
+		}
+	}
+
+	cancel()
+	waitClosed(t, merged)
+
+	t.Run("errors", func(t *testing.T) {
+		wantErr := errors.New("foo")
+		f := pubsub.NewFanOut[int](a, failingAdapter[int]{err: wantErr})
+		if err := f.Publish(context.Background(), &one); !errors.Is(err, wantErr) {
+			t.Errorf("Publish got error %v, want %v", err, wantErr)
+		}
+		if _, err := f.Subscribe(context.Background()); !errors.Is(err, wantErr) {
+			t.Errorf("Subscribe got error %v, want %v", err, wantErr)
+		}
+	})
+}
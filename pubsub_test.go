@@ -0,0 +1,202 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+// bus is an in-memory stalecache.PubSubAdapter delivering to all subscribers.
+//
+// With unbuffered, Publish blocks until all the subscribers received val.
+type bus[T any] struct {
+	unbuffered bool
+
+	mu   sync.Mutex
+	subs []chan *T
+}
+
+func (b *bus[T]) Publish(_ context.Context, val *T) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		ch <- val
+	}
+	return nil
+}
+
+func (b *bus[T]) Subscribe(ctx context.Context) (<-chan *T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size := 10
+	if b.unbuffered {
+		size = 0
+	}
+	ch := make(chan *T, size)
+	b.subs = append(b.subs, ch)
+	return ch, nil
+}
+
+func (b *bus[T]) subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func TestPubSub(t *testing.T) {
+	const timeout = time.Second
+	ps := new(bus[string])
+	newCache := func(value string) *stalecache.Cache[string] {
+		return stalecache.New(
+			func(context.Context) (*string, error) {
+				return &value, nil
+			},
+			stalecache.WithPubSub[string](ps),
+		)
+	}
+	a := newCache("a")
+	defer a.Close()
+	b := newCache("b")
+	defer b.Close()
+	for deadline := time.Now().Add(timeout); ps.subscribers() < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Caches did not subscribe in %v", timeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	waitFor := func(t *testing.T, c *stalecache.Cache[string], want string) {
+		t.Helper()
+		deadline := time.Now().Add(timeout)
+		for {
+			if data, _, _ := c.PeekStale(); data != nil && *data == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Cache did not get %q in %v", want, timeout)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("load", func(t *testing.T) {
+		if _, err := a.Load(context.Background()); err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		waitFor(t, b, "a")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		value := "c"
+		b.Update(context.Background(), &value)
+		waitFor(t, a, "c")
+	})
+}
+
+func TestPubSubOwnValues(t *testing.T) {
+	const timeout = time.Second
+	ctx := context.Background()
+	ps := &bus[string]{unbuffered: true}
+	newCache := func(value string) *stalecache.Cache[string] {
+		return stalecache.New(
+			func(context.Context) (*string, error) {
+				return &value, nil
+			},
+			stalecache.WithPubSub[string](ps),
+		)
+	}
+	a := newCache("a")
+	defer a.Close()
+	b := newCache("b")
+	defer b.Close()
+	for deadline := time.Now().Add(timeout); ps.subscribers() < 2; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Caches did not subscribe in %v", timeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// As the bus is unbuffered, after the nil value is received (and ignored)
+	// by both caches, all the values published before are applied.
+	flush := func() {
+		ps.Publish(ctx, nil)
+	}
+
+	if _, err := a.Load(ctx); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	flush()
+	value := "c"
+	if err := a.Update(ctx, &value); err != nil {
+		t.Fatalf("Update got error: %v", err)
+	}
+	flush()
+
+	data, version, err := a.LoadWithVersion(ctx)
+	if err != nil {
+		t.Fatalf("LoadWithVersion got error: %v", err)
+	}
+	if *data != "c" {
+		t.Errorf("Got %q, want %q", *data, "c")
+	}
+	if version != 2 {
+		t.Errorf("Got version %d, want 2", version)
+	}
+	for _, c := range []*stalecache.Cache[string]{a, b} {
+		if !c.Rollback() {
+			t.Fatal("Rollback returned false")
+		}
+		data, err := c.Load(ctx)
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != "a" {
+			t.Errorf("Got %q after Rollback, want %q", *data, "a")
+		}
+	}
+}
+
+type failingPubSub[T any] struct{}
+
+var errPubSub = errors.New("pubsub")
+
+func (failingPubSub[T]) Publish(context.Context, *T) error {
+	return errPubSub
+}
+
+func (failingPubSub[T]) Subscribe(context.Context) (<-chan *T, error) {
+	return nil, errPubSub
+}
+
+func TestPubSubErrors(t *testing.T) {
+	errs := make(chan error, 2)
+	c := stalecache.New(
+		func(context.Context) (*string, error) {
+			value := "a"
+			return &value, nil
+		},
+		stalecache.WithPubSub[string](failingPubSub[string]{}),
+		stalecache.WithHooks(stalecache.Hooks[string]{
+			OnPubSubError: func(err error) {
+				errs <- err
+			},
+		}),
+	)
+	defer c.Close()
+	if _, err := c.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, errPubSub) {
+				t.Errorf("Got error %v, want %v", err, errPubSub)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("OnPubSubError was not called for both Subscribe and Publish")
+		}
+	}
+}
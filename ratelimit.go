@@ -0,0 +1,48 @@
+package stalecache
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit is an Option to limit the rate of the loader calls.
+//
+// Default is no limit.
+// When set, every loader call (or every attempt with WithRetry) waits for a
+// token from a rate.Limiter with rps and burst from golang.org/x/time/rate,
+// which is per Cache instance (and per key with Map).
+// While waiting, the ctx cancellation is respected,
+// and a CacheError with CodeTimeout or CodeCanceled is returned when giving up.
+//
+// When a reload is needed but there's no token available right away,
+// and there's stale data,
+// Load returns the stale data immediately (with nil error) instead,
+// and the reload is done in a background goroutine after acquiring the
+// token.
+func WithRateLimit[T any](rps float64, burst int) Option[T] {
+	return func(o *opt[T]) {
+		o.rateLimit = true
+		o.rps = rps
+		o.burst = burst
+	}
+}
+
+func rateLimitLoader[T any](limiter *rate.Limiter, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		if err := limiter.Wait(ctx); err != nil {
+			if ctx.Err() == nil {
+				// the ctx deadline is too close to get the token
+				return nil, &CacheError{Code: CodeTimeout, Err: err}
+			}
+			return nil, &CacheError{Code: CodeCanceled, Err: err}
+		}
+		return loader(ctx)
+	}
+}
+
+// rateLimited returns true if the reload should be done in background because
+// of WithRateLimit.
+func (c *Cache[T]) rateLimited(stale *cached[T]) bool {
+	return c.limiter != nil && stale != nil && stale.data != nil && c.limiter.Tokens() < 1
+}
@@ -0,0 +1,81 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestRateLimit(t *testing.T) {
+	const (
+		ttl     = time.Millisecond
+		timeout = 10 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		// 1 token per hour
+		stalecache.WithRateLimit[int64](1/time.Hour.Seconds(), 1),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	t.Run("stale", func(t *testing.T) {
+		clock.Advance(ttl)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 1 {
+			t.Errorf("Load got %d, want stale 1", *data)
+		}
+	})
+
+	t.Run("no-stale", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		_, err := cache.ForceReload(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ForceReload got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls, want 1", calls)
+	}
+}
+
+func TestRateLimitMap(t *testing.T) {
+	const timeout = 10 * time.Millisecond
+	var loaderCalls atomic.Int64
+	m := stalecache.NewMap(
+		func(_ context.Context, key string) (*string, error) {
+			loaderCalls.Add(1)
+			return &key, nil
+		},
+		// 1 token per hour
+		stalecache.WithRateLimit[string](1/time.Hour.Seconds(), 1),
+	)
+	for _, key := range []string{"foo", "bar"} {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := m.Load(ctx, key); err != nil {
+			t.Errorf("Load(%q) got error: %v", key, err)
+		}
+	}
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls, want 2", calls)
+	}
+}
@@ -0,0 +1,107 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StartReaper starts a background goroutine to delete the keys that are too
+// stale to be returned from the Map every interval,
+// so the memory used by the keys no longer accessed can be reclaimed.
+//
+// A key is deleted when its last successfully loaded value (or the last
+// failure, if it's never loaded successfully) is older than its ttl plus the
+// max stale set by WithMaxStale.
+// Keys without a ttl and keys being loaded are never deleted by the reaper.
+// A deleted key is loaded again from the loader on its next Load,
+// same as Delete.
+//
+// The reaper runs until ctx is canceled or StopReaper is called.
+// Calling StartReaper again stops the previous reaper first.
+// It never blocks Load and other calls to the Map.
+//
+// It returns an error without starting (or stopping) any reaper if interval is
+// not positive.
+func (m *Map[K, T]) StartReaper(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("stalecache: non-positive StartReaper interval: %v", interval)
+	}
+	m.StopReaper()
+
+	m.reaperMu.Lock()
+	defer m.reaperMu.Unlock()
+	ctx, m.reaperCancel = context.WithCancel(ctx)
+	done := make(chan struct{})
+	m.reaperDone = done
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			m.reap()
+		}
+	}()
+	return nil
+}
+
+// StopReaper stops the reaper started by StartReaper and waits for it to
+// return.
+//
+// It's safe to call StopReaper multiple times,
+// or without calling StartReaper.
+func (m *Map[K, T]) StopReaper() {
+	m.reaperMu.Lock()
+	defer m.reaperMu.Unlock()
+	if m.reaperCancel == nil {
+		return
+	}
+	m.reaperCancel()
+	<-m.reaperDone
+	m.reaperCancel = nil
+	m.reaperDone = nil
+}
+
+// ReapedCount returns the number of keys deleted by the reaper.
+func (m *Map[K, T]) ReapedCount() uint64 {
+	return m.reaped.Load()
+}
+
+// reap does a single pass of the reaper.
+func (m *Map[K, T]) reap() {
+	m.caches.Range(func(key, c any) bool {
+		cache := c.(*Cache[T])
+		if !cache.reapable() {
+			return true
+		}
+		// Only delete the cache we checked,
+		// in case the key is deleted and loaded again concurrently.
+		if m.caches.CompareAndDelete(key, c) {
+			cache.Close()
+			m.reaped.Add(1)
+		}
+		return true
+	})
+}
+
+// reapable returns true if the current entry of c is too stale to be returned
+// by Load.
+func (c *Cache[T]) reapable() bool {
+	curr := c.current()
+	if !curr.done.Load() {
+		return false
+	}
+	d := curr.lastGood()
+	if d == nil {
+		d = curr
+	}
+	if c.ttl(d) <= 0 {
+		return false
+	}
+	return !c.expiry(d).Add(c.opt.maxStale).After(c.opt.now())
+}
@@ -0,0 +1,80 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestMapReaper(t *testing.T) {
+	const (
+		ttl      = 10 * time.Millisecond
+		maxStale = 5 * time.Millisecond
+		interval = time.Millisecond
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var calls atomic.Int64
+	m := stalecache.NewMap(
+		func(_ context.Context, key string) (*string, error) {
+			calls.Add(1)
+			return &key, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithMaxStale[string](maxStale),
+		stalecache.WithClock[string](clock),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := m.StartReaper(ctx, 0); err == nil {
+		t.Error("StartReaper with 0 interval got nil error")
+	}
+	if err := m.StartReaper(ctx, interval); err != nil {
+		t.Fatalf("StartReaper got error: %v", err)
+	}
+	defer m.StopReaper()
+
+	for _, key := range []string{"foo", "bar"} {
+		if _, err := m.Load(context.Background(), key); err != nil {
+			t.Fatalf("Load(%q) got error: %v", key, err)
+		}
+	}
+
+	// Stale but still within max stale, nothing is reaped.
+	clock.Advance(ttl)
+	time.Sleep(10 * interval)
+	if got := m.ReapedCount(); got != 0 {
+		t.Errorf("ReapedCount got %d, want 0", got)
+	}
+	if _, err := m.Load(context.Background(), "bar"); err != nil {
+		t.Fatalf("Load(bar) got error: %v", err)
+	}
+
+	clock.Advance(maxStale)
+	deadline := time.Now().Add(time.Second)
+	for m.ReapedCount() < 1 && time.Now().Before(deadline) {
+		time.Sleep(interval)
+	}
+	if got := m.ReapedCount(); got != 1 {
+		t.Fatalf("ReapedCount got %d, want 1", got)
+	}
+	snap := m.Snapshot()
+	if _, ok := snap["foo"]; ok {
+		t.Errorf("Snapshot got foo after it's reaped: %v", snap)
+	}
+	if _, ok := snap["bar"]; !ok {
+		t.Errorf("Snapshot missing bar: %v", snap)
+	}
+
+	m.StopReaper()
+	before := calls.Load()
+	if _, err := m.Load(context.Background(), "foo"); err != nil {
+		t.Fatalf("Load(foo) got error: %v", err)
+	}
+	if got := calls.Load(); got != before+1 {
+		t.Errorf("Got %d loader calls after reaped, want %d", got, before+1)
+	}
+}
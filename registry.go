@@ -0,0 +1,116 @@
+package stalecache
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// registry holds the caches registered by NewTyped and Register.
+var registry sync.Map // map[registryKey]any, values are *Cache[T]
+
+type registryKey struct {
+	name string
+	typ  reflect.Type
+}
+
+func newRegistryKey[T any](name string) registryKey {
+	return registryKey{
+		name: name,
+		typ:  reflect.TypeOf((*T)(nil)).Elem(),
+	}
+}
+
+// NewTyped creates a new Cache with loader and options,
+// and registers it in the package level registry with id.
+//
+// The id only needs to be unique among caches of the same T.
+// Registering a new Cache with the same T and id replaces the old one.
+//
+// Registered caches can be retrieved via Lookup,
+// invalidated via InvalidateAll, and iterated via ForEach.
+func NewTyped[T any](id string, loader Loader[T], options ...Option[T]) *Cache[T] {
+	c := New(loader, options...)
+	registry.Store(newRegistryKey[T](id), c)
+	return c
+}
+
+// Register registers c under name in a package level registry,
+// so it can be retrieved by Lookup or MustLookup elsewhere.
+//
+// It shares the same registry with NewTyped,
+// and caches of different types can be registered under the same name.
+// Unlike NewTyped, it panics if c is nil,
+// or a Cache of the same type is already registered under name.
+//
+// It's meant for applications that use one global cache per type,
+// similar to http.DefaultServeMux.
+// Passing the Cache explicitly is still preferred when it's practical.
+func Register[T any](name string, c *Cache[T]) {
+	if c == nil {
+		panic("stalecache: Register called with nil Cache")
+	}
+	key := newRegistryKey[T](name)
+	if _, loaded := registry.LoadOrStore(key, c); loaded {
+		panic(fmt.Sprintf("stalecache: Cache[%v] %q already registered", key.typ, name))
+	}
+}
+
+// Lookup returns the Cache of type T registered under name,
+// by either NewTyped or Register.
+func Lookup[T any](name string) (*Cache[T], bool) {
+	c, ok := registry.Load(newRegistryKey[T](name))
+	if !ok {
+		return nil, false
+	}
+	return c.(*Cache[T]), true
+}
+
+// MustLookup is Lookup but panics if no Cache of type T is registered under
+// name.
+func MustLookup[T any](name string) *Cache[T] {
+	c, ok := Lookup[T](name)
+	if !ok {
+		panic(fmt.Sprintf(
+			"stalecache: Cache[%v] %q not registered",
+			reflect.TypeOf((*T)(nil)).Elem(),
+			name,
+		))
+	}
+	return c
+}
+
+// Deregister removes the caches of all types registered under name.
+//
+// It's mainly useful for cleanups in tests.
+func Deregister(name string) {
+	registry.Range(func(key, _ any) bool {
+		if key.(registryKey).name == name {
+			registry.Delete(key)
+		}
+		return true
+	})
+}
+
+// ForEach calls f with every Cache of type T registered by NewTyped or
+// Register.
+//
+// The order of the iteration is unspecified.
+func ForEach[T any](f func(id string, c *Cache[T])) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	registry.Range(func(k, v any) bool {
+		if key := k.(registryKey); key.typ == typ {
+			f(key.name, v.(*Cache[T]))
+		}
+		return true
+	})
+}
+
+// InvalidateAll calls Reset on every Cache of type T registered by NewTyped or
+// Register, so they are back to the never-loaded state,
+// and the next Load on them calls the loader.
+func InvalidateAll[T any]() {
+	ForEach(func(_ string, c *Cache[T]) {
+		c.Reset()
+	})
+}
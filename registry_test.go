@@ -0,0 +1,151 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestRegistryTyped(t *testing.T) {
+	type myType struct{}
+	t.Cleanup(func() {
+		stalecache.Deregister("foo")
+		stalecache.Deregister("bar")
+	})
+
+	var loaderCalls atomic.Int64
+	loader := func(context.Context) (*myType, error) {
+		loaderCalls.Add(1)
+		return new(myType), nil
+	}
+	foo := stalecache.NewTyped("foo", loader)
+	bar := stalecache.NewTyped("bar", loader)
+
+	if got, ok := stalecache.Lookup[myType]("foo"); !ok || got != foo {
+		t.Errorf("Lookup(foo) got %p, %v, want %p", got, ok, foo)
+	}
+	if got, ok := stalecache.Lookup[int]("foo"); ok {
+		t.Errorf("Lookup[int](foo) got %p", got)
+	}
+	if got, ok := stalecache.Lookup[myType]("baz"); ok {
+		t.Errorf("Lookup(baz) got %p", got)
+	}
+
+	var ids []string
+	stalecache.ForEach(func(id string, _ *stalecache.Cache[myType]) {
+		ids = append(ids, id)
+	})
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "bar" || ids[1] != "foo" {
+		t.Errorf("ForEach got ids %q, want [bar foo]", ids)
+	}
+
+	foo.Load(context.Background())
+	bar.Load(context.Background())
+	stalecache.InvalidateAll[myType]()
+	foo.Load(context.Background())
+	bar.Load(context.Background())
+	if calls := loaderCalls.Load(); calls != 4 {
+		t.Errorf("Got %d loader calls, want 4", calls)
+	}
+
+	// NewTyped replaces instead of panicking.
+	newFoo := stalecache.NewTyped("foo", loader)
+	if got := stalecache.MustLookup[myType]("foo"); got != newFoo {
+		t.Errorf("MustLookup(foo) got %p, want %p", got, newFoo)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	const name = "TestRegistry"
+	t.Cleanup(func() {
+		stalecache.Deregister(name)
+	})
+
+	intCache := stalecache.New(func(context.Context) (*int, error) {
+		data := 1
+		return &data, nil
+	})
+	stringCache := stalecache.New(func(context.Context) (*string, error) {
+		data := "foo"
+		return &data, nil
+	})
+	stalecache.Register(name, intCache)
+	stalecache.Register(name, stringCache)
+
+	if c, ok := stalecache.Lookup[int](name); !ok || c != intCache {
+		t.Errorf("Lookup[int] got %p, %v, want %p, true", c, ok, intCache)
+	}
+	if c := stalecache.MustLookup[string](name); c != stringCache {
+		t.Errorf("MustLookup[string] got %p, want %p", c, stringCache)
+	}
+	if c, ok := stalecache.Lookup[float64](name); ok {
+		t.Errorf("Lookup[float64] got %p, true, want not found", c)
+	}
+
+	t.Run("duplicate", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("Register with duplicate name and type did not panic")
+			}
+		}()
+		stalecache.Register(name, intCache)
+	})
+
+	stalecache.Deregister(name)
+	if _, ok := stalecache.Lookup[int](name); ok {
+		t.Error("Lookup[int] found the Cache after Deregister")
+	}
+	if _, ok := stalecache.Lookup[string](name); ok {
+		t.Error("Lookup[string] found the Cache after Deregister")
+	}
+
+	t.Run("must", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("MustLookup for unregistered name did not panic")
+			}
+		}()
+		stalecache.MustLookup[int](name)
+	})
+}
+
+func TestInvalidateAllAsync(t *testing.T) {
+	const name = "TestInvalidateAllAsync"
+	t.Cleanup(func() {
+		stalecache.Deregister(name)
+	})
+
+	release := make(chan struct{})
+	c := stalecache.NewTyped(
+		name,
+		func(context.Context) (*int, error) {
+			<-release
+			var data int
+			return &data, nil
+		},
+		stalecache.WithAsyncLoad[int](true),
+	)
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if _, err := c.Load(context.Background()); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Cache not loaded in time")
+		}
+	}
+
+	release = make(chan struct{})
+	defer close(release)
+	stalecache.InvalidateAll[int]()
+	// Back to the never-loaded state, so Load doesn't block on the loader.
+	if _, err := c.Load(context.Background()); !errors.Is(err, stalecache.ErrNotYetLoaded) {
+		t.Errorf("Load got error %v, want %v", err, stalecache.ErrNotYetLoaded)
+	}
+}
@@ -0,0 +1,47 @@
+package stalecache
+
+// Result is a pair of value and error,
+// for example the result of a Load call returned by LoadAsync.
+type Result[T any] struct {
+	Value *T
+	Err   error
+}
+
+// OKResult returns a Result with v and nil error.
+func OKResult[T any](v *T) Result[T] {
+	return Result[T]{Value: v}
+}
+
+// ErrResult returns a Result with err and nil value.
+func ErrResult[T any](err error) Result[T] {
+	return Result[T]{Err: err}
+}
+
+func newResult[T any](v *T, err error) Result[T] {
+	return Result[T]{Value: v, Err: err}
+}
+
+// OK returns true if the Result has nil error.
+func (r Result[T]) OK() bool {
+	return r.Err == nil
+}
+
+// Unwrap returns the value and error of the Result.
+func (r Result[T]) Unwrap() (*T, error) {
+	return r.Value, r.Err
+}
+
+// Must returns the value of the Result, or panics when the error is non-nil.
+//
+// The panic value is the error if it's already a *CacheError,
+// otherwise it's a *CacheError with CodeLoadFailed wrapping the error.
+func (r Result[T]) Must() *T {
+	if r.Err != nil {
+		ce, ok := r.Err.(*CacheError)
+		if !ok {
+			ce = &CacheError{Code: CodeLoadFailed, Err: r.Err}
+		}
+		panic(ce)
+	}
+	return r.Value
+}
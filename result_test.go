@@ -0,0 +1,45 @@
+package stalecache_test
+
+import (
+	"errors"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestResult(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		data := 1
+		r := stalecache.OKResult(&data)
+		if !r.OK() {
+			t.Error("OK got false, want true")
+		}
+		if v, err := r.Unwrap(); v != &data || err != nil {
+			t.Errorf("Unwrap got (%v, %v), want (%v, nil)", v, err, &data)
+		}
+		if v := r.Must(); v != &data {
+			t.Errorf("Must got %v, want %v", v, &data)
+		}
+	})
+
+	t.Run("err", func(t *testing.T) {
+		wantErr := errors.New("foo")
+		r := stalecache.ErrResult[int](wantErr)
+		if r.OK() {
+			t.Error("OK got true, want false")
+		}
+		if v, err := r.Unwrap(); v != nil || err != wantErr {
+			t.Errorf("Unwrap got (%v, %v), want (nil, %v)", v, err, wantErr)
+		}
+		defer func() {
+			ce, ok := recover().(*stalecache.CacheError)
+			if !ok {
+				t.Fatal("Must did not panic with *CacheError")
+			}
+			if !errors.Is(ce, wantErr) {
+				t.Errorf("Must panicked with %v, want %v", ce, wantErr)
+			}
+		}()
+		r.Must()
+	})
+}
@@ -0,0 +1,152 @@
+package stalecache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithRetry is an Option to retry the loader when it fails.
+//
+// Default is 0 (or 1), means the loader is only called once per reload.
+// When attempts is larger than 1,
+// a failed loader call is retried after delay,
+// until it succeeds or it's called attempts times,
+// and the error from the last attempt is returned.
+// If the ctx is canceled while waiting for the next attempt,
+// the ctx error is returned instead.
+func WithRetry[T any](attempts int, delay time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.retryAttempts = attempts
+		o.retryDelay = delay
+	}
+}
+
+// refreshRetryBackoff is the delay before retrying a failed background
+// refresh with WithRefreshRetryLimit,
+// doubled for every consecutive failure up to maxRefreshRetryBackoff.
+const (
+	refreshRetryBackoff    = time.Second
+	maxRefreshRetryBackoff = 10 * time.Minute
+)
+
+// WithRefreshRetryLimit is an Option to cap the retries of the background
+// refreshes,
+// started by WithBackgroundRefresh, WithProbabilisticExpiry, WithRateLimit,
+// and PeekRefresh.
+//
+// Default is 0, means a failed background refresh is retried by the next Load
+// (or PeekRefresh) call still wanting it.
+// When n is positive,
+// after a background refresh failed the next one is only started after a
+// backoff (1s, doubled for every consecutive failure, up to 10m),
+// and after n consecutive failures no more background refreshes are started
+// for the current value,
+// until it's stale and reloaded by Load as usual.
+// The error from the last failure is then returned by LastError.
+//
+// It's counted separately from WithRetry,
+// which applies to every loader call, in background or not.
+func WithRefreshRetryLimit[T any](n int) Option[T] {
+	return func(o *opt[T]) {
+		o.refreshRetryLimit = n
+	}
+}
+
+// LastError returns the error from the last background refresh,
+// once the background refreshes of the current value failed as many times as
+// the limit set by WithRefreshRetryLimit.
+//
+// It returns nil if the limit is not reached,
+// or the loader succeeded after that.
+func (c *Cache[T]) LastError() error {
+	if err := c.lastErr.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// refreshAllowed returns true if a background refresh can be started to
+// replace curr, according to WithRefreshRetryLimit.
+func (c *Cache[T]) refreshAllowed(curr *cached[T]) bool {
+	if c.opt.refreshRetryLimit <= 0 {
+		return true
+	}
+	failures := curr.refreshFailures.Load()
+	if failures == 0 {
+		return true
+	}
+	if failures >= int64(c.opt.refreshRetryLimit) {
+		return false
+	}
+	backoff := maxRefreshRetryBackoff
+	// stop shifting before it overflows
+	if shift := failures - 1; shift < 32 && refreshRetryBackoff<<shift < backoff {
+		backoff = refreshRetryBackoff << shift
+	}
+	return !time.Unix(0, curr.refreshFailedAt.Load()).Add(backoff).After(c.opt.now())
+}
+
+// refreshFailed records the failed background refresh to replace curr for
+// WithRefreshRetryLimit.
+func (c *Cache[T]) refreshFailed(curr *cached[T], err error) {
+	if c.opt.refreshRetryLimit <= 0 {
+		return
+	}
+	curr.refreshFailedAt.Store(c.opt.now().UnixNano())
+	if curr.refreshFailures.Add(1) >= int64(c.opt.refreshRetryLimit) {
+		c.lastErr.Store(&err)
+	}
+}
+
+// WithLoadTimeout is an Option to set the timeout of every loader call.
+//
+// Default is 0, means no timeout other than the ones from the ctx.
+// When set, the ctx passed into the loader is canceled after d,
+// and the loader is expected to return an error (usually
+// context.DeadlineExceeded) which is treated the same as other loader errors.
+//
+// With WithRetry, the timeout applies to every attempt,
+// not the whole retry cycle.
+func WithLoadTimeout[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.loadTimeout = d
+	}
+}
+
+// TimeoutMiddleware is the LoaderMiddleware implementing WithLoadTimeout.
+func TimeoutMiddleware[T any](timeout time.Duration) LoaderMiddleware[T] {
+	return func(loader Loader[T]) Loader[T] {
+		return func(ctx context.Context) (*T, error) {
+			parent := ctx
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			data, err := loader(ctx)
+			if err != nil && parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return data, &CacheError{Code: CodeTimeout, Err: err}
+			}
+			return data, err
+		}
+	}
+}
+
+// RetryMiddleware is the LoaderMiddleware implementing WithRetry.
+func RetryMiddleware[T any](attempts int, delay time.Duration) LoaderMiddleware[T] {
+	return func(loader Loader[T]) Loader[T] {
+		return func(ctx context.Context) (*T, error) {
+			for i := 1; ; i++ {
+				data, err := loader(ctx)
+				if err == nil || i >= attempts {
+					return data, err
+				}
+				timer := time.NewTimer(delay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, &CacheError{Code: CodeCanceled, Err: ctx.Err()}
+				case <-timer.C:
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestRetry(t *testing.T) {
+	const (
+		attempts = 3
+		delay    = time.Millisecond
+	)
+	wantErr := errors.New("foo")
+
+	newCache := func(succeedAt int64, delay time.Duration, calls, ends *atomic.Int64) *stalecache.Cache[int] {
+		return stalecache.New(
+			func(context.Context) (*int, error) {
+				if calls.Add(1) < succeedAt {
+					return nil, wantErr
+				}
+				var data int
+				return &data, nil
+			},
+			stalecache.WithRetry[int](attempts, delay),
+			stalecache.WithHooks(stalecache.Hooks[int]{
+				OnLoadEnd: func(*int, error, time.Duration) {
+					ends.Add(1)
+				},
+			}),
+		)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		var calls, ends atomic.Int64
+		cache := newCache(attempts, delay, &calls, &ends)
+		if _, err := cache.Load(context.Background()); err != nil {
+			t.Errorf("Load got error: %v", err)
+		}
+		if got := calls.Load(); got != attempts {
+			t.Errorf("Got %d loader calls, want %d", got, attempts)
+		}
+		if got := ends.Load(); got != attempts {
+			t.Errorf("Got %d OnLoadEnd calls, want %d", got, attempts)
+		}
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		var calls, ends atomic.Int64
+		cache := newCache(attempts+1, delay, &calls, &ends)
+		if _, err := cache.ForceReload(context.Background()); !errors.Is(err, wantErr) {
+			t.Errorf("ForceReload got error %v, want %v", err, wantErr)
+		}
+		if got := calls.Load(); got != attempts {
+			t.Errorf("Got %d loader calls, want %d", got, attempts)
+		}
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		const timeout = 5 * time.Millisecond
+		var calls, ends atomic.Int64
+		// The delay is long enough that the retry only stops by cancellation.
+		cache := newCache(attempts+1, time.Hour, &calls, &ends)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := cache.ForceReload(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("ForceReload got error %v, want %v", err, context.DeadlineExceeded)
+		}
+		if err := cache.Drain(context.Background()); err != nil {
+			t.Errorf("Drain got error: %v", err)
+		}
+		if got := calls.Load(); got != 1 {
+			t.Errorf("Got %d loader calls, want 1", got)
+		}
+	})
+}
+
+func TestLoadTimeout(t *testing.T) {
+	const (
+		timeout  = 5 * time.Millisecond
+		attempts = 2
+	)
+	var calls atomic.Int64
+	cache := stalecache.New(
+		func(ctx context.Context) (*int, error) {
+			calls.Add(1)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		stalecache.WithLoadTimeout[int](timeout),
+		stalecache.WithRetry[int](attempts, time.Millisecond),
+	)
+	if _, err := cache.ForceReload(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ForceReload got error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := calls.Load(); got != attempts {
+		t.Errorf("Got %d loader calls, want %d", got, attempts)
+	}
+}
+
+func TestRefreshRetryLimit(t *testing.T) {
+	const (
+		ttl   = time.Minute
+		ahead = 30 * time.Second
+		limit = 2
+	)
+	wantErr := errors.New("bg failed")
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, wantErr
+			}
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		stalecache.WithBackgroundRefresh[int64](ahead),
+		stalecache.WithRefreshRetryLimit[int64](limit),
+	)
+	ctx := context.Background()
+	if _, err := cache.Load(ctx); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	// refresh keeps calling Load until the loader is called want times in
+	// total, or fails after a deadline.
+	refresh := func(t *testing.T, want int64) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); loaderCalls.Load() < want; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Got %d loader calls, want %d", loaderCalls.Load(), want)
+			}
+			if data, err := cache.Load(ctx); err != nil || *data != 1 {
+				t.Fatalf("Load got %v, %v, want 1, nil", data, err)
+			}
+		}
+		if err := cache.Drain(ctx); err != nil {
+			t.Fatalf("Drain got error: %v", err)
+		}
+	}
+	// noRefresh checks that Load does not call the loader.
+	noRefresh := func(t *testing.T) {
+		t.Helper()
+		before := loaderCalls.Load()
+		for i := 0; i < 10; i++ {
+			if data, err := cache.Load(ctx); err != nil || *data != 1 {
+				t.Fatalf("Load got %v, %v, want 1, nil", data, err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if got := loaderCalls.Load(); got != before {
+			t.Errorf("Got %d loader calls, want %d", got, before)
+		}
+	}
+
+	fail.Store(true)
+	clock.Advance(ttl - ahead)
+	refresh(t, 2)
+	if err := cache.LastError(); err != nil {
+		t.Errorf("LastError got %v before the limit, want nil", err)
+	}
+
+	// backoff
+	noRefresh(t)
+	clock.Advance(time.Second)
+	refresh(t, 3)
+	if err := cache.LastError(); !errors.Is(err, wantErr) {
+		t.Errorf("LastError got %v, want %v", err, wantErr)
+	}
+
+	// limit reached, until it's stale
+	clock.Advance(10 * time.Second)
+	noRefresh(t)
+
+	fail.Store(false)
+	clock.Advance(ahead)
+	data, err := cache.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != 4 {
+		t.Errorf("Load got %d, want 4", *data)
+	}
+	if err := cache.LastError(); err != nil {
+		t.Errorf("LastError got %v after reloaded, want nil", err)
+	}
+}
+
+func TestRefreshRetryLimitBackoffCap(t *testing.T) {
+	const (
+		ttl      = 100 * time.Hour
+		ahead    = 99 * time.Hour
+		failures = 40
+	)
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, errors.New("bg failed")
+			}
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		stalecache.WithBackgroundRefresh[int64](ahead),
+		stalecache.WithRefreshRetryLimit[int64](failures+1),
+	)
+	ctx := context.Background()
+	if _, err := cache.Load(ctx); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	fail.Store(true)
+	clock.Advance(ttl - ahead)
+	for i := int64(2); i <= failures+1; i++ {
+		// the backoff is capped at 10m
+		clock.Advance(10 * time.Minute)
+		for deadline := time.Now().Add(time.Second); loaderCalls.Load() < i; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Got %d loader calls, want %d", loaderCalls.Load(), i)
+			}
+			cache.Load(ctx)
+		}
+		if err := cache.Drain(ctx); err != nil {
+			t.Fatalf("Drain got error: %v", err)
+		}
+	}
+
+	// Still backing off after many failures.
+	before := loaderCalls.Load()
+	for i := 0; i < 10; i++ {
+		cache.Load(ctx)
+		time.Sleep(time.Millisecond)
+	}
+	if got := loaderCalls.Load(); got != before {
+		t.Errorf("Got %d loader calls, want %d", got, before)
+	}
+}
@@ -0,0 +1,63 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithMaxConcurrentLoads is an Option to limit the number of concurrent loader
+// calls to n across all the caches using the returned Option,
+// for example all the keys of a Map.
+//
+// Default is no limit.
+// When set, a loader call waits until there are less than n loader calls in
+// flight,
+// and if the ctx passed into the loader is canceled while waiting,
+// the loader is not called and Load returns a CacheError with CodeCanceled.
+//
+// The limit is created when WithMaxConcurrentLoads is called,
+// so to share the limit the same Option should be used,
+// while calling WithMaxConcurrentLoads once per cache gives every cache its
+// own limit.
+// Use WithSharedSemaphore to share the limit across different types.
+// Non-positive n means no limit.
+func WithMaxConcurrentLoads[T any](n int) Option[T] {
+	var sem chan struct{}
+	if n > 0 {
+		sem = make(chan struct{}, n)
+	}
+	return WithSharedSemaphore[T](sem)
+}
+
+// WithSharedSemaphore is the same as WithMaxConcurrentLoads,
+// except that sem is used as the limit,
+// so multiple Maps and Caches (of different types) can share the same
+// concurrency budget.
+//
+// The capacity of sem is the number of concurrent loader calls allowed,
+// and it must be buffered.
+// A loader call sends to sem before calling the loader and receives from it
+// after the loader returns.
+// Nil sem means no limit.
+func WithSharedSemaphore[T any](sem chan struct{}) Option[T] {
+	return func(o *opt[T]) {
+		o.semaphore = sem
+	}
+}
+
+func semaphoreLoader[T any](loader Loader[T], sem chan struct{}) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, &CacheError{
+				Code: CodeCanceled,
+				Err:  fmt.Errorf("failed to acquire semaphore: %w", ctx.Err()),
+			}
+		}
+		defer func() {
+			<-sem
+		}()
+		return loader(ctx)
+	}
+}
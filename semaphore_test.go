@@ -0,0 +1,93 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestMaxConcurrentLoads(t *testing.T) {
+	const (
+		limit = 2
+		n     = 10
+		sleep = 5 * time.Millisecond
+	)
+	var inflight, maxInflight atomic.Int64
+	m := stalecache.NewMap(
+		func(_ context.Context, key int) (*int, error) {
+			curr := inflight.Add(1)
+			defer inflight.Add(-1)
+			for {
+				max := maxInflight.Load()
+				if curr <= max || maxInflight.CompareAndSwap(max, curr) {
+					break
+				}
+			}
+			time.Sleep(sleep)
+			return &key, nil
+		},
+		stalecache.WithMaxConcurrentLoads[int](limit),
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := m.Load(context.Background(), i); err != nil {
+				t.Errorf("Load(%d) got error: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := maxInflight.Load(); got > limit {
+		t.Errorf("Got %d concurrent loader calls, want <= %d", got, limit)
+	}
+}
+
+func TestSharedSemaphore(t *testing.T) {
+	sem := make(chan struct{}, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := stalecache.New(
+		func(context.Context) (*int, error) {
+			close(started)
+			<-release
+			var data int
+			return &data, nil
+		},
+		stalecache.WithSharedSemaphore[int](sem),
+	)
+	go slow.Load(context.Background())
+	<-started
+	defer close(release)
+
+	var called atomic.Bool
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			called.Store(true)
+			var data string
+			return &data, nil
+		},
+		stalecache.WithSharedSemaphore[string](sem),
+	)
+	for i := 0; i < 2; i++ {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+			defer cancel()
+			_, err := cache.Load(ctx)
+			var ce *stalecache.CacheError
+			if !errors.As(err, &ce) || ce.Code != stalecache.CodeCanceled {
+				t.Errorf("Load got error %v, want CodeCanceled", err)
+			}
+			if called.Load() {
+				t.Error("Loader called without acquiring the semaphore")
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package stalecache
+
+import (
+	"context"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedGroup is the package level singleflight group used by SharedLoader.
+var sharedGroup singleflight.Group
+
+// SharedLoader wraps loader so that at most one call to it is in-flight
+// at any given time across all the Cache instances using a SharedLoader with
+// the same key.
+//
+// Concurrent calls of the returned Loader with the same key wait for the
+// in-flight one and share its result,
+// while every Cache still has its own ttl and other options.
+// The ctx of the in-flight call is the one passed into the underlying loader,
+// so canceling the ctx of the other calls does not cancel it.
+//
+// Different loaders must use different keys.
+func SharedLoader[T any](key string, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		data, err, _ := sharedGroup.Do(key, func() (any, error) {
+			return loader(ctx)
+		})
+		if data == nil {
+			return nil, err
+		}
+		return data.(*T), err
+	}
+}
@@ -0,0 +1,47 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestSharedLoader(t *testing.T) {
+	const (
+		key   = "TestSharedLoader"
+		sleep = 20 * time.Millisecond
+		n     = 5
+	)
+	var loaderCalls atomic.Int64
+	loader := stalecache.SharedLoader(key, func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		time.Sleep(sleep)
+		return &calls, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		cache := stalecache.New(loader)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			data, err := cache.Load(context.Background())
+			if err != nil {
+				t.Errorf("Load #%d got error: %v", i, err)
+				return
+			}
+			if *data != 1 {
+				t.Errorf("Load #%d got %d, want 1", i, *data)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls, want 1", calls)
+	}
+}
@@ -0,0 +1,39 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// ErrSkipped is the error returned by Load with WithSkipCondition when the
+// loader is skipped and there's no data loaded before.
+var ErrSkipped error = &CacheError{Code: CodeSkipped}
+
+// WithSkipCondition is an Option to skip the loader when skip returns true,
+// for example during maintenance or when a feature flag is off.
+//
+// Default is nil, means the loader is never skipped.
+// When set, skip is called with the ctx passed into Load after the cache is
+// found stale (by the ttl or the validator),
+// and when it returns true Load returns the current data (with the error of
+// the last load, if any) as-is, without calling the loader or replacing the
+// cached entry.
+// If there's no data loaded before, Load returns ErrSkipped instead.
+func WithSkipCondition[T any](skip func(context.Context) bool) Option[T] {
+	return func(o *opt[T]) {
+		o.skip = skip
+	}
+}
+
+func (c *Cache[T]) skipped(ctx context.Context) bool {
+	return c.opt.skip != nil && c.opt.skip(ctx)
+}
+
+// skipResult is the result of Load when the loader is skipped,
+// stale is the last successfully loaded entry and err is the error of the
+// last load.
+func skipResult[T any](stale *cached[T], err error) (*T, *cached[T], error) {
+	if stale == nil {
+		return nil, nil, ErrSkipped
+	}
+	return stale.data, stale, err
+}
@@ -0,0 +1,77 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestSkipCondition(t *testing.T) {
+	const ttl = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls int
+	skip := true
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls++
+			return &loaderCalls, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithSkipCondition[int](func(context.Context) bool {
+			return skip
+		}),
+	)
+
+	t.Run("never-loaded", func(t *testing.T) {
+		data, err := cache.Load(context.Background())
+		if !errors.Is(err, stalecache.ErrSkipped) {
+			t.Errorf("Load got error %v, want %v", err, stalecache.ErrSkipped)
+		}
+		if data != nil {
+			t.Errorf("Load got %d, want nil", *data)
+		}
+		if loaderCalls != 0 {
+			t.Errorf("Got %d loader calls, want 0", loaderCalls)
+		}
+	})
+
+	skip = false
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	skip = true
+
+	t.Run("fresh", func(t *testing.T) {
+		data, err := cache.Load(context.Background())
+		if err != nil || *data != 1 {
+			t.Errorf("Load got %v, %v, want 1, nil", data, err)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		clock.Advance(ttl)
+		data, err := cache.Load(context.Background())
+		if err != nil || *data != 1 {
+			t.Errorf("Load got %v, %v, want 1, nil", data, err)
+		}
+		if loaderCalls != 1 {
+			t.Errorf("Got %d loader calls, want 1", loaderCalls)
+		}
+		if !cache.IsStale(context.Background()) {
+			t.Error("Cache is not stale after skipped reload")
+		}
+	})
+
+	t.Run("not-skipped", func(t *testing.T) {
+		skip = false
+		data, err := cache.Load(context.Background())
+		if err != nil || *data != 2 {
+			t.Errorf("Load got %v, %v, want 2, nil", data, err)
+		}
+	})
+}
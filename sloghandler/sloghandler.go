@@ -0,0 +1,78 @@
+// Package sloghandler provides structured logging for stalecache with
+// log/slog.
+package sloghandler // import "go.yhsif.com/stalecache/sloghandler"
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+// KeyAttr is the attribute key of the cache key set by
+// stalecache.WithCacheKey.
+const KeyAttr = "stalecache.key"
+
+// NameAttr is the attribute key of the cache name set by
+// stalecache.WithName.
+const NameAttr = "stalecache.name"
+
+// WithSlogLogger is an Option to log cache events to logger.
+//
+// The events logged are:
+//
+//   - cache miss, at Debug level
+//   - loader start, at Debug level
+//   - loader success, at Info level, with the duration ("took")
+//   - loader failure, at Warn level, with the error ("err")
+//   - circuit breaker open, at Error level
+//
+// All records have the cache key set by stalecache.WithCacheKey as the
+// "stalecache.key" attribute,
+// and the cache name set by stalecache.WithName as the "stalecache.name"
+// attribute if it's not empty.
+func WithSlogLogger[T any](logger *slog.Logger) stalecache.Option[T] {
+	return stalecache.WithNamedHooks(func(name, key string) stalecache.Hooks[T] {
+		l := logger.With(slog.String(KeyAttr, key))
+		if name != "" {
+			l = l.With(slog.String(NameAttr, name))
+		}
+		return Hooks[T](l)
+	})
+}
+
+// Hooks returns the stalecache.Hooks used by WithSlogLogger.
+//
+// Unlike WithSlogLogger, it does not add the cache key and name attributes to
+// logger.
+func Hooks[T any](logger *slog.Logger) stalecache.Hooks[T] {
+	ctx := context.Background()
+	return stalecache.Hooks[T]{
+		OnMiss: func() {
+			logger.DebugContext(ctx, "stalecache: cache miss")
+		},
+		OnLoadStart: func() {
+			logger.DebugContext(ctx, "stalecache: loader start")
+		},
+		OnLoadEnd: func(_ *T, err error, took time.Duration) {
+			if err != nil {
+				logger.WarnContext(
+					ctx,
+					"stalecache: loader failed",
+					slog.Any("err", err),
+					slog.Duration("took", took),
+				)
+				return
+			}
+			logger.InfoContext(
+				ctx,
+				"stalecache: loader succeeded",
+				slog.Duration("took", took),
+			)
+		},
+		OnCircuitOpen: func() {
+			logger.ErrorContext(ctx, "stalecache: circuit breaker open")
+		},
+	}
+}
@@ -0,0 +1,61 @@
+package sloghandler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/sloghandler"
+)
+
+func TestWithSlogLogger(t *testing.T) {
+	const (
+		key  = "my-cache"
+		name = "users"
+	)
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "took" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	var fail bool
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail {
+				return nil, errors.New("foo")
+			}
+			var data int
+			return &data, nil
+		},
+		sloghandler.WithSlogLogger[int](logger),
+		stalecache.WithCacheKey[int](key),
+		stalecache.WithName[int](name),
+		stalecache.WithCircuitBreaker[int](1, time.Hour),
+	)
+	cache.Load(context.Background())
+	fail = true
+	cache.ForceReload(context.Background())
+
+	got := buf.String()
+	t.Logf("Logs:\n%s", got)
+	for _, want := range []string{
+		`level=DEBUG msg="stalecache: loader start" stalecache.key=my-cache stalecache.name=users`,
+		`level=INFO msg="stalecache: loader succeeded" stalecache.key=my-cache stalecache.name=users`,
+		`level=WARN msg="stalecache: loader failed" stalecache.key=my-cache stalecache.name=users err=foo`,
+		`level=ERROR msg="stalecache: circuit breaker open" stalecache.key=my-cache stalecache.name=users`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Logs do not contain %q", want)
+		}
+	}
+}
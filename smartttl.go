@@ -0,0 +1,101 @@
+package stalecache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SmartTTLConfig defines the config used by WithSmartTTL.
+//
+// The effective TTL is calculated as:
+//
+//	BaseTTL * (1 + LatencyFactor) * (1 - ErrorPenalty) + HotBonus
+//
+// Where:
+//
+//   - LatencyFactor is LatencyMultiplier * (latency of the last loader call) /
+//     BaseTTL, so slower loaders cause longer TTL.
+//   - ErrorPenalty is ErrorPenaltyFactor * (ratio of failed loader calls),
+//     capped at 1, so flaky loaders cause shorter TTL.
+//   - HotBonus is HotAccessBonus * hits / (hits + 1),
+//     where hits is the number of Load calls served by the current value,
+//     so frequently accessed values stay longer.
+type SmartTTLConfig struct {
+	BaseTTL            time.Duration
+	LatencyMultiplier  float64
+	ErrorPenaltyFactor float64
+	HotAccessBonus     time.Duration
+}
+
+// SmartTTLStats shows each component's contribution to the effective TTL
+// calculated from SmartTTLConfig.
+type SmartTTLStats struct {
+	BaseTTL       time.Duration
+	LatencyFactor float64
+	ErrorPenalty  float64
+	HotBonus      time.Duration
+	EffectiveTTL  time.Duration
+}
+
+// WithSmartTTL is an Option to set a dynamic TTL calculated from the load
+// latency, the loader error rate, and the access frequency.
+//
+// Default is nil.
+// When set it overrides WithTTL.
+// See SmartTTLConfig for the details of the calculation.
+// If the calculated TTL is not positive, the value is considered stale
+// immediately.
+func WithSmartTTL[T any](config SmartTTLConfig) Option[T] {
+	return func(o *opt[T]) {
+		o.smartTTL = &config
+	}
+}
+
+type smartTTLState struct {
+	lastLatency atomic.Int64
+	loads       atomic.Uint64
+	errors      atomic.Uint64
+}
+
+func smartTTLLoader[T any](s *smartTTLState, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		start := time.Now()
+		data, err := loader(ctx)
+		s.lastLatency.Store(int64(time.Since(start)))
+		s.loads.Add(1)
+		if err != nil {
+			s.errors.Add(1)
+		}
+		return data, err
+	}
+}
+
+func (s *smartTTLState) stats(config SmartTTLConfig, hits uint64) SmartTTLStats {
+	stats := SmartTTLStats{
+		BaseTTL: config.BaseTTL,
+	}
+	if config.BaseTTL > 0 {
+		stats.LatencyFactor = config.LatencyMultiplier * float64(s.lastLatency.Load()) / float64(config.BaseTTL)
+	}
+	if loads := s.loads.Load(); loads > 0 {
+		stats.ErrorPenalty = config.ErrorPenaltyFactor * float64(s.errors.Load()) / float64(loads)
+		if stats.ErrorPenalty > 1 {
+			stats.ErrorPenalty = 1
+		}
+	}
+	stats.HotBonus = time.Duration(float64(config.HotAccessBonus) * float64(hits) / float64(hits+1))
+	stats.EffectiveTTL = time.Duration(float64(config.BaseTTL)*(1+stats.LatencyFactor)*(1-stats.ErrorPenalty)) + stats.HotBonus
+	return stats
+}
+
+// SmartTTLStats returns the current effective TTL calculated from the config
+// set by WithSmartTTL, and each component's contribution to it.
+//
+// It returns zero value if WithSmartTTL is not set.
+func (c *Cache[T]) SmartTTLStats() SmartTTLStats {
+	if c.smart == nil {
+		return SmartTTLStats{}
+	}
+	return c.smart.stats(*c.opt.smartTTL, c.current().hits.Load())
+}
@@ -0,0 +1,67 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestSmartTTL(t *testing.T) {
+	const (
+		sleep = time.Millisecond
+		bonus = time.Second
+	)
+	config := stalecache.SmartTTLConfig{
+		BaseTTL:            time.Minute,
+		LatencyMultiplier:  10,
+		ErrorPenaltyFactor: 0.5,
+		HotAccessBonus:     bonus,
+	}
+	var calls int
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			calls++
+			time.Sleep(sleep)
+			if calls == 1 {
+				return nil, errors.New("foo")
+			}
+			return &calls, nil
+		},
+		stalecache.WithSmartTTL[int](config),
+	)
+
+	if got := cache.SmartTTLStats(); got.EffectiveTTL != config.BaseTTL {
+		t.Errorf("SmartTTLStats before Load got %+v, want EffectiveTTL %v", got, config.BaseTTL)
+	}
+
+	// First loader call fails and the second one succeeds.
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	// Served from cache.
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Got %d loader calls, want 2", calls)
+	}
+
+	got := cache.SmartTTLStats()
+	t.Logf("SmartTTLStats: %+v", got)
+	if min := config.LatencyMultiplier * float64(sleep) / float64(config.BaseTTL); got.LatencyFactor < min {
+		t.Errorf("LatencyFactor got %v, want >= %v", got.LatencyFactor, min)
+	}
+	if want := 0.25; got.ErrorPenalty != want {
+		t.Errorf("ErrorPenalty got %v, want %v", got.ErrorPenalty, want)
+	}
+	if want := bonus / 2; got.HotBonus != want {
+		t.Errorf("HotBonus got %v, want %v", got.HotBonus, want)
+	}
+	want := time.Duration(float64(config.BaseTTL)*(1+got.LatencyFactor)*(1-got.ErrorPenalty)) + got.HotBonus
+	if got.EffectiveTTL != want {
+		t.Errorf("EffectiveTTL got %v, want %v", got.EffectiveTTL, want)
+	}
+}
@@ -2,30 +2,164 @@ package stalecache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 type cached[T any] struct {
-	once   sync.Once
+	// mu guards started, waiters, base, ctx, cancel, canceled, and finished.
+	mu sync.Mutex
+	// started is set to true when this entry starts to be filled.
+	started bool
+	// waiters is the number of Load calls waiting for the loader to fill this
+	// entry.
+	waiters int
+	// base carries the values (but not the cancellation) of the ctx of the
+	// Load call started to fill this entry.
+	base context.Context
+	// ctx is the ctx passed into the loader, derived from base.
+	ctx context.Context
+	// cancel cancels ctx,
+	// it's called when all the waiters gave up.
+	cancel context.CancelFunc
+	// canceled is set to true when cancel is called because all the waiters
+	// gave up.
+	canceled bool
+	// finished is closed after this entry is filled.
+	finished chan struct{}
+	// panicked is set to true when filling this entry panicked without
+	// WithRecoverPanics,
+	// in which case err is the PanicError and the waiters re-raise the panic.
+	panicked bool
+
+	// replacing is the entry this entry is loaded in background to replace,
+	// see refreshInBackground.
+	replacing *cached[T]
+	// detached is set to true for the entries filled before being stored (by
+	// WithCoalescing(false)).
+	detached bool
+	// held is set to true for the entries loaded by SyncGroup.RefreshAll,
+	// which are never stored as-is.
+	held bool
+
 	data   *T
 	loaded time.Time
 	err    error
+	// version is the version of the Cache when this entry is filled
+	// successfully.
+	version uint64
+	// ttl is the ttl of this entry returned by WithDynamicTTL.
+	ttl time.Duration
+
+	// done is set to true after this entry is filled.
+	done atomic.Bool
+	// hits is the number of Load calls returned this entry as fresh.
+	hits atomic.Uint64
+	// invalidated is set to true by WithEagerInvalidation when this entry is
+	// being replaced.
+	invalidated atomic.Bool
+	// asyncStarted is set to true by WithAsyncLoad when a background load is
+	// started for this entry.
+	asyncStarted atomic.Bool
+
+	// prev is the last successfully loaded entry this entry is replacing,
+	// it's set before this entry is stored into Cache and cleared after this
+	// entry is loaded successfully.
+	prev atomic.Pointer[cached[T]]
+
+	// accessed is the last time (in unix nanoseconds) the data of this entry is
+	// returned by Load, only used by WithSlidingTTL and WithIdleTTL.
+	accessed atomic.Int64
+
+	// next is the entry being loaded in background to replace this entry.
+	next atomic.Pointer[cached[T]]
+
+	// loadDuration is how long the loader call took to fill this entry,
+	// only used by WithProbabilisticExpiry.
+	loadDuration time.Duration
+
+	// failedAt is the time (in unix nanoseconds) of the first failed reload
+	// since this entry is loaded successfully, only used by WithGracePeriod.
+	failedAt atomic.Int64
+
+	// staleNotified is set to true when the callback set by WithOnStale is
+	// called for this entry.
+	staleNotified atomic.Bool
+
+	// expiresAt is the expiry returned by the ExpiringLoader of NewExpiring,
+	// zero otherwise.
+	expiresAt time.Time
+
+	// evicted is set to true when the callback set by WithOnEvict is called for
+	// the data of this entry.
+	evicted atomic.Bool
+
+	// refreshFailures is the number of consecutive failed background refreshes
+	// to replace this entry,
+	// and refreshFailedAt is the time (in unix nanoseconds) of the last one,
+	// only used by WithRefreshRetryLimit.
+	refreshFailures atomic.Int64
+	refreshFailedAt atomic.Int64
 }
 
-func (d *cached[T]) load(ctx context.Context, loader Loader[T]) (*T, time.Time, error) {
-	d.once.Do(func() {
-		d.data, d.err = loader(ctx)
-		d.loaded = time.Now()
-	})
-	return d.data, d.loaded, d.err
+// good returns the last successfully loaded entry of d without waiting for
+// it, which is d.prev if d is still being loaded.
+func (d *cached[T]) good() *cached[T] {
+	if d.done.Load() {
+		return d.lastGood()
+	}
+	return d.prev.Load()
 }
 
-func (d *cached[T]) update(data *T) {
-	d.once.Do(func() {
+// lastGood returns d if it's loaded successfully, or d.prev otherwise.
+//
+// It must only be called after d is loaded.
+func (d *cached[T]) lastGood() *cached[T] {
+	if d.err == nil {
+		return d
+	}
+	return d.prev.Load()
+}
+
+// do fills d by calling f,
+// unless d is already being filled,
+// in which case it waits for that to finish instead.
+func (d *cached[T]) do(f func()) {
+	d.mu.Lock()
+	if d.started {
+		finished := d.finished
+		d.mu.Unlock()
+		<-finished
+		return
+	}
+	d.started = true
+	d.finished = make(chan struct{})
+	d.mu.Unlock()
+
+	defer d.finish()
+	f()
+}
+
+// finish marks d as filled.
+//
+// It must only be called once by the one started to fill d.
+func (d *cached[T]) finish() {
+	d.done.Store(true)
+	close(d.finished)
+}
+
+func (d *cached[T]) set(data *T, loaded time.Time, err error) {
+	d.do(func() {
 		d.data = data
-		d.loaded = time.Now()
+		d.loaded = loaded
+		d.err = err
 	})
 }
 
@@ -35,17 +169,162 @@ func (d *cached[T]) update(data *T) {
 type Loader[T any] func(context.Context) (*T, error)
 
 // Cache defines a cached single value with optional TTL.
+//
+// T is the type of the value itself, not a pointer to it,
+// as Load and its variants already return *T.
+// For example, use Cache[MyStruct] (and Loader[MyStruct] returning
+// *MyStruct), not Cache[*MyStruct],
+// which would make the loader return **MyStruct instead.
+// Passing a func returning (*MyStruct, error) to New infers T as MyStruct.
 type Cache[T any] struct {
 	opt opt[T]
 
+	// init lazily sets up pool and the initial entry in cached,
+	// use current instead of reading cached directly.
+	init   func()
 	cached atomic.Pointer[cached[T]]
-	pool   sync.Pool
+	pool   *sync.Pool
+	// pooled is the approximate number of items currently in pool.
+	pooled atomic.Int64
+
+	// version is increased by every successful load or update.
+	version atomic.Uint64
+
+	// everLoaded is set to true after the first successful load or update.
+	everLoaded atomic.Bool
+
+	// previous is the last successfully loaded entry before the current one,
+	// used by Rollback.
+	previous atomic.Pointer[cached[T]]
+
+	// lastErr is the error returned by LastError.
+	lastErr atomic.Pointer[error]
+
+	// metadata set by WithMetadataStore.
+	meta atomic.Pointer[any]
+
+	stats statsCounters
+	subs  subscribers[T]
+	// only non-nil when WithConcurrencyMetrics is set.
+	concurrency *concurrencyCounters
+	// only non-nil when WithSmartTTL is set.
+	smart *smartTTLState
+	// only non-nil when WithRateLimit is set.
+	limiter *rate.Limiter
+	// only used by WithDebounce.
+	debounced debouncer[T]
+	// only used by WithPubSub.
+	published published[T]
+
+	// background goroutines started by options, stopped by Close.
+	bgCtx    context.Context
+	bgCancel context.CancelFunc
+	bgWG     sync.WaitGroup
 }
 
 type opt[T any] struct {
-	loader    Loader[T]
-	ttl       time.Duration
-	validator func(context.Context, *T, time.Time) bool
+	loader Loader[T]
+	// batcher is a *batcher[K, T] set by WithBatchLoader, used by Map.
+	batcher  any
+	ttl      time.Duration
+	errorTTL time.Duration
+	negTTL   time.Duration
+	// readOnly is set by NewReplica.
+	readOnly   bool
+	isNegative func(error) bool
+	slidingTTL time.Duration
+	dynamicTTL func(*T) time.Duration
+	idleTTL    time.Duration
+	bucketTTL  time.Duration
+	debounce   time.Duration
+	jitter     float64
+	maxStale   time.Duration
+	grace      time.Duration
+	validator  func(context.Context, *T, time.Time) bool
+	// set by WithValidatorV2
+	validatorV2 func(context.Context, *T, time.Time) (*T, bool)
+	clock       Clock
+
+	noCoalescing       bool
+	concurrencyMetrics bool
+	eagerInvalidation  bool
+	refreshAhead       time.Duration
+	xfetchBeta         float64
+	asyncLoad          bool
+	contextFunc        func(context.Context) context.Context
+
+	pool      *sync.Pool
+	onPoolGet func()
+	onPoolPut func()
+
+	weightedLoaders []LoaderWeight[T]
+	adaptiveWeights bool
+	weighted        *weightedLoaders[T]
+
+	initialValue *T
+
+	merge     func(old, fresh *T) *T
+	equal     func(a, b *T) bool
+	copyFn    func(*T) *T
+	cow       bool
+	sizeFn    func(*T) int64
+	maxBytes  int64
+	transform func(context.Context, *T) (*T, error)
+
+	hooks       []Hooks[T]
+	keyedHooks  []func(name, key string) Hooks[T]
+	key         string
+	name        string
+	middlewares []LoaderMiddleware[T]
+
+	preRefresh  func(ctx context.Context, current *T)
+	postRefresh func(ctx context.Context, old, new *T, err error)
+	onStale     func(ctx context.Context, data *T, loaded time.Time)
+	onEvict     func(ctx context.Context, old *T)
+	traceFunc   TraceFunc
+	skip        func(context.Context) bool
+
+	metaInit   any
+	updateMeta func(prev any, data *T, loaded time.Time) any
+
+	mutexName    string
+	mutexTimeout time.Duration
+	semaphore    chan struct{}
+
+	// expiring is set by NewExpiring.
+	expiring bool
+
+	streamFallback   Loader[T]
+	streamBackoffMin time.Duration
+	streamBackoffMax time.Duration
+
+	smartTTL *SmartTTLConfig
+
+	retryAttempts     int
+	retryDelay        time.Duration
+	refreshRetryLimit int
+	loadTimeout       time.Duration
+	recoverPanics     bool
+
+	rateLimit bool
+	rps       float64
+	burst     int
+
+	circuitThreshold int
+	circuitCooldown  time.Duration
+
+	warmer         func(context.Context) []*T
+	warmerInterval time.Duration
+
+	pubsub        PubSubAdapter[T]
+	writer        func(context.Context, *T) error
+	invalidations []<-chan struct{}
+
+	errorFallback Loader[T]
+	errorWrapper  func(error) error
+
+	encode func(*T) ([]byte, error)
+	decode func([]byte) (*T, error)
 }
 
 // Option defines Cache options.
@@ -61,88 +340,2133 @@ func WithTTL[T any](ttl time.Duration) Option[T] {
 	}
 }
 
+// WithJitter is an Option to randomize the TTL set by WithTTL.
+//
+// Default is 0, means no jitter.
+// Set it to a value in (0, 1] will cause the ttl to be randomly extended or
+// shortened by up to factor*ttl, once when the Cache is created.
+// Values larger than 1 are treated as 1.
+// The randomized ttl is always positive.
+//
+// It's useful to avoid correlated reloads of multiple caches created at the
+// same time with the same ttl.
+func WithJitter[T any](factor float64) Option[T] {
+	return func(o *opt[T]) {
+		o.jitter = factor
+	}
+}
+
+// jitterTTL returns ttl randomly extended or shortened by up to factor*ttl.
+func jitterTTL(ttl time.Duration, factor float64) time.Duration {
+	if factor > 1 {
+		factor = 1
+	}
+	ttl += time.Duration((rand.Float64()*2 - 1) * factor * float64(ttl))
+	if ttl <= 0 {
+		return time.Nanosecond
+	}
+	return ttl
+}
+
+// WithIdleTTL is an Option to set an idle TTL for the cache.
+//
+// Default is 0, means no idle TTL.
+// Set it to positive value will cause the cache to be re-loaded after it's
+// not returned by any Load for d (or for d since it's loaded,
+// if it's never returned by Load).
+// So a cache without readers naturally expires.
+//
+// It's similar to WithSlidingTTL, but can be used together with WithTTL
+// (and other ttl related Options),
+// and the cache is stale when either the ttl or the idle TTL is reached.
+func WithIdleTTL[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.idleTTL = d
+	}
+}
+
+// WithDynamicTTL is an Option to set the ttl of every loaded value based on
+// the value itself,
+// for example from the expiry of a token or the max-age of an HTTP response.
+//
+// Default is nil, means the ttl is from WithTTL.
+// When set, ttlFn is called with every successfully loaded (or updated)
+// value, and the returned duration is used as the ttl of that value,
+// overriding WithTTL, WithSlidingTTL, and WithSmartTTL.
+// Not positive duration means the value is stale immediately,
+// so the next Load calls the loader again.
+func WithDynamicTTL[T any](ttlFn func(*T) time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.dynamicTTL = ttlFn
+	}
+}
+
+// dynamicTTL returns the ttl of data according to WithDynamicTTL.
+func (c *Cache[T]) dynamicTTL(data *T) time.Duration {
+	if c.opt.dynamicTTL == nil {
+		return 0
+	}
+	if ttl := c.opt.dynamicTTL(data); ttl > 0 {
+		return ttl
+	}
+	// stale immediately
+	return time.Nanosecond
+}
+
+// WithSlidingTTL is an Option to set a sliding TTL for the cache.
+//
+// Default is 0, means no sliding TTL.
+// Set it to positive value will cause the cache to be re-loaded after it's not
+// returned by any Load for d,
+// every Load returning the fresh cached value restarts the countdown.
+//
+// It's mutually exclusive with WithTTL,
+// New panics when both are set.
+func WithSlidingTTL[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.slidingTTL = d
+	}
+}
+
+// WithBucketTTL is an Option to align the expiry of the cache to the
+// boundaries of bucket.
+//
+// Default is 0, means no alignment.
+// When set, a value loaded at t expires at t.Truncate(bucket).Add(bucket),
+// for example with 30s bucket a value loaded at 12:00:10 expires at 12:00:30,
+// so all the caches with the same bucket (across processes with synchronized
+// clocks) expire at the same time,
+// regardless of when they were created or last reloaded.
+// See time.Time.Truncate for how the boundaries are defined.
+//
+// It takes precedence over WithTTL, WithDynamicTTL and WithSmartTTL.
+// It's mutually exclusive with WithSlidingTTL,
+// New panics (NewE returns an error) when both are set.
+func WithBucketTTL[T any](bucket time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.bucketTTL = bucket
+	}
+}
+
+// WithErrorTTL is an Option to set the TTL for failed loads.
+//
+// Default is 0, means a failed load is retried by the next Load call.
+// Set it to positive value will cause all Load calls within d after a failed
+// load to return the error from the failed load without calling the loader
+// again,
+// along with the stale data from before the failed load, if any.
+// This avoids hammering the external source during outages.
+func WithErrorTTL[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.errorTTL = d
+	}
+}
+
+// WithOnErrorFallback is an Option to set a secondary loader,
+// used when the loader failed and there's no stale data to return.
+//
+// Default is nil, means Load returns nil data along with the error.
+// When set, fallback is called in that case (including when the stale data is
+// too old according to WithMaxStale or WithGracePeriod),
+// and its result is returned with nil error,
+// but it's not stored in the cache,
+// so the next Load still calls the loader.
+// If fallback also fails,
+// the errors from both are joined by errors.Join.
+//
+// fallback is not called when the ctx of Load is canceled.
+func WithOnErrorFallback[T any](fallback Loader[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.errorFallback = fallback
+	}
+}
+
+// WithErrorWrapper is an Option to wrap the errors returned by the loader.
+//
+// Default is nil, means the errors from the loader are returned as-is.
+// When set, wrapper is called with every non-nil error returned by the
+// loader (including the ones from WithWeightedLoaders),
+// and the returned error is used instead.
+// It's useful to add stack traces or sentinel errors without modifying the
+// loader.
+//
+// Errors from the mechanisms of this package (CacheError) are never passed
+// into wrapper.
+// wrapper should keep the original error available to errors.As,
+// for example by using %w with fmt.Errorf.
+func WithErrorWrapper[T any](wrapper func(error) error) Option[T] {
+	return func(o *opt[T]) {
+		o.errorWrapper = wrapper
+	}
+}
+
+func errorWrapperLoader[T any](loader Loader[T], wrapper func(error) error) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		data, err := loader(ctx)
+		var ce *CacheError
+		if err != nil && !errors.As(err, &ce) {
+			err = wrapper(err)
+		}
+		return data, err
+	}
+}
+
+// WithMaxStale is an Option to set the maximum age of the stale data Load
+// could return when the reload failed.
+//
+// Default is 0, means there's no maximum and the stale data is always
+// returned along with the error from the loader,
+// the same as negative values.
+// Set it to positive value will cause Load to return nil data along with the
+// error instead, once the stale data was loaded more than d ago.
+//
+// WithTTL controls when a reload is attempted,
+// and WithMaxStale controls for how long stale data can still be used when the
+// reload keeps failing,
+// so it's usually set to a value larger than the ttl.
+func WithMaxStale[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.maxStale = d
+	}
+}
+
+// WithGracePeriod is an Option to set for how long the stale data Load could
+// return when the reload keeps failing.
+//
+// Default is 0, means there's no grace period and the stale data is always
+// returned along with the error from the loader.
+// Set it to positive value will cause Load to return nil data along with the
+// error instead, once the reload has been failing continuously for more than
+// d since the first failure.
+//
+// It differs from WithMaxStale in that WithMaxStale is measured from the time
+// the stale data was loaded,
+// while WithGracePeriod is measured from the time the reload started to fail.
+func WithGracePeriod[T any](d time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.grace = d
+	}
+}
+
 // WithValidator is an Option to set a validator to the cache.
 //
 // Default is nil.
 // A non-nil validator will be called (only after the ttl check passed if set),
-// and if it returns true the cache will be re-loaded.
+// and if it returns false the cache will be re-loaded.
 //
 // An usual use case for validator is to use a faster external source
 // (for example, redis) to validate whether the cache is fresh.
+//
+// The validator could call Load on other Cache instances with the ctx passed
+// in.
+// If that causes the validator of this Cache to be called again with the same
+// call chain (for example, validator of cache A loads cache B,
+// and validator of cache B loads cache A),
+// the nested validator call is skipped and the value is considered stale,
+// instead of recursing forever.
+//
+// It can be used multiple times (along with WithAllValidators and
+// WithAnyValidator),
+// the cache is only considered fresh when all of them return true.
 func WithValidator[T any](validator func(ctx context.Context, data *T, loaded time.Time) (fresh bool)) Option[T] {
 	return func(o *opt[T]) {
+		o.addValidator(validator)
+	}
+}
+
+// WithAllValidators is an Option to add validators that must all return true
+// for the cache to be considered fresh.
+//
+// It works the same as calling WithValidator with every validator.
+// The validators are called in order,
+// and the ones after the first returned false are skipped.
+func WithAllValidators[T any](validators ...func(ctx context.Context, data *T, loaded time.Time) (fresh bool)) Option[T] {
+	return func(o *opt[T]) {
+		for _, v := range validators {
+			o.addValidator(v)
+		}
+	}
+}
+
+// WithAnyValidator is an Option to add validators that the cache is considered
+// fresh when any of them returns true.
+//
+// The validators are called in order,
+// and the ones after the first returned true are skipped.
+// When combined with WithValidator (or WithAllValidators),
+// they are treated as a single validator.
+func WithAnyValidator[T any](validators ...func(ctx context.Context, data *T, loaded time.Time) (fresh bool)) Option[T] {
+	return func(o *opt[T]) {
+		if len(validators) == 0 {
+			return
+		}
+		o.addValidator(func(ctx context.Context, data *T, loaded time.Time) bool {
+			for _, v := range validators {
+				if v(ctx, data, loaded) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+// addValidator adds validator to o,
+// combined with the existing validator with AND logic.
+func (o *opt[T]) addValidator(validator func(context.Context, *T, time.Time) bool) {
+	prev := o.validator
+	if prev == nil {
 		o.validator = validator
+		return
+	}
+	o.validator = func(ctx context.Context, data *T, loaded time.Time) bool {
+		return prev(ctx, data, loaded) && validator(ctx, data, loaded)
 	}
 }
 
-// New creates a new Cache with loader and options.
-func New[T any](loader Loader[T], options ...Option[T]) *Cache[T] {
+// WithValidatorV2 is an Option to set a validator that could also provide the
+// new value.
+//
+// It works the same as WithValidator,
+// except that when the validator returns a non-nil replacement with true,
+// the replacement is stored (with current timestamp) and returned by Load
+// instead of calling the loader.
+// When it returns nil with true, the current cached value is still fresh.
+// When it returns false, the cache will be re-loaded by the loader,
+// and the returned replacement is ignored.
+//
+// It can be used together with WithValidator,
+// in which case it's only called after the validator returned true.
+// AtomicCache ignores it.
+func WithValidatorV2[T any](validator func(ctx context.Context, data *T, loaded time.Time) (replacement *T, fresh bool)) Option[T] {
+	return func(o *opt[T]) {
+		o.validatorV2 = validator
+	}
+}
+
+func newOpt[T any](loader Loader[T], options []Option[T]) *opt[T] {
 	o := &opt[T]{
 		loader: loader,
 	}
 	for _, option := range options {
 		option(o)
 	}
-	c := &Cache[T]{
-		opt: *o,
-		pool: sync.Pool{
-			New: func() any {
-				return new(cached[T])
-			},
+	for _, f := range o.keyedHooks {
+		o.hooks = append(o.hooks, f(o.name, o.key))
+	}
+	if len(o.weightedLoaders) > 0 {
+		o.weighted = newWeightedLoaders(o.weightedLoaders, o.adaptiveWeights)
+		o.loader = o.weighted.load
+	}
+	if o.errorWrapper != nil {
+		o.loader = errorWrapperLoader(o.loader, o.errorWrapper)
+	}
+	if o.mutexName != "" {
+		o.loader = mutualExclusionLoader(o.loader, o.mutexName, o.mutexTimeout)
+	}
+	if o.semaphore != nil {
+		o.loader = semaphoreLoader(o.loader, o.semaphore)
+	}
+	return o
+}
+
+// WithCoalescing is an Option to set whether concurrent reloads are coalesced
+// into a single loader call.
+//
+// Default is true.
+// Set it to false will cause every Load that found the cache stale to call the
+// loader by itself, and the last one finished wins.
+func WithCoalescing[T any](coalesce bool) Option[T] {
+	return func(o *opt[T]) {
+		o.noCoalescing = !coalesce
+	}
+}
+
+// WithEagerInvalidation is an Option to invalidate the stale value as soon as
+// a reload starts.
+//
+// Default is false,
+// means the stale value is still returned by Load when the reload failed.
+// Set it to true will cause Load to return nil data instead when the reload
+// failed, which trades availability for strict freshness
+// (never returns data older than the ttl plus the load latency).
+func WithEagerInvalidation[T any](eager bool) Option[T] {
+	return func(o *opt[T]) {
+		o.eagerInvalidation = eager
+	}
+}
+
+// NewGlobalPool creates a new pool to be used with WithGlobalPool.
+func NewGlobalPool[T any]() *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			return new(cached[T])
 		},
 	}
-	c.cached.Store(c.poolGet())
-	return c
 }
 
-func (c *Cache[T]) poolGet() *cached[T] {
-	return c.pool.Get().(*cached[T])
+// WithGlobalPool is an Option to share the internal pool used for reloads
+// across multiple Cache instances of the same type.
+//
+// Default is nil, means every Cache has its own pool.
+// When many short-lived Cache instances are created (for example,
+// per-request caches), sharing a pool created by NewGlobalPool allows the
+// internal entries to be reused across them, reducing GC pressure.
+//
+// pool must be created by NewGlobalPool with the same T.
+// When it's set, PoolSize only counts the entries put into the pool by this
+// Cache.
+func WithGlobalPool[T any](pool *sync.Pool) Option[T] {
+	return func(o *opt[T]) {
+		o.pool = pool
+	}
 }
 
-// Load loads the cached value.
+// WithOnPoolGet is an Option to set a hook called every time an entry is got
+// from the internal pool (or the pool set by WithGlobalPool).
 //
-// If the cached value is stale (or never loaded before),
-// the set loader will be called to load it from external source.
+// Default is nil.
+// It's a low level instrumentation option,
+// for example a counting hook can be used in benchmarks to verify that pool
+// reuse is happening.
+func WithOnPoolGet[T any](hook func()) Option[T] {
+	return func(o *opt[T]) {
+		o.onPoolGet = hook
+	}
+}
+
+// WithOnPoolPut is an Option to set a hook called every time an entry is put
+// back to the internal pool (or the pool set by WithGlobalPool).
 //
-// If the cached last loader call failed,
-// it immediately calls loader again with the same ctx and return the new result
-// instead.
-// If the cached value is stale but the new loader call failed,
-// it returns the cached stale data with error form the new loader call.
+// Default is nil.
+// See WithOnPoolGet for more details.
+func WithOnPoolPut[T any](hook func()) Option[T] {
+	return func(o *opt[T]) {
+		o.onPoolPut = hook
+	}
+}
+
+// WithPartialUpdate is an Option to incrementally update the cached value.
 //
-// In worst case scenario a single Load could call loader twice:
-// once another goroutine is causing it to reload (or it's loaded for the first
-// time), and the first loader failed so it immediately calls loader again.
+// Default is nil, means the loaded data replaces the cached value.
+// When set, after every successful loader call,
+// merge is called with the current cached value and the loaded data,
+// and the returned value is cached instead.
+// This enables incremental update patterns where the loader only returns the
+// delta.
 //
-// A single Cache instance would never have 2 loader calls at the same time.
-func (c *Cache[T]) Load(ctx context.Context) (*T, error) {
-	curr := c.cached.Load()
-	data, loaded, err := curr.load(ctx, c.opt.loader)
-	if err == nil {
-		fresh := c.opt.ttl <= 0 || loaded.Add(c.opt.ttl).After(time.Now())
-		if fresh && c.opt.validator != nil {
-			fresh = c.opt.validator(ctx, data, loaded)
-		}
-		if fresh {
-			return data, nil
+// When there's no current cached value (for example, the first load),
+// old is nil and merge should usually just return fresh.
+func WithPartialUpdate[T any](merge func(old, fresh *T) *T) Option[T] {
+	return func(o *opt[T]) {
+		o.merge = merge
+	}
+}
+
+// WithWriteBack is an Option to make the cache write-through.
+//
+// Default is nil, means Update only updates the cache in memory.
+// When set, Update calls writer with the new value before updating the cache,
+// and when writer returns an error,
+// Update returns the error without updating the cache.
+//
+// writer is only called by Update,
+// not by other ways of updating the cache (loader calls, UpdateFunc,
+// TryUpdate, etc.).
+func WithWriteBack[T any](writer func(ctx context.Context, data *T) error) Option[T] {
+	return func(o *opt[T]) {
+		o.writer = writer
+	}
+}
+
+// WithCopyFunc is an Option to return copies of the cached data to the
+// callers, so they can modify it without affecting the cached value.
+//
+// Default is nil, means Load returns the cached pointer directly,
+// so callers must not modify it.
+// When set, Load and its variants return the result of calling copyFn with
+// the cached data instead.
+// Peek, PeekStale, and the data passed into hooks and validators are still
+// the cached data, not copied.
+//
+// ShallowCopy can be used as copyFn when T has no pointers, slices, or maps.
+//
+// It's mutually exclusive with WithCOW.
+func WithCopyFunc[T any](copyFn func(*T) *T) Option[T] {
+	return func(o *opt[T]) {
+		o.copyFn = copyFn
+	}
+}
+
+// ShallowCopy returns a shallow copy of v, to be used with WithCopyFunc.
+func ShallowCopy[T any](v *T) *T {
+	cp := *v
+	return &cp
+}
+
+// WithSizeLimit is an Option to prevent oversized data from being cached for
+// long.
+//
+// Default is nil, means there's no size limit.
+// When set, sizeFn is called with the data after every successful loader call,
+// and when the size is larger than maxBytes,
+// the data is still returned and cached but already expired,
+// so the next Load calls the loader again.
+// OnOversized of the Hooks (see WithHooks) are also called.
+//
+// It only works with WithTTL.
+func WithSizeLimit[T any](sizeFn func(*T) int64, maxBytes int64) Option[T] {
+	return func(o *opt[T]) {
+		o.sizeFn = sizeFn
+		o.maxBytes = maxBytes
+	}
+}
+
+// WithEqualFunc is an Option to skip updates with unchanged values.
+//
+// Default is nil, means every Update replaces the cached value and notifies
+// the subscribers.
+// When set, Update and LoadOrUpdate compare the new value with the cached
+// value using equal,
+// and when they are equal the cached value is kept as-is:
+// the version is not increased, the subscribers are not notified,
+// and the time it's loaded is not updated.
+//
+// Reloads from the loader are compared with the last successfully loaded
+// value as well,
+// and when they are equal the last value (the same pointer) is kept with its
+// version, and the subscribers are not notified,
+// but the time it's loaded is still updated so it's fresh again.
+func WithEqualFunc[T any](equal func(a, b *T) bool) Option[T] {
+	return func(o *opt[T]) {
+		o.equal = equal
+	}
+}
+
+// unchanged returns true if d is successfully loaded with data equal to data,
+// according to WithEqualFunc.
+func (c *Cache[T]) unchanged(d *cached[T], data *T) bool {
+	return c.opt.equal != nil && d != nil && d.done.Load() && d.err == nil && c.opt.equal(d.data, data)
+}
+
+// WithTransform is an Option to transform the data returned by the loader
+// before it's cached.
+//
+// Default is nil, means the data returned by the loader is cached as-is.
+// When set, transform is called once after every successful loader call,
+// and the returned data is cached instead.
+// If transform returns an error, it's treated the same as the loader returned
+// that error.
+func WithTransform[T any](transform func(ctx context.Context, data *T) (*T, error)) Option[T] {
+	return func(o *opt[T]) {
+		o.transform = transform
+	}
+}
+
+func transformLoader[T any](loader Loader[T], transform func(context.Context, *T) (*T, error)) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		data, err := loader(ctx)
+		if err != nil {
+			return data, err
 		}
+		return transform(ctx, data)
+	}
+}
+
+// WithInitialValue is an Option to seed the cache with data,
+// loaded at the time the Cache is created.
+//
+// Default is nil, means the first Load calls the loader.
+// When set, Load returns data without calling the loader until it's stale.
+func WithInitialValue[T any](data *T) Option[T] {
+	return func(o *opt[T]) {
+		o.initialValue = data
 	}
-	// try to re-load new data
-	newCached := c.poolGet()
-	if !c.cached.CompareAndSwap(curr, newCached) {
-		// not swapped, put back to the pool
-		c.pool.Put(newCached)
+}
+
+// WithBackgroundRefresh is an Option to refresh the cache in background
+// before it expires (stale-while-revalidate).
+//
+// Default is 0, means the cache is only reloaded after it's stale,
+// and the Load call found it stale blocks until the reload finishes.
+// Set it to a positive value will cause the first Load found the cached value
+// within ahead of expiring to start a reload in a background goroutine,
+// with context.Background() (see WithContextFunc),
+// while all Load calls keep getting the current cached value.
+// Once the background reload succeeds the new value replaces the current one.
+// If the background reload fails,
+// the current value is kept until it's stale.
+//
+// It only works with WithTTL.
+// The validator (if set) will still cause a blocking reload when it returns
+// false.
+func WithBackgroundRefresh[T any](ahead time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.refreshAhead = ahead
 	}
-	newData, _, err := c.cached.Load().load(ctx, c.opt.loader)
-	if err != nil {
-		return data, err
+}
+
+// WithProbabilisticExpiry is an Option to refresh the cache in background
+// before it expires, with a probability increasing when it gets closer to the
+// expiry (XFetch),
+// to prevent multiple caches with the same ttl from reloading at the same
+// time.
+//
+// Default is 0, means disabled.
+// When set to positive beta,
+// every Load returning a fresh cached value starts a background reload (the
+// same as WithBackgroundRefresh) with the probability of
+// exp(-(expiry - now) / (beta * duration)),
+// where duration is how long the loader call took to load the cached value.
+// Larger beta causes earlier reloads, 1 is a good default.
+func WithProbabilisticExpiry[T any](beta float64) Option[T] {
+	return func(o *opt[T]) {
+		o.xfetchBeta = beta
 	}
-	return newData, nil
 }
 
-// Update updates the cache with data and current timestamp.
-func (c *Cache[T]) Update(data *T) {
-	entry := new(cached[T])
-	entry.update(data)
-	c.cached.Store(entry)
+// WithContextFunc is an Option to set the ctx passed into the loader when
+// it's called in a background goroutine,
+// for example by WithBackgroundRefresh and WithAsyncLoad.
+//
+// Default is nil, means context.Background() is used.
+// When set, f is called with the ctx of the Load call triggered the background
+// loader call.
+// It can be used to propagate values from the ctx
+// (trace ids, for example) without the cancellation,
+// by returning context.WithoutCancel(ctx) or a new ctx with the values
+// cherry-picked.
+//
+// Loader calls blocking Load always use the ctx of Load directly.
+func WithContextFunc[T any](f func(context.Context) context.Context) Option[T] {
+	return func(o *opt[T]) {
+		o.contextFunc = f
+	}
+}
+
+// backgroundContext returns the ctx to be used by background loader calls
+// triggered by a Load call with ctx.
+func (o *opt[T]) backgroundContext(ctx context.Context) context.Context {
+	if o.contextFunc == nil {
+		return context.Background()
+	}
+	return o.contextFunc(ctx)
+}
+
+// validate checks for invalid and conflicting options.
+func (o *opt[T]) validate() error {
+	var errs []error
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"WithTTL", o.ttl},
+		{"WithErrorTTL", o.errorTTL},
+		{"WithNegativeCaching", o.negTTL},
+		{"WithSlidingTTL", o.slidingTTL},
+		{"WithIdleTTL", o.idleTTL},
+		{"WithBucketTTL", o.bucketTTL},
+		{"WithGracePeriod", o.grace},
+		{"WithLoadTimeout", o.loadTimeout},
+		{"WithCircuitBreaker cooldown", o.circuitCooldown},
+	} {
+		if d.value < 0 {
+			errs = append(errs, fmt.Errorf("stalecache: negative %s: %v", d.name, d.value))
+		}
+	}
+	if o.ttl > 0 && o.slidingTTL > 0 {
+		errs = append(errs, errors.New("stalecache: WithTTL and WithSlidingTTL are mutually exclusive"))
+	}
+	if o.bucketTTL > 0 && o.slidingTTL > 0 {
+		errs = append(errs, errors.New("stalecache: WithBucketTTL and WithSlidingTTL are mutually exclusive"))
+	}
+	if o.cow && o.copyFn != nil {
+		errs = append(errs, errors.New("stalecache: WithCOW and WithCopyFunc are mutually exclusive"))
+	}
+	if o.xfetchBeta < 0 {
+		errs = append(errs, fmt.Errorf("stalecache: negative WithProbabilisticExpiry beta: %v", o.xfetchBeta))
+	}
+	if o.circuitThreshold < 0 {
+		errs = append(errs, fmt.Errorf("stalecache: negative WithCircuitBreaker threshold: %d", o.circuitThreshold))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateOptions checks options for invalid values and conflicts,
+// the same way NewE does, without creating a Cache.
+func ValidateOptions[T any](options ...Option[T]) error {
+	return newOpt[T](nil, options).validate()
+}
+
+// New creates a new Cache with loader and options.
+//
+// The internal pool and entries are set up lazily on first use,
+// so creating many rarely used caches is cheap.
+//
+// It panics if the options are invalid or conflict with each other,
+// use NewE to get the error instead.
+func New[T any](loader Loader[T], options ...Option[T]) *Cache[T] {
+	c, err := NewE(loader, options...)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// NewE is New but returns an error instead of panicking,
+// if the options are invalid (for example negative ttl) or conflict with each
+// other (for example both WithTTL and WithSlidingTTL).
+func NewE[T any](loader Loader[T], options ...Option[T]) (*Cache[T], error) {
+	o := newOpt(loader, options)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	if o.jitter > 0 && o.ttl > 0 {
+		o.ttl = jitterTTL(o.ttl, o.jitter)
+	}
+	c := &Cache[T]{
+		opt:  *o,
+		pool: o.pool,
+	}
+	if c.opt.updateMeta != nil {
+		c.meta.Store(&c.opt.metaInit)
+	}
+	if c.opt.concurrencyMetrics {
+		c.concurrency = new(concurrencyCounters)
+	}
+	c.opt.loader = chainMiddlewares(c.opt.loader, c.opt.middlewares)
+	if c.opt.recoverPanics {
+		c.opt.loader = recoverLoader(c.opt.loader)
+	}
+	if c.opt.loadTimeout > 0 {
+		c.opt.loader = TimeoutMiddleware[T](c.opt.loadTimeout)(c.opt.loader)
+	}
+	if c.opt.rateLimit {
+		c.limiter = rate.NewLimiter(rate.Limit(c.opt.rps), c.opt.burst)
+		c.opt.loader = rateLimitLoader(c.limiter, c.opt.loader)
+	}
+	c.opt.loader = statsLoader(&c.stats, c.opt.loader)
+	if c.opt.transform != nil {
+		c.opt.loader = transformLoader(c.opt.loader, c.opt.transform)
+	}
+	if len(c.opt.hooks) > 0 {
+		c.opt.loader = hooksLoader(&c.opt, c.opt.loader)
+	}
+	if c.opt.retryAttempts > 1 {
+		c.opt.loader = RetryMiddleware[T](c.opt.retryAttempts, c.opt.retryDelay)(c.opt.loader)
+	}
+	if c.opt.smartTTL != nil {
+		c.smart = new(smartTTLState)
+		c.opt.loader = smartTTLLoader(c.smart, c.opt.loader)
+	}
+	if c.opt.circuitThreshold > 0 {
+		c.opt.loader = circuitBreakerLoader(&circuitBreaker{
+			threshold: int64(c.opt.circuitThreshold),
+			cooldown:  c.opt.circuitCooldown,
+			now:       c.opt.now,
+			onOpen:    c.opt.onCircuitOpen,
+		}, c.opt.loader)
+	}
+	if c.opt.traceFunc != nil {
+		c.opt.loader = traceLoader(c.opt.traceFunc, c.opt.loader)
+	}
+	c.init = sync.OnceFunc(func() {
+		if c.pool == nil {
+			c.pool = NewGlobalPool[T]()
+		}
+		d := c.poolGet()
+		if data := c.opt.initialValue; data != nil {
+			// Don't use fillWith here: the subscribers were not notified
+			// when New set the initial value eagerly,
+			// and they could call back into c while still inside init.
+			d.do(func() {
+				d.data = data
+				d.loaded = c.opt.now()
+				d.version = c.version.Add(1)
+				d.ttl = c.dynamicTTL(data)
+				c.everLoaded.Store(true)
+			})
+		}
+		c.cached.Store(d)
+	})
+	if c.opt.warmer != nil && c.opt.warmerInterval > 0 {
+		c.startBackground(c.warm)
+	}
+	if c.opt.pubsub != nil {
+		c.startBackground(c.subscribePubSub)
+	}
+	for _, ch := range c.opt.invalidations {
+		ch := ch
+		c.startBackground(func(ctx context.Context) {
+			c.listenInvalidations(ctx, ch)
+		})
+	}
+	return c, nil
+}
+
+// ErrNoLoader is the error returned by Load of a Cache created by CacheOf
+// without an initial value,
+// or by NewStreamed without WithFallbackLoader before the first update.
+var ErrNoLoader error = &CacheError{Code: CodeNoLoader}
+
+// CacheOf creates a new Cache without a loader, seeded with initial.
+//
+// It's for using the Cache as a concurrency-safe value store,
+// updated manually by Update (and its variants),
+// with subscriber notifications and version tracking.
+// The cached value never expires,
+// as the ttl and validator related Options are ignored.
+// If initial is nil, Load returns ErrNoLoader until the first Update.
+func CacheOf[T any](initial *T, options ...Option[T]) *Cache[T] {
+	options = append(options, func(o *opt[T]) {
+		o.ttl = 0
+		o.slidingTTL = 0
+		o.dynamicTTL = nil
+		o.smartTTL = nil
+		o.validator = nil
+		o.validatorV2 = nil
+		o.initialValue = initial
+	})
+	return New(func(context.Context) (*T, error) {
+		return nil, ErrNoLoader
+	}, options...)
+}
+
+// startBackground starts f in a background goroutine,
+// the ctx passed into f is canceled by Close.
+//
+// It must only be called from New.
+func (c *Cache[T]) startBackground(f func(ctx context.Context)) {
+	if c.bgCancel == nil {
+		c.bgCtx, c.bgCancel = context.WithCancel(context.Background())
+	}
+	c.bgWG.Add(1)
+	go func(ctx context.Context) {
+		defer c.bgWG.Done()
+		f(ctx)
+	}(c.bgCtx)
+}
+
+// Close stops all the background goroutines started by options
+// (for example, WithCacheWarming) and waits for them to return.
+//
+// It's safe to call Close multiple times.
+// It's not required to call Close if no such options are used.
+// Load and Update can still be used after Close.
+func (c *Cache[T]) Close() {
+	if c.bgCancel != nil {
+		c.bgCancel()
+	}
+	c.bgWG.Wait()
+}
+
+// current returns the current entry,
+// setting up the initial one first if it's not done yet.
+func (c *Cache[T]) current() *cached[T] {
+	c.init()
+	return c.cached.Load()
+}
+
+func (c *Cache[T]) poolGet() *cached[T] {
+	if c.concurrency != nil {
+		c.concurrency.poolGets.Add(1)
+	}
+	if c.opt.onPoolGet != nil {
+		c.opt.onPoolGet()
+	}
+	for {
+		n := c.pooled.Load()
+		if n <= 0 || c.pooled.CompareAndSwap(n, n-1) {
+			break
+		}
+	}
+	return c.pool.Get().(*cached[T])
+}
+
+func (c *Cache[T]) poolPut(d *cached[T]) {
+	d.mu.Lock()
+	started := d.started
+	d.mu.Unlock()
+	if started {
+		// only the entries never started to be filled can be reused.
+		return
+	}
+	d.prev.Store(nil)
+	d.replacing = nil
+	d.detached = false
+	d.held = false
+	if c.concurrency != nil {
+		c.concurrency.poolPuts.Add(1)
+	}
+	if c.opt.onPoolPut != nil {
+		c.opt.onPoolPut()
+	}
+	c.pool.Put(d)
+	c.pooled.Add(1)
+}
+
+// Prefetch pre-allocates internal entries used by future reloads.
+//
+// It's useful to call it before a known traffic burst to reduce allocations
+// during the burst.
+// It's a no-op if PoolSize is already n or more,
+// and it stops early if ctx is canceled.
+func (c *Cache[T]) Prefetch(ctx context.Context, n int) {
+	c.init()
+	for i := c.PoolSize(); i < n; i++ {
+		if ctx.Err() != nil {
+			return
+		}
+		c.poolPut(new(cached[T]))
+	}
+}
+
+// PoolSize returns the approximate number of pre-allocated internal entries.
+//
+// It's approximate because the underlying sync.Pool could drop items at any
+// time without notice.
+func (c *Cache[T]) PoolSize() int {
+	return int(c.pooled.Load())
+}
+
+// Load loads the cached value.
+//
+// If the cached value is stale (or never loaded before),
+// the set loader will be called to load it from external source.
+//
+// If the cached last loader call failed,
+// it immediately calls loader again and return the new result instead.
+// If the cached value is stale but the new loader call failed,
+// it returns the cached stale data with error form the new loader call.
+//
+// In worst case scenario a single Load could call loader twice:
+// once another goroutine is causing it to reload (or it's loaded for the first
+// time), and the first loader failed so it immediately calls loader again.
+//
+// A single Cache instance would never have 2 loader calls at the same time.
+//
+// The loader is called in a background goroutine,
+// with a ctx carrying the values from the ctx of the Load call triggered it.
+// All the Load calls waiting for the same loader call return early with
+// ctx.Err() (and the stale data, if any) when their own ctx is canceled,
+// and the ctx passed into the loader is only canceled after all of them gave
+// up.
+// A Load call comes after that (before the canceled loader call returns)
+// gets the loader called again if the canceled call failed.
+// A panic from the loader is re-raised in all the Load calls waiting for it
+// (see WithRecoverPanics).
+func (c *Cache[T]) Load(ctx context.Context) (*T, error) {
+	data, _, err := c.load(ctx, nil, freshness{})
+	return data, err
+}
+
+// MustLoad is the same as Load but panics when Load returns an error.
+//
+// The panic value is the error from Load if it's already a *CacheError,
+// otherwise it's a *CacheError with CodeLoadFailed wrapping the error.
+//
+// It's meant for tests and initializations only.
+func (c *Cache[T]) MustLoad(ctx context.Context) *T {
+	return newResult(c.Load(ctx)).Must()
+}
+
+// WarmUp makes sure the cache is loaded and fresh,
+// it's usually called before serving traffic.
+//
+// It's the same as Load except that it only returns the error.
+// It's a no-op if the cache is already loaded and fresh.
+// If the loader failed, the error is returned so the caller can abort startup,
+// even if there's stale data available.
+func (c *Cache[T]) WarmUp(ctx context.Context) error {
+	_, _, err := c.load(ctx, nil, freshness{})
+	return err
+}
+
+// LoadWithVersion is the same as Load,
+// but also returns the version of the returned data.
+//
+// The version is increased by every successful loader call and update,
+// starting from 1,
+// so the same version means the same data.
+// It's 0 when there's no data returned.
+func (c *Cache[T]) LoadWithVersion(ctx context.Context) (*T, uint64, error) {
+	data, entry, err := c.load(ctx, nil, freshness{})
+	if entry == nil {
+		return data, 0, err
+	}
+	return data, entry.version, err
+}
+
+// LoadIfOlderThan returns the cached value and its version if the version is
+// at least version, regardless of the ttl and validator.
+//
+// Otherwise it reloads the cache the same as ForceReload (joining the
+// in-flight reload, if any),
+// and returns the reloaded value with its new version.
+// It's the pull side complement of UpdateIfVersion,
+// for example when the version of the latest data is known from a stream of
+// notifications.
+// The returned data is copied by WithCopyFunc, if set.
+func (c *Cache[T]) LoadIfOlderThan(ctx context.Context, version uint64) (*T, uint64, error) {
+	// Read the data and the version from the same entry,
+	// so they always match.
+	entry := c.current()
+	if !entry.done.Load() || entry.err != nil || entry.version < version {
+		var err error
+		if _, entry, err = c.forceReload(ctx); err != nil {
+			return nil, 0, c.named(err)
+		}
+	}
+	data := entry.data
+	if c.opt.copyFn != nil {
+		data = c.opt.copyFn(data)
+	}
+	return data, entry.version, nil
+}
+
+// LoadOrUpdate is the same as Load,
+// except that when the cached value needs to be reloaded and newVal is not
+// nil,
+// newVal is stored with current timestamp instead of calling the loader.
+//
+// If there's already a loader call in-flight,
+// it waits for that loader call instead.
+// On a read-only Cache (see NewReplica), newVal is ignored.
+func (c *Cache[T]) LoadOrUpdate(ctx context.Context, newVal *T) (*T, error) {
+	if c.opt.readOnly {
+		newVal = nil
+	}
+	data, _, err := c.load(ctx, newVal, freshness{})
+	return data, err
+}
+
+// LoadWithDeadline is the same as Load,
+// except that the cached value is also considered stale for this call when
+// it was loaded maxStale ago or earlier, even if it's still fresh by the ttl.
+//
+// It's for callers with stronger consistency requirements sharing the same
+// cache with more lenient callers.
+// The stricter requirement only applies to this call,
+// other Load calls are unaffected (except that they could get the newer value
+// from the reload triggered by it).
+func (c *Cache[T]) LoadWithDeadline(ctx context.Context, maxStale time.Duration) (*T, error) {
+	data, _, err := c.load(ctx, nil, freshness{maxAge: maxStale})
+	return data, err
+}
+
+// freshness overrides how load decides whether the cached entry is fresh,
+// the zero value means no overrides.
+type freshness struct {
+	// When positive, the entry loaded maxAge ago or earlier is also considered
+	// stale.
+	maxAge time.Duration
+	// When positive, it's used instead of the ttl of the cache.
+	ttl time.Duration
+}
+
+// fresh returns true if the loaded entry d is fresh according to f and the
+// ttl, without checking the validator.
+func (c *Cache[T]) fresh(d *cached[T], f freshness) bool {
+	now := c.opt.now()
+	if f.ttl > 0 {
+		if !d.loaded.Add(f.ttl).After(now) || c.idle(d) {
+			return false
+		}
+	} else if c.expired(d) {
+		return false
+	}
+	return f.maxAge <= 0 || d.loaded.Add(f.maxAge).After(now)
+}
+
+// loadWithTTL is Load with ttl used instead of the ttl of the cache for this
+// call's freshness decision only, it's used by Map.LoadWithTTL.
+func (c *Cache[T]) loadWithTTL(ctx context.Context, ttl time.Duration) (*T, error) {
+	data, _, err := c.load(ctx, nil, freshness{ttl: ttl})
+	return data, err
+}
+
+// load implements Load, LoadOrUpdate, LoadWithDeadline, etc.
+//
+// When update is non-nil, it's used to fill the entry instead of the loader.
+//
+// It also returns the entry the returned data is from,
+// which is nil when there's no data returned.
+// The returned data is copied by WithCopyFunc, if set.
+func (c *Cache[T]) load(ctx context.Context, update *T, f freshness) (*T, *cached[T], error) {
+	data, entry, err := c.loadShared(ctx, update, f)
+	if data != nil && c.opt.copyFn != nil {
+		data = c.opt.copyFn(data)
+	}
+	err = c.named(err)
+	return data, entry, err
+}
+
+// loadShared implements load without WithCopyFunc.
+func (c *Cache[T]) loadShared(ctx context.Context, update *T, f freshness) (*T, *cached[T], error) {
+	c.init()
+	if IsBypassed(ctx) {
+		return c.forceReload(ctx)
+	}
+	if c.opt.asyncLoad && !c.everLoaded.Load() {
+		if err := c.loadAsync(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	curr := c.current()
+	wasDone := curr.done.Load()
+	if update != nil && !wasDone {
+		c.fillWith(curr, update)
+	}
+	if update == nil && !wasDone && c.skipped(ctx) {
+		// Only the never-loaded entry or an entry being loaded can get here,
+		// either way don't start or wait for the loader.
+		return skipResult(curr.prev.Load(), nil)
+	}
+	if !c.wait(ctx, curr) {
+		// ctx is canceled before curr is loaded
+		return c.fallback(ctx, curr.prev.Load(), canceledError(ctx))
+	}
+	data, loaded, err := curr.data, curr.loaded, curr.err
+	// stale is the entry to fallback to when the reload failed,
+	// which is the last successfully loaded entry.
+	stale := curr.lastGood()
+	if curr.invalidated.Load() {
+		stale = nil
+	} else if err == nil {
+		// curr is just loaded for this call when it's not done before,
+		// don't reload it again even if it's already expired
+		// (e.g. by WithSizeLimit).
+		fresh := !wasDone || c.fresh(curr, f)
+		var replacement *T
+		if fresh && c.hasValidator() {
+			replacement, fresh = c.validate(ctx, data, loaded)
+		}
+		if fresh && replacement == nil {
+			curr.hits.Add(1)
+			if c.opt.slidingTTL > 0 || c.opt.idleTTL > 0 {
+				curr.accessed.Store(c.opt.now().UnixNano())
+			}
+			if wasDone {
+				c.stats.hits.Add(1)
+				c.opt.onHit(data, loaded)
+			}
+			if c.opt.refreshAhead > 0 {
+				c.refreshAhead(ctx, curr)
+			}
+			if c.opt.xfetchBeta > 0 && c.expiresEarly(curr) {
+				c.refreshInBackground(c.opt.backgroundContext(ctx), curr)
+			}
+			return data, curr, nil
+		}
+		if fresh {
+			// the validator provided the new value, store it instead of calling
+			// the loader
+			update = replacement
+		} else {
+			c.stats.misses.Add(1)
+			c.opt.onMiss()
+			if c.opt.onStale != nil && curr.staleNotified.CompareAndSwap(false, true) {
+				go c.opt.onStale(c.opt.backgroundContext(ctx), data, loaded)
+			}
+		}
+	} else if c.negativeFresh(curr) {
+		// the resource is known to be missing, don't retry or fallback yet
+		return nil, nil, err
+	} else if c.opt.errorTTL > 0 && curr.loaded.Add(c.opt.errorTTL).After(c.opt.now()) {
+		// the last load failed recently, don't retry yet
+		return c.fallback(ctx, stale, err)
+	}
+	if update != nil && c.unchanged(stale, update) {
+		return stale.data, stale, nil
+	}
+	if update == nil && c.skipped(ctx) {
+		return skipResult(stale, err)
+	}
+	if update == nil && c.rateLimited(stale) {
+		c.refreshInBackground(c.opt.backgroundContext(ctx), curr)
+		return stale.data, stale, nil
+	}
+	// try to re-load new data, join the background refresh if there's one
+	newCached := curr.next.Load()
+	if newCached != nil && newCached.held {
+		// being loaded by SyncGroup.RefreshAll, which must not be stored
+		// here, but wait for it so the loader calls never overlap.
+		c.await(ctx, newCached)
+		newCached = nil
+	} else if newCached != nil && newCached.done.Load() && newCached.err != nil {
+		// the background refresh failed, don't return its error without
+		// calling the loader again.
+		curr.next.CompareAndSwap(newCached, nil)
+		newCached = nil
+	}
+	fromPool := newCached == nil
+	if fromPool {
+		newCached = c.poolGet()
+		newCached.prev.Store(curr.lastGood())
+	}
+	if c.opt.noCoalescing {
+		newCached.detached = true
+		if update != nil {
+			c.fillWith(newCached, update)
+		}
+		c.loadEntry(ctx, newCached)
+		c.cached.Store(newCached)
+	} else if c.cached.CompareAndSwap(curr, newCached) {
+		// only fill it with update after it's stored,
+		// so a discarded entry is never filled.
+		if update != nil {
+			c.fillWith(newCached, update)
+		}
+		if c.opt.eagerInvalidation {
+			curr.invalidated.Store(true)
+			stale = nil
+		}
+	} else {
+		if fromPool {
+			// not swapped, put back to the pool
+			c.poolPut(newCached)
+		}
+		if c.concurrency != nil {
+			c.concurrency.casFailures.Add(1)
+		}
+		newCached = c.current()
+	}
+	newData, _, err := c.loadEntry(ctx, newCached)
+	if err != nil {
+		if c.negative(err) {
+			return nil, nil, err
+		}
+		return c.fallback(ctx, stale, err)
+	}
+	return newData, newCached, nil
+}
+
+// fallback returns the data from stale entry along with err,
+// if it's servable.
+// Otherwise it returns the result from the loader set by WithOnErrorFallback,
+// if any.
+func (c *Cache[T]) fallback(ctx context.Context, stale *cached[T], err error) (*T, *cached[T], error) {
+	data, entry, err := c.staleFallback(stale, err)
+	if data == nil && c.opt.errorFallback != nil && ctx.Err() == nil {
+		data, fallbackErr := c.opt.errorFallback(ctx)
+		if fallbackErr != nil {
+			return nil, nil, errors.Join(err, fallbackErr)
+		}
+		return data, nil, nil
+	}
+	return data, entry, err
+}
+
+// staleFallback returns the data from stale entry along with err,
+// if it's servable.
+//
+// When the stale data is too old according to WithMaxStale,
+// err is wrapped in a CacheError with CodeMaxStaleExceeded.
+func (c *Cache[T]) staleFallback(stale *cached[T], err error) (*T, *cached[T], error) {
+	if stale == nil || stale.data == nil {
+		return nil, nil, err
+	}
+	if c.opt.maxStale > 0 && !stale.loaded.Add(c.opt.maxStale).After(c.opt.now()) {
+		return nil, nil, &CacheError{Code: CodeMaxStaleExceeded, Err: err}
+	}
+	if c.opt.grace > 0 {
+		if failedAt := stale.failedAt.Load(); failedAt != 0 && !time.Unix(0, failedAt).Add(c.opt.grace).After(c.opt.now()) {
+			return nil, nil, err
+		}
+	}
+	return stale.data, stale, err
+}
+
+// refreshAhead starts a background refresh if curr is close to expire.
+func (c *Cache[T]) refreshAhead(ctx context.Context, curr *cached[T]) {
+	if c.ttl(curr) <= 0 || c.expiry(curr).Add(-c.opt.refreshAhead).After(c.opt.now()) {
+		return
+	}
+	c.refreshInBackground(c.opt.backgroundContext(ctx), curr)
+}
+
+// expiresEarly returns true if the fresh entry curr should be refreshed
+// early according to WithProbabilisticExpiry.
+func (c *Cache[T]) expiresEarly(curr *cached[T]) bool {
+	if c.ttl(curr) <= 0 || curr.loadDuration <= 0 {
+		return false
+	}
+	remaining := c.expiry(curr).Sub(c.opt.now())
+	p := math.Exp(-float64(remaining) / (c.opt.xfetchBeta * float64(curr.loadDuration)))
+	return rand.Float64() < p
+}
+
+// refreshInBackground starts a background goroutine to load a new entry to
+// replace curr, unless there's already one.
+//
+// Load calls keep getting curr until the new entry is loaded successfully.
+func (c *Cache[T]) refreshInBackground(ctx context.Context, curr *cached[T]) {
+	if curr.next.Load() != nil || !c.refreshAllowed(curr) {
+		return
+	}
+	next := c.poolGet()
+	next.prev.Store(curr.lastGood())
+	next.replacing = curr
+	if !curr.next.CompareAndSwap(nil, next) {
+		c.poolPut(next)
+		return
+	}
+	go func() {
+		// when loaded successfully, next replaces curr before it's marked as
+		// filled (see store).
+		if c.await(ctx, next) && next.err != nil {
+			// let the next stale Load call the loader again instead of joining
+			// the failed entry.
+			curr.next.CompareAndSwap(next, nil)
+		}
+	}()
+}
+
+// ContextualLoad is the same as Load,
+// but also returns the remaining ttl of the returned data.
+//
+// remainingTTL is positive when the returned data is fresh,
+// negative when it's stale (for example, returned with an error from the
+// failed reload), and zero when there's no ttl or no data returned.
+//
+// It's useful for callers to make ttl aware decisions,
+// for example to set a downstream "Cache-Control: max-age" header.
+func (c *Cache[T]) ContextualLoad(ctx context.Context) (data *T, remainingTTL time.Duration, err error) {
+	data, entry, err := c.load(ctx, nil, freshness{})
+	if entry != nil {
+		if c.ttl(entry) > 0 {
+			remainingTTL = c.expiry(entry).Sub(c.opt.now())
+		}
+	}
+	return data, remainingTTL, err
+}
+
+// PeekRemainingTTL returns the remaining ttl of the data returned by Peek,
+// without triggering a reload.
+//
+// It has the same semantics as the remainingTTL returned by ContextualLoad.
+func (c *Cache[T]) PeekRemainingTTL() time.Duration {
+	curr := c.current()
+	if !curr.done.Load() || curr.err != nil || c.ttl(curr) <= 0 {
+		return 0
+	}
+	return c.expiry(curr).Sub(c.opt.now())
+}
+
+// validatingKey is the context key to track the Cache instances currently
+// running their validators in the call chain.
+type validatingKey struct{}
+
+type validating struct {
+	cache  any
+	parent *validating
+}
+
+// enterValidator returns the ctx to call the validators of cache with,
+// or false if the validator of cache is already running in the call chain of
+// ctx, in which case the cached value should be treated as stale.
+func enterValidator(ctx context.Context, cache any) (context.Context, bool) {
+	parent, _ := ctx.Value(validatingKey{}).(*validating)
+	for v := parent; v != nil; v = v.parent {
+		if v.cache == cache {
+			return ctx, false
+		}
+	}
+	return context.WithValue(ctx, validatingKey{}, &validating{
+		cache:  cache,
+		parent: parent,
+	}), true
+}
+
+// hasValidator returns whether either WithValidator or WithValidatorV2 is set.
+func (c *Cache[T]) hasValidator() bool {
+	return c.opt.validator != nil || c.opt.validatorV2 != nil
+}
+
+// validate calls the validators,
+// unless the validator of c is already running in the call chain of ctx.
+//
+// When it returns fresh with non-nil replacement,
+// the replacement should be stored instead of the current data.
+func (c *Cache[T]) validate(ctx context.Context, data *T, loaded time.Time) (replacement *T, fresh bool) {
+	ctx, ok := enterValidator(ctx, c)
+	if !ok {
+		return nil, false
+	}
+	if c.opt.traceFunc != nil {
+		var finish func(error)
+		ctx, finish = c.opt.traceFunc(ctx, TraceOpValidate)
+		defer finish(nil)
+	}
+	if c.opt.validator != nil && !c.opt.validator(ctx, data, loaded) {
+		return nil, false
+	}
+	if c.opt.validatorV2 == nil {
+		return nil, true
+	}
+	replacement, fresh = c.opt.validatorV2(ctx, data, loaded)
+	if !fresh {
+		return nil, false
+	}
+	return replacement, true
+}
+
+// expired returns true if the loaded entry d is stale according to the ttl.
+func (c *Cache[T]) expired(d *cached[T]) bool {
+	return c.ttl(d) > 0 && !c.expiry(d).After(c.opt.now()) || c.idle(d)
+}
+
+// idle returns true if d has not been returned by Load for longer than the
+// idle ttl.
+func (c *Cache[T]) idle(d *cached[T]) bool {
+	if c.opt.idleTTL <= 0 {
+		return false
+	}
+	last := d.loaded
+	if accessed := d.accessed.Load(); accessed != 0 {
+		last = time.Unix(0, accessed)
+	}
+	return !last.Add(c.opt.idleTTL).After(c.opt.now())
+}
+
+// expiry returns the time the loaded entry d becomes stale if there's a ttl.
+func (c *Cache[T]) expiry(d *cached[T]) time.Time {
+	if !d.expiresAt.IsZero() {
+		return d.expiresAt
+	}
+	if c.opt.bucketTTL > 0 {
+		return d.loaded.Truncate(c.opt.bucketTTL).Add(c.opt.bucketTTL)
+	}
+	if c.opt.slidingTTL > 0 && c.opt.dynamicTTL == nil {
+		if accessed := d.accessed.Load(); accessed != 0 {
+			return time.Unix(0, accessed).Add(c.opt.slidingTTL)
+		}
+	}
+	return d.loaded.Add(c.ttl(d))
+}
+
+// ttl returns the effective ttl of entry d.
+func (c *Cache[T]) ttl(d *cached[T]) time.Duration {
+	if !d.expiresAt.IsZero() {
+		if ttl := d.expiresAt.Sub(d.loaded); ttl > 0 {
+			return ttl
+		}
+		// already expired when loaded means stale immediately
+		return time.Nanosecond
+	}
+	if c.opt.bucketTTL > 0 {
+		// the actual expiry is calculated by expiry.
+		return c.opt.bucketTTL
+	}
+	if c.opt.dynamicTTL != nil {
+		return d.ttl
+	}
+	if c.smart != nil {
+		if ttl := c.smart.stats(*c.opt.smartTTL, d.hits.Load()).EffectiveTTL; ttl > 0 {
+			return ttl
+		}
+		// not positive smart ttl means stale immediately
+		return time.Nanosecond
+	}
+	if c.opt.slidingTTL > 0 {
+		return c.opt.slidingTTL
+	}
+	return c.opt.ttl
+}
+
+// loadEntry makes sure d is loaded and returns its data,
+// or returns ctx.Err() if ctx is canceled before that.
+func (c *Cache[T]) loadEntry(ctx context.Context, d *cached[T]) (*T, time.Time, error) {
+	if !c.wait(ctx, d) {
+		return nil, time.Time{}, canceledError(ctx)
+	}
+	return d.data, d.loaded, d.err
+}
+
+// canceledError returns the error when ctx is canceled while waiting for the
+// loader.
+func canceledError(ctx context.Context) error {
+	return &CacheError{Code: CodeCanceled, Err: ctx.Err()}
+}
+
+// wait is await,
+// but also re-raises the panic from filling d if it waited for d to be filled.
+func (c *Cache[T]) wait(ctx context.Context, d *cached[T]) bool {
+	if d.done.Load() {
+		return true
+	}
+	if !c.await(ctx, d) {
+		return false
+	}
+	if d.panicked {
+		panic(d.err.(*panicError).recovered)
+	}
+	return true
+}
+
+// await starts to fill d with the loader in a background goroutine if it's
+// not started yet, and waits for it to finish.
+//
+// It returns false if ctx is canceled before d is filled.
+// The ctx passed into the loader is only canceled after all the waiters gave
+// up,
+// and if a new waiter comes after that, the loader is called again with a new
+// ctx when the canceled call failed.
+//
+// It's meant for the background goroutines,
+// the panics from filling d are stored in d as a PanicError instead of being
+// re-raised, use wait to re-raise them.
+func (c *Cache[T]) await(ctx context.Context, d *cached[T]) bool {
+	if d.done.Load() {
+		return true
+	}
+	d.mu.Lock()
+	won := !d.started
+	if won {
+		d.started = true
+		d.finished = make(chan struct{})
+		d.base = context.WithoutCancel(ctx)
+		d.ctx, d.cancel = context.WithCancel(d.base)
+		go c.run(d)
+	} else if d.canceled {
+		d.canceled = false
+		d.ctx, d.cancel = context.WithCancel(d.base)
+	}
+	d.waiters++
+	finished := d.finished
+	d.mu.Unlock()
+
+	if c.concurrency != nil && !won {
+		start := time.Now()
+		defer func() {
+			c.concurrency.onceWaits.Add(1)
+			c.concurrency.onceWaitTime.Add(int64(time.Since(start)))
+		}()
+	}
+	select {
+	case <-finished:
+		return true
+	case <-ctx.Done():
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.waiters--
+		if d.waiters == 0 && !d.canceled {
+			d.canceled = true
+			d.cancel()
+		}
+		return false
+	}
+}
+
+// run fills d and commits it if it's stored,
+// it must only be called by the one started to fill d.
+func (c *Cache[T]) run(d *cached[T]) {
+	defer d.finish()
+	defer func() {
+		if r := recover(); r != nil {
+			d.data = nil
+			d.err = &panicError{recovered: r}
+			d.panicked = !c.opt.recoverPanics
+		}
+	}()
+	defer func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.cancel()
+	}()
+
+	d.mu.Lock()
+	ctx := d.ctx
+	d.mu.Unlock()
+	var changed bool
+	for {
+		changed = c.fill(ctx, d)
+		d.mu.Lock()
+		// all the waiters of the last call gave up,
+		// and new waiters came before it returned.
+		retry := d.err != nil && d.ctx != ctx
+		ctx = d.ctx
+		d.mu.Unlock()
+		if !retry {
+			break
+		}
+	}
+	if d.err != nil && d.replacing != nil {
+		c.refreshFailed(d.replacing, d.err)
+	}
+	if changed && c.store(d) {
+		c.commit(ctx, d)
+	}
+	if d.err == nil {
+		d.prev.Store(nil)
+		c.lastErr.Store(nil)
+	}
+}
+
+// fill calls the loader to fill d.
+//
+// It returns true if d is loaded successfully with changed data,
+// which should be committed after it's stored.
+// It must only be called by the one started to fill d.
+func (c *Cache[T]) fill(ctx context.Context, d *cached[T]) (changed bool) {
+	var old *T
+	if prev := d.prev.Load(); prev != nil {
+		old = prev.data
+	}
+	if c.opt.preRefresh != nil {
+		c.opt.preRefresh(ctx, old)
+	}
+	var expiresAt *time.Time
+	if c.opt.expiring {
+		expiresAt = new(time.Time)
+		ctx = context.WithValue(ctx, expiresAtKey{}, expiresAt)
+	}
+	start := c.opt.now()
+	d.data, d.err = c.opt.loader(ctx)
+	d.loaded = c.opt.now()
+	if expiresAt != nil && d.err == nil {
+		d.expiresAt = *expiresAt
+	}
+	d.loadDuration = d.loaded.Sub(start)
+	if d.err != nil && c.opt.grace > 0 {
+		if prev := d.prev.Load(); prev != nil {
+			prev.failedAt.CompareAndSwap(0, d.loaded.UnixNano())
+		}
+	}
+	if c.opt.postRefresh != nil {
+		defer func() {
+			c.opt.postRefresh(ctx, old, d.data, d.err)
+		}()
+	}
+	if d.err != nil {
+		return false
+	}
+	if c.opt.merge != nil {
+		d.data = c.opt.merge(old, d.data)
+	}
+	d.ttl = c.dynamicTTL(d.data)
+	if prev := d.prev.Load(); c.unchanged(prev, d.data) {
+		// keep the old value and version, only the time it's loaded (and
+		// the dynamic ttl) are updated.
+		d.data = prev.data
+		d.version = prev.version
+		return false
+	}
+	if c.opt.sizeFn != nil {
+		if size := c.opt.sizeFn(d.data); size > c.opt.maxBytes {
+			// expire it immediately
+			d.loaded = time.Time{}
+			c.opt.onOversized(d.data, size)
+		}
+	}
+	return true
+}
+
+// store returns true if the filled entry d is (now) stored in c,
+// replacing the entry it's loaded in background to replace,
+// or false if it's discarded (for example by Reset) before it's filled.
+func (c *Cache[T]) store(d *cached[T]) bool {
+	if d.detached {
+		// stored by the caller after it's filled
+		return true
+	}
+	if d.replacing != nil && d.err == nil && c.cached.CompareAndSwap(d.replacing, d) {
+		return true
+	}
+	return c.cached.Load() == d
+}
+
+// commit sets the version of the newly loaded entry d,
+// and makes the other changes (Rollback, subscribers, etc.) after it's stored.
+func (c *Cache[T]) commit(ctx context.Context, d *cached[T]) {
+	d.version = c.version.Add(1)
+	c.committed(ctx, d, d.prev.Load())
+}
+
+// committed makes the changes after the newly loaded entry d replaced
+// replaced.
+func (c *Cache[T]) committed(ctx context.Context, d, replaced *cached[T]) {
+	if replaced != nil {
+		c.keepPrevious(replaced)
+	}
+	if c.opt.updateMeta != nil {
+		c.updateMetadata(d.data, d.loaded)
+	}
+	c.everLoaded.Store(true)
+	c.subs.notify(d.data)
+	c.publish(ctx, d.data)
+}
+
+// fillWith fills d with data instead of calling the loader,
+// unless d is already being loaded.
+//
+// The version and the other changes are only made if d is stored.
+func (c *Cache[T]) fillWith(d *cached[T], data *T) {
+	d.do(func() {
+		d.data = data
+		d.loaded = c.opt.now()
+		d.ttl = c.dynamicTTL(data)
+		prev := d.prev.Load()
+		d.prev.Store(nil)
+		if !c.store(d) {
+			return
+		}
+		if prev != nil {
+			c.keepPrevious(prev)
+		}
+		d.version = c.version.Add(1)
+		c.everLoaded.Store(true)
+		c.subs.notify(data)
+	})
+}
+
+// PeekStale returns the cached value without triggering a reload.
+//
+// If nothing has been loaded successfully (or it's being loaded),
+// it returns nil, zero time, and false.
+// Otherwise it returns the cached value, the time it's loaded,
+// and whether it's stale according to the ttl.
+// The validator is not called by PeekStale.
+func (c *Cache[T]) PeekStale() (data *T, loadedAt time.Time, isStale bool) {
+	curr := c.current()
+	if !curr.done.Load() || curr.err != nil {
+		return nil, time.Time{}, false
+	}
+	return curr.data, curr.loaded, c.expired(curr)
+}
+
+// Peek returns what's currently in the cache without triggering a reload,
+// regardless of whether it's stale.
+//
+// If the current entry is not loaded yet (never loaded, or being loaded),
+// it returns nil, zero time, and nil error.
+// Otherwise it returns the data, the time it's loaded,
+// and the error returned by the loader.
+// Neither the ttl nor the validator is checked by Peek.
+func (c *Cache[T]) Peek() (*T, time.Time, error) {
+	curr := c.current()
+	if !curr.done.Load() {
+		return nil, time.Time{}, nil
+	}
+	return curr.data, curr.loaded, curr.err
+}
+
+// IsStale returns whether Load would reload the cache,
+// according to the ttl and the validator, without reloading it.
+//
+// It returns true if the cache has never been loaded (or it's being loaded),
+// or the last load failed.
+func (c *Cache[T]) IsStale(ctx context.Context) bool {
+	return c.isStale(ctx, c.current())
+}
+
+func (c *Cache[T]) isStale(ctx context.Context, curr *cached[T]) bool {
+	if !curr.done.Load() || curr.err != nil || curr.invalidated.Load() {
+		return true
+	}
+	if c.expired(curr) {
+		return true
+	}
+	if !c.hasValidator() {
+		return false
+	}
+	_, fresh := c.validate(ctx, curr.data, curr.loaded)
+	return !fresh
+}
+
+// PeekRefresh is Peek,
+// but also reloads the cache in background if it's stale according to IsStale.
+//
+// It never blocks on the loader.
+// The background reload uses a ctx with the values from ctx but not its
+// cancellation,
+// and concurrent PeekRefresh calls share the same background reload.
+func (c *Cache[T]) PeekRefresh(ctx context.Context) (*T, time.Time, error) {
+	curr := c.current()
+	bgCtx := context.WithoutCancel(ctx)
+	if !curr.done.Load() {
+		if curr.asyncStarted.CompareAndSwap(false, true) {
+			go c.await(bgCtx, curr)
+		}
+		return nil, time.Time{}, nil
+	}
+	if c.isStale(ctx, curr) {
+		c.refreshInBackground(bgCtx, curr)
+	}
+	return curr.data, curr.loaded, curr.err
+}
+
+// LoadedAt returns the time the current cached data was loaded,
+// or zero time if the cache has never been loaded successfully
+// (or it's being loaded).
+//
+// If the last load failed,
+// it returns the time of the last successful load before that.
+func (c *Cache[T]) LoadedAt() time.Time {
+	curr := c.current()
+	if !curr.done.Load() {
+		return time.Time{}
+	}
+	if curr = curr.lastGood(); curr == nil {
+		return time.Time{}
+	}
+	return curr.loaded
+}
+
+// PeekWithVersion is the same as Peek,
+// but also returns the version of the data (see LoadWithVersion).
+func (c *Cache[T]) PeekWithVersion() (*T, time.Time, uint64, error) {
+	curr := c.current()
+	if !curr.done.Load() {
+		return nil, time.Time{}, 0, nil
+	}
+	return curr.data, curr.loaded, curr.version, curr.err
+}
+
+// ForceReload reloads the cache from the loader,
+// regardless of the ttl and validator.
+//
+// If there's a load already in-flight, ForceReload joins it instead of
+// starting a new one,
+// so concurrent ForceReload calls only cause one loader call.
+//
+// Unlike Load, ForceReload does not fallback to the stale data when the loader
+// fails.
+func (c *Cache[T]) ForceReload(ctx context.Context) (*T, error) {
+	data, _, err := c.forceReload(ctx)
+	return data, c.named(err)
+}
+
+// forceReload implements ForceReload,
+// it also returns the entry the returned data is from.
+func (c *Cache[T]) forceReload(ctx context.Context) (*T, *cached[T], error) {
+	curr := c.current()
+	if curr.done.Load() {
+		newCached := c.poolGet()
+		newCached.prev.Store(curr.lastGood())
+		if c.cached.CompareAndSwap(curr, newCached) {
+			curr = newCached
+		} else {
+			// not swapped, put back to the pool
+			c.poolPut(newCached)
+			if c.concurrency != nil {
+				c.concurrency.casFailures.Add(1)
+			}
+			curr = c.current()
+		}
+	}
+	data, _, err := c.loadEntry(ctx, curr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, curr, nil
+}
+
+// Reset puts the cache back to the never-loaded state,
+// the same as it was right after New.
+//
+// The next Load call will call the loader and block until it returns.
+// If there's a load in-flight, its result is discarded once it returns,
+// without notifying the subscribers or changing the version.
+func (c *Cache[T]) Reset() {
+	c.everLoaded.Store(false)
+	c.invalidate()
+}
+
+// Drain blocks until the in-flight loader calls
+// (including the ones from WithBackgroundRefresh) of the current entry return.
+//
+// It returns immediately if there's no loader call in-flight.
+// If ctx is canceled before that, it returns ctx.Err().
+func (c *Cache[T]) Drain(ctx context.Context) error {
+	curr := c.current()
+	for _, d := range []*cached[T]{curr, curr.next.Load()} {
+		if d == nil {
+			continue
+		}
+		d.mu.Lock()
+		started, finished := d.started, d.finished
+		d.mu.Unlock()
+		if !started {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-finished:
+		}
+	}
+	return nil
+}
+
+// invalidate puts c back to the never-loaded state.
+func (c *Cache[T]) invalidate() {
+	c.init()
+	c.evict(c.cached.Swap(c.poolGet()).good())
+}
+
+// Update updates the cache with data and current timestamp.
+//
+// With WithWriteBack, the writer is called with ctx and data first,
+// and if it returns an error,
+// the cache is not updated and the error is returned.
+// With WithDebounce, the cache is updated after the debounce window.
+// On a read-only Cache (see NewReplica), it returns ErrReadOnly.
+func (c *Cache[T]) Update(ctx context.Context, data *T) error {
+	if c.opt.readOnly {
+		return c.named(ErrReadOnly)
+	}
+	if c.unchanged(c.current(), data) {
+		return nil
+	}
+	if c.opt.writer != nil {
+		if err := c.opt.writer(ctx, data); err != nil {
+			return err
+		}
+	}
+	if c.opt.debounce > 0 {
+		c.debounceUpdate(ctx, data)
+		return nil
+	}
+	if c.update(data) {
+		c.publish(ctx, data)
+	}
+	return nil
+}
+
+// restore sets data as if it's loaded at loadedAt,
+// unless the cache already has a successful value loaded at or after loadedAt.
+//
+// It returns whether data is restored.
+// It doesn't call the writer, publish, or notify the subscribers.
+func (c *Cache[T]) restore(data *T, loadedAt time.Time) bool {
+	for {
+		curr := c.current()
+		if curr.done.Load() && curr.err == nil && !curr.loaded.Before(loadedAt) {
+			return false
+		}
+		entry := new(cached[T])
+		entry.version = c.version.Add(1)
+		entry.ttl = c.dynamicTTL(data)
+		entry.set(data, loadedAt, nil)
+		if c.cached.CompareAndSwap(curr, entry) {
+			if curr.done.Load() && curr.err == nil {
+				c.keepPrevious(curr)
+			}
+			c.everLoaded.Store(true)
+			return true
+		}
+	}
+}
+
+// update implements Update without publishing to WithPubSub.
+//
+// It returns false if data is unchanged according to WithEqualFunc.
+func (c *Cache[T]) update(data *T) bool {
+	curr := c.current()
+	if c.unchanged(curr, data) {
+		return false
+	}
+	entry := new(cached[T])
+	entry.version = c.version.Add(1)
+	entry.ttl = c.dynamicTTL(data)
+	entry.set(data, c.opt.now(), nil)
+	c.cached.Store(entry)
+	c.keepPrevious(curr)
+	c.everLoaded.Store(true)
+	c.subs.notify(data)
+	return true
+}
+
+// UpdateFunc atomically updates the cache with the data returned by fn and
+// current timestamp.
+//
+// fn is called with the current cached data,
+// which is nil if the cache has never been loaded successfully (or it's being
+// loaded).
+// If fn returns nil, the cache is not updated.
+//
+// fn could be called multiple times when there are concurrent updates,
+// so it should not have side effects.
+// On a read-only Cache (see NewReplica), fn is not called.
+func (c *Cache[T]) UpdateFunc(fn func(*T) *T) {
+	if c.opt.readOnly {
+		return
+	}
+	for {
+		curr := c.current()
+		var data *T
+		if curr.done.Load() {
+			if good := curr.lastGood(); good != nil {
+				data = good.data
+			}
+		}
+		data = fn(data)
+		if data == nil {
+			return
+		}
+		entry := new(cached[T])
+		entry.version = c.version.Add(1)
+		entry.ttl = c.dynamicTTL(data)
+		entry.set(data, c.opt.now(), nil)
+		if c.cached.CompareAndSwap(curr, entry) {
+			c.keepPrevious(curr)
+			c.everLoaded.Store(true)
+			c.subs.notify(data)
+			c.publish(context.Background(), data)
+			return
+		}
+	}
+}
+
+// TryUpdate updates the cache with replacement and current timestamp,
+// only if the current cached data is still expected.
+//
+// The current cached data is the same as the one passed into fn by
+// UpdateFunc,
+// and it's compared with expected by pointer identity.
+// It returns false without modifying the cache if the cached data has been
+// changed since expected was read (by a loader call or another update),
+// or when the Cache is read-only (see NewReplica).
+func (c *Cache[T]) TryUpdate(expected, replacement *T) bool {
+	if c.opt.readOnly {
+		return false
+	}
+	curr := c.current()
+	var data *T
+	if curr.done.Load() {
+		if good := curr.lastGood(); good != nil {
+			data = good.data
+		}
+	}
+	if data != expected {
+		return false
+	}
+	entry := new(cached[T])
+	entry.version = c.version.Add(1)
+	entry.ttl = c.dynamicTTL(replacement)
+	entry.set(replacement, c.opt.now(), nil)
+	if !c.cached.CompareAndSwap(curr, entry) {
+		return false
+	}
+	c.keepPrevious(curr)
+	c.everLoaded.Store(true)
+	c.subs.notify(replacement)
+	c.publish(context.Background(), replacement)
+	return true
+}
+
+// UpdateIfVersion updates the cache with data and current timestamp,
+// only if the version of the current cached data is still version,
+// as returned by LoadWithVersion.
+//
+// It returns false with nil error if the version has been changed (by a
+// loader call or another update).
+// With WithWriteBack, the writer is called after the version check and before
+// updating the cache,
+// and its error is returned with false.
+// A concurrent update could still happen after the writer returned,
+// in which case it returns false with nil error as well.
+// On a read-only Cache (see NewReplica), it returns false with ErrReadOnly.
+func (c *Cache[T]) UpdateIfVersion(ctx context.Context, version uint64, data *T) (bool, error) {
+	if c.opt.readOnly {
+		return false, c.named(ErrReadOnly)
+	}
+	curr := c.current()
+	var currVersion uint64
+	if curr.done.Load() {
+		if good := curr.lastGood(); good != nil {
+			currVersion = good.version
+		}
+	}
+	if currVersion != version {
+		return false, nil
+	}
+	if c.opt.writer != nil {
+		if err := c.opt.writer(ctx, data); err != nil {
+			return false, err
+		}
+	}
+	entry := new(cached[T])
+	entry.version = c.version.Add(1)
+	entry.ttl = c.dynamicTTL(data)
+	entry.set(data, c.opt.now(), nil)
+	if !c.cached.CompareAndSwap(curr, entry) {
+		return false, nil
+	}
+	c.keepPrevious(curr)
+	c.everLoaded.Store(true)
+	c.subs.notify(data)
+	c.publish(ctx, data)
+	return true, nil
+}
+
+// ExtendTTL extends the ttl of the current cached value without calling the
+// loader, so it stays fresh for d from now.
+//
+// If d is not positive, the full ttl is used instead,
+// the same as the value is just loaded.
+// It's a no-op if the cache is being loaded,
+// or the last load failed, or there's no ttl,
+// or WithDynamicTTL or WithBucketTTL is used, or the Cache is created by
+// NewExpiring.
+// The version of the cached value does not change,
+// and the subscribers are not notified.
+func (c *Cache[T]) ExtendTTL(d time.Duration) {
+	if c.opt.dynamicTTL != nil || c.opt.bucketTTL > 0 || c.opt.expiring {
+		return
+	}
+	curr := c.current()
+	if !curr.done.Load() || curr.err != nil {
+		return
+	}
+	ttl := c.ttl(curr)
+	if ttl <= 0 {
+		return
+	}
+	loaded := c.opt.now()
+	if d > 0 {
+		loaded = loaded.Add(d - ttl)
+	}
+	entry := new(cached[T])
+	entry.version = curr.version
+	entry.set(curr.data, loaded, nil)
+	c.cached.CompareAndSwap(curr, entry)
+}
+
+// keepPrevious keeps the last successfully loaded entry of replaced for
+// Rollback.
+func (c *Cache[T]) keepPrevious(replaced *cached[T]) {
+	prev := replaced.good()
+	if prev != nil {
+		if old := c.previous.Swap(prev); old != prev {
+			c.evict(old)
+		}
+	}
+}
+
+// Rollback restores the previous successfully loaded (or updated) value,
+// replacing the current one.
+//
+// It returns false if there's no previous value.
+// Only one generation is kept,
+// so a second Rollback without new values in between returns false.
+//
+// The restored value is treated as just loaded by the ttl,
+// and the subscribers are not notified.
+// Use ForceReload to get a new value from the loader after Rollback.
+func (c *Cache[T]) Rollback() bool {
+	prev := c.previous.Swap(nil)
+	if prev == nil {
+		return false
+	}
+	// prev could be linked to other entries (prev, next, etc.),
+	// use a new entry instead.
+	entry := new(cached[T])
+	entry.version = prev.version
+	entry.ttl = prev.ttl
+	entry.set(prev.data, c.opt.now(), nil)
+	c.init()
+	c.evict(c.cached.Swap(entry).good())
+	return true
 }
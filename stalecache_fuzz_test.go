@@ -0,0 +1,103 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+// FuzzLoadUpdateRace runs random interleavings of Load, Update, Reset and
+// ForceReload from multiple goroutines and checks the invariants.
+//
+// It's best run with -race.
+func FuzzLoadUpdateRace(f *testing.F) {
+	const (
+		opLoad = iota
+		opUpdate
+		opReset
+		opForceReload
+		numOps
+	)
+
+	// From TestCacheLoadFailure: 5 concurrent loads with an always failing
+	// loader, twice.
+	f.Add([]byte{opLoad, opLoad}, uint8(5), uint16(100), uint8(1))
+	f.Add([]byte{opLoad, opUpdate, opLoad, opReset, opLoad}, uint8(3), uint16(10), uint8(0))
+	f.Add([]byte{opForceReload, opLoad, opUpdate, opForceReload}, uint8(4), uint16(0), uint8(2))
+	f.Add([]byte{opReset, opReset, opLoad, opUpdate, opUpdate}, uint8(8), uint16(5), uint8(3))
+
+	f.Fuzz(func(t *testing.T, ops []byte, goroutines uint8, sleepMicros uint16, errEvery uint8) {
+		if len(ops) > 64 {
+			ops = ops[:64]
+		}
+		n := int(goroutines%16) + 1
+		sleep := time.Duration(sleepMicros%1000) * time.Microsecond
+
+		// Every value stored into the cache, either by loader or Update,
+		// comes from counter, so they are strictly increasing.
+		var counter, loaderCalls, loaderErrors atomic.Int64
+		cache := stalecache.New(
+			func(context.Context) (*int64, error) {
+				calls := loaderCalls.Add(1)
+				time.Sleep(sleep)
+				if errEvery > 0 && calls%int64(errEvery) == 0 {
+					loaderErrors.Add(1)
+					return nil, errors.New("fuzz")
+				}
+				data := counter.Add(1)
+				return &data, nil
+			},
+			stalecache.WithTTL[int64](sleep),
+		)
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+
+				var lastVersion uint64
+				for j, op := range ops {
+					switch (int(op) + i) % numOps {
+					case opLoad:
+						cache.Load(context.Background())
+					case opUpdate:
+						data := counter.Add(1)
+						cache.Update(context.Background(), &data)
+					case opReset:
+						cache.Reset()
+					case opForceReload:
+						cache.ForceReload(context.Background())
+					}
+
+					data, _, version, err := cache.PeekWithVersion()
+					if err != nil || data == nil {
+						continue
+					}
+					if max := counter.Load(); *data > max {
+						t.Errorf("#%d op %d: Peek got %d, newer than the last value %d", i, j, *data, max)
+					}
+					if version < lastVersion {
+						t.Errorf("#%d op %d: Peek got version %d after %d", i, j, version, lastVersion)
+					}
+					lastVersion = version
+				}
+			}(i)
+		}
+		wg.Wait()
+		cache.Drain(context.Background())
+
+		stats := cache.Stats()
+		if got, want := stats.Loads, uint64(loaderCalls.Load()); got != want {
+			t.Errorf("Stats.Loads got %d, want %d", got, want)
+		}
+		if got, want := stats.LoadErrors, uint64(loaderErrors.Load()); got != want {
+			t.Errorf("Stats.LoadErrors got %d, want %d", got, want)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
 )
 
 func TestCacheLoad(t *testing.T) {
@@ -24,7 +25,12 @@ func TestCacheLoad(t *testing.T) {
 		return &data, nil
 	}
 
-	cache := stalecache.New(loader, stalecache.WithTTL[int](ttl))
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		loader,
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
 	var wg sync.WaitGroup
 
 	for i := 0; i < 2; i++ {
@@ -51,7 +57,7 @@ func TestCacheLoad(t *testing.T) {
 				t.Errorf("%d Loads took more than %v: %v", n, max, elapsed)
 			}
 		})
-		time.Sleep(ttl)
+		clock.Advance(ttl)
 	}
 }
 
@@ -72,7 +78,12 @@ func TestCacheLoadFailure(t *testing.T) {
 		return nil, wantErr
 	}
 
-	cache := stalecache.New(loader, stalecache.WithTTL[int](ttl))
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		loader,
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
 	var wg sync.WaitGroup
 
 	for i := 0; i < 2; i++ {
@@ -99,7 +110,7 @@ func TestCacheLoadFailure(t *testing.T) {
 				t.Errorf("%d Loads took more than %v: %v", n, max, elapsed)
 			}
 		})
-		time.Sleep(ttl)
+		clock.Advance(ttl)
 	}
 }
 
@@ -128,6 +139,96 @@ func TestCacheValidator(t *testing.T) {
 	}
 }
 
+func TestCacheValidatorComposition(t *testing.T) {
+	yes := func(context.Context, *int, time.Time) bool { return true }
+	no := func(context.Context, *int, time.Time) bool { return false }
+	for _, c := range []struct {
+		label     string
+		options   []stalecache.Option[int]
+		wantFresh bool
+	}{
+		{"all-true", []stalecache.Option[int]{stalecache.WithAllValidators(yes, yes)}, true},
+		{"all-false", []stalecache.Option[int]{stalecache.WithAllValidators(yes, no)}, false},
+		{"any-true", []stalecache.Option[int]{stalecache.WithAnyValidator(no, yes)}, true},
+		{"any-false", []stalecache.Option[int]{stalecache.WithAnyValidator(no, no)}, false},
+		{"merged-true", []stalecache.Option[int]{
+			stalecache.WithValidator(yes),
+			stalecache.WithAnyValidator(no, yes),
+		}, true},
+		{"merged-false", []stalecache.Option[int]{
+			stalecache.WithValidator(no),
+			stalecache.WithAnyValidator(no, yes),
+		}, false},
+		{"multiple-false", []stalecache.Option[int]{
+			stalecache.WithValidator(yes),
+			stalecache.WithValidator(no),
+		}, false},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			cache := stalecache.New(func(context.Context) (*int, error) {
+				var data int
+				return &data, nil
+			}, c.options...)
+			cache.Load(context.Background())
+			if got := !cache.IsStale(context.Background()); got != c.wantFresh {
+				t.Errorf("Got fresh %v, want %v", got, c.wantFresh)
+			}
+		})
+	}
+}
+
+func TestCacheValidatorV2(t *testing.T) {
+	const (
+		loaded   = "loaded"
+		replaced = "replaced"
+	)
+	var loaderCalls atomic.Int64
+	var result atomic.Value // func() (*string, bool)
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			loaderCalls.Add(1)
+			s := loaded
+			return &s, nil
+		},
+		stalecache.WithValidatorV2(func(context.Context, *string, time.Time) (*string, bool) {
+			return result.Load().(func() (*string, bool))()
+		}),
+	)
+
+	check := func(t *testing.T, f func() (*string, bool), want string, wantCalls int64) {
+		t.Helper()
+		result.Store(f)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %q, want %q", *data, want)
+		}
+		if calls := loaderCalls.Load(); calls != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", calls, wantCalls)
+		}
+	}
+	fresh := func() (*string, bool) { return nil, true }
+
+	t.Run("load", func(t *testing.T) {
+		check(t, fresh, loaded, 1)
+	})
+	t.Run("replace", func(t *testing.T) {
+		check(t, func() (*string, bool) {
+			s := replaced
+			return &s, true
+		}, replaced, 1)
+		check(t, fresh, replaced, 1)
+	})
+	t.Run("stale", func(t *testing.T) {
+		check(t, func() (*string, bool) {
+			s := replaced
+			return &s, false
+		}, loaded, 2)
+	})
+}
+
 func TestCacheUpdate(t *testing.T) {
 	const (
 		loaded  = "loaded"
@@ -135,12 +236,14 @@ func TestCacheUpdate(t *testing.T) {
 
 		ttl = 10 * time.Millisecond
 	)
+	clock := stalecachetest.NewFakeClock(time.Now())
 	cache := stalecache.New(
 		func(context.Context) (*string, error) {
 			s := loaded
 			return &s, nil
 		},
 		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
 	)
 
 	checkLoaded := func(t *testing.T, want string) {
@@ -159,19 +262,2400 @@ func TestCacheUpdate(t *testing.T) {
 	})
 
 	t.Run("update", func(t *testing.T) {
-		time.Sleep(ttl / 2)
+		clock.Advance(ttl / 2)
 		s := updated
-		cache.Update(&s)
+		cache.Update(context.Background(), &s)
 		checkLoaded(t, updated)
 	})
 
 	t.Run("not-stale", func(t *testing.T) {
-		time.Sleep(ttl / 2)
+		clock.Advance(ttl / 2)
 		checkLoaded(t, updated)
 	})
 
 	t.Run("stale", func(t *testing.T) {
-		time.Sleep(ttl / 2)
+		clock.Advance(ttl / 2)
 		checkLoaded(t, loaded)
 	})
 }
+
+func TestCacheUpdateFunc(t *testing.T) {
+	const n = 100
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	})
+	cache.Load(context.Background())
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.UpdateFunc(func(curr *int) *int {
+				next := *curr + 1
+				return &next
+			})
+		}()
+	}
+	wg.Wait()
+	cache.UpdateFunc(func(*int) *int {
+		return nil
+	})
+
+	data, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != n {
+		t.Errorf("Load got %d, want %d", *data, n)
+	}
+}
+
+func TestCacheInitialValue(t *testing.T) {
+	const (
+		ttl     = 10 * time.Millisecond
+		initial = "initial"
+		loaded  = "loaded"
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	data := initial
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			loaderCalls.Add(1)
+			s := loaded
+			return &s, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+		stalecache.WithInitialValue(&data),
+	)
+
+	check := func(t *testing.T, want string, wantCalls int64) {
+		t.Helper()
+		got, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *got != want {
+			t.Errorf("Load got %q, want %q", *got, want)
+		}
+		if calls := loaderCalls.Load(); calls != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", calls, wantCalls)
+		}
+	}
+
+	t.Run("fresh", func(t *testing.T) {
+		check(t, initial, 0)
+	})
+	t.Run("stale", func(t *testing.T) {
+		clock.Advance(ttl)
+		check(t, loaded, 1)
+	})
+}
+
+func TestCacheVersion(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	check := func(t *testing.T, want uint64) {
+		t.Helper()
+		_, got, err := cache.LoadWithVersion(context.Background())
+		if err != nil {
+			t.Fatalf("LoadWithVersion got error: %v", err)
+		}
+		if got != want {
+			t.Errorf("LoadWithVersion got version %d, want %d", got, want)
+		}
+		if _, _, got, _ := cache.PeekWithVersion(); got != want {
+			t.Errorf("PeekWithVersion got version %d, want %d", got, want)
+		}
+	}
+
+	if _, _, got, _ := cache.PeekWithVersion(); got != 0 {
+		t.Errorf("PeekWithVersion before Load got version %d, want 0", got)
+	}
+	t.Run("load", func(t *testing.T) {
+		check(t, 1)
+	})
+	t.Run("fresh", func(t *testing.T) {
+		check(t, 1)
+	})
+	t.Run("reload", func(t *testing.T) {
+		clock.Advance(ttl)
+		check(t, 2)
+	})
+	t.Run("update", func(t *testing.T) {
+		var data int
+		cache.Update(context.Background(), &data)
+		check(t, 3)
+	})
+}
+
+func TestCachePrefetch(t *testing.T) {
+	const n = 5
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	})
+	if size := cache.PoolSize(); size != 0 {
+		t.Errorf("PoolSize before Prefetch got %d, want 0", size)
+	}
+	cache.Prefetch(context.Background(), n)
+	if size := cache.PoolSize(); size != n {
+		t.Errorf("PoolSize after Prefetch got %d, want %d", size, n)
+	}
+	cache.Prefetch(context.Background(), n-1)
+	if size := cache.PoolSize(); size != n {
+		t.Errorf("PoolSize after second Prefetch got %d, want %d", size, n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cache.Prefetch(ctx, 2*n)
+	if size := cache.PoolSize(); size != n {
+		t.Errorf("PoolSize after canceled Prefetch got %d, want %d", size, n)
+	}
+}
+
+func TestCacheNoCoalescing(t *testing.T) {
+	const (
+		sleep = 5 * time.Millisecond
+		n     = 5
+	)
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			time.Sleep(sleep)
+			return nil, errors.New("foo")
+		},
+		stalecache.WithCoalescing[int](false),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Load(context.Background())
+		}()
+	}
+	wg.Wait()
+	if calls := loaderCalls.Load(); calls <= n {
+		t.Errorf("Got %d loader calls, want > %d", calls, n)
+	}
+}
+
+func TestCacheValidatorReentry(t *testing.T) {
+	loader := func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	}
+	var a, b *stalecache.Cache[int]
+	a = stalecache.New(
+		loader,
+		stalecache.WithValidator(func(ctx context.Context, _ *int, _ time.Time) bool {
+			_, err := b.Load(ctx)
+			return err == nil
+		}),
+	)
+	b = stalecache.New(
+		loader,
+		stalecache.WithValidator(func(ctx context.Context, _ *int, _ time.Time) bool {
+			_, err := a.Load(ctx)
+			return err == nil
+		}),
+	)
+
+	// Without the re-entry detection these would recurse forever.
+	for i := 0; i < 2; i++ {
+		if _, err := a.Load(context.Background()); err != nil {
+			t.Errorf("a.Load #%d got error: %v", i, err)
+		}
+		if _, err := b.Load(context.Background()); err != nil {
+			t.Errorf("b.Load #%d got error: %v", i, err)
+		}
+	}
+}
+
+func TestCacheEagerInvalidation(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	wantErr := errors.New("foo")
+	for _, c := range []struct {
+		eager     bool
+		wantStale bool
+	}{
+		{false, true},
+		{true, false},
+	} {
+		t.Run(fmt.Sprintf("%v", c.eager), func(t *testing.T) {
+			var fail atomic.Bool
+			clock := stalecachetest.NewFakeClock(time.Now())
+			cache := stalecache.New(
+				func(context.Context) (*int, error) {
+					if fail.Load() {
+						return nil, wantErr
+					}
+					var data int
+					return &data, nil
+				},
+				stalecache.WithTTL[int](ttl),
+				stalecache.WithClock[int](clock),
+				stalecache.WithEagerInvalidation[int](c.eager),
+			)
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Fatalf("Load got error: %v", err)
+			}
+			clock.Advance(ttl)
+			fail.Store(true)
+			data, err := cache.Load(context.Background())
+			if !errors.Is(err, wantErr) {
+				t.Errorf("Load got error %v, want %v", err, wantErr)
+			}
+			if gotStale := data != nil; gotStale != c.wantStale {
+				t.Errorf("Load got stale data %v, want %v", gotStale, c.wantStale)
+			}
+		})
+	}
+}
+
+func TestCachePeekStale(t *testing.T) {
+	const (
+		n   = 5
+		ttl = 10 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			data := n
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	check := func(t *testing.T, wantData bool, wantStale bool) {
+		t.Helper()
+		data, loaded, stale := cache.PeekStale()
+		if gotData := data != nil; gotData != wantData {
+			t.Errorf("PeekStale got data %v, want %v", gotData, wantData)
+		}
+		if loaded.IsZero() == wantData {
+			t.Errorf("PeekStale got loaded %v", loaded)
+		}
+		if stale != wantStale {
+			t.Errorf("PeekStale got stale %v, want %v", stale, wantStale)
+		}
+	}
+
+	t.Run("never-loaded", func(t *testing.T) {
+		check(t, false, false)
+	})
+	cache.Load(context.Background())
+	t.Run("fresh", func(t *testing.T) {
+		check(t, true, false)
+	})
+	clock.Advance(ttl)
+	t.Run("stale", func(t *testing.T) {
+		check(t, true, true)
+	})
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls, want 1", calls)
+	}
+}
+
+func TestCacheGlobalPool(t *testing.T) {
+	const n = 5
+	pool := stalecache.NewGlobalPool[int]()
+	loader := func(context.Context) (*int, error) {
+		data := n
+		return &data, nil
+	}
+
+	var gets, puts atomic.Int64
+	options := []stalecache.Option[int]{
+		stalecache.WithGlobalPool[int](pool),
+		stalecache.WithOnPoolGet[int](func() {
+			gets.Add(1)
+		}),
+		stalecache.WithOnPoolPut[int](func() {
+			puts.Add(1)
+		}),
+	}
+
+	warm := stalecache.New(loader, options...)
+	warm.Prefetch(context.Background(), n)
+
+	for i := 0; i < 2*n; i++ {
+		cache := stalecache.New(loader, options...)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load #%d got error: %v", i, err)
+		}
+		if *data != n {
+			t.Errorf("Load #%d got %d, want %d", i, *data, n)
+		}
+	}
+	if got, want := gets.Load(), int64(2*n+1); got != want {
+		t.Errorf("Got %d pool gets, want %d", got, want)
+	}
+	if got, want := puts.Load(), int64(n); got != want {
+		t.Errorf("Got %d pool puts, want %d", got, want)
+	}
+}
+
+func TestCacheLazyInit(t *testing.T) {
+	const n = 5
+	loader := func(context.Context) (*int, error) {
+		data := n
+		return &data, nil
+	}
+
+	var gets atomic.Int64
+	onPoolGet := stalecache.WithOnPoolGet[int](func() {
+		gets.Add(1)
+	})
+
+	t.Run("load", func(t *testing.T) {
+		gets.Store(0)
+		cache := stalecache.New(loader, onPoolGet)
+		if got := gets.Load(); got != 0 {
+			t.Errorf("Got %d pool gets from New, want 0", got)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Fatalf("Load #%d got error: %v", i, err)
+			}
+		}
+		if got := gets.Load(); got != 1 {
+			t.Errorf("Got %d pool gets after Load, want 1", got)
+		}
+	})
+
+	t.Run("initial-value", func(t *testing.T) {
+		initial := n + 1
+		cache := stalecache.New(loader, stalecache.WithInitialValue(&initial))
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != initial {
+			t.Errorf("Load got %d, want %d", *data, initial)
+		}
+	})
+
+	t.Run("race", func(t *testing.T) {
+		cache := stalecache.New(loader)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(2)
+			go func(i int) {
+				defer wg.Done()
+				data, err := cache.Load(context.Background())
+				if err != nil {
+					t.Errorf("Load #%d got error: %v", i, err)
+					return
+				}
+				if *data != n {
+					t.Errorf("Load #%d got %d, want %d", i, *data, n)
+				}
+			}(i)
+			go func() {
+				defer wg.Done()
+				cache.Reset()
+			}()
+		}
+		wg.Wait()
+	})
+}
+
+func TestCachePartialUpdate(t *testing.T) {
+	const ttl = time.Millisecond
+	var loaderCalls int
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*[]int, error) {
+			loaderCalls++
+			if loaderCalls == 2 {
+				return nil, errors.New("foo")
+			}
+			delta := []int{loaderCalls}
+			return &delta, nil
+		},
+		stalecache.WithTTL[[]int](ttl),
+		stalecache.WithClock[[]int](clock),
+		stalecache.WithPartialUpdate(func(old, fresh *[]int) *[]int {
+			if old == nil {
+				return fresh
+			}
+			merged := append(append([]int(nil), *old...), *fresh...)
+			return &merged
+		}),
+	)
+
+	var data *[]int
+	for i := 0; i < 3; i++ {
+		data, _ = cache.Load(context.Background())
+		clock.Advance(ttl)
+	}
+	// The second load failed so it's not merged.
+	if want := []int{1, 3}; fmt.Sprint(*data) != fmt.Sprint(want) {
+		t.Errorf("Load got %v, want %v", *data, want)
+	}
+}
+
+func TestCacheContextualLoad(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail.Load() {
+				return nil, errors.New("foo")
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	_, remaining, err := cache.ContextualLoad(context.Background())
+	if err != nil {
+		t.Fatalf("ContextualLoad got error: %v", err)
+	}
+	if remaining <= 0 || remaining > ttl {
+		t.Errorf("ContextualLoad got remaining ttl %v, want (0, %v]", remaining, ttl)
+	}
+
+	clock.Advance(ttl)
+	fail.Store(true)
+	data, remaining, err := cache.ContextualLoad(context.Background())
+	if err == nil {
+		t.Error("ContextualLoad got nil error")
+	}
+	if data == nil {
+		t.Error("ContextualLoad got nil stale data")
+	}
+	if remaining > 0 {
+		t.Errorf("ContextualLoad got remaining ttl %v for stale data, want negative", remaining)
+	}
+}
+
+func TestCacheBackgroundRefresh(t *testing.T) {
+	const (
+		ttl   = 50 * time.Millisecond
+		ahead = 40 * time.Millisecond
+		sleep = 20 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			time.Sleep(sleep)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		stalecache.WithBackgroundRefresh[int64](ahead),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	// Now in the refresh ahead window.
+	clock.Advance(ttl - ahead)
+	deadline := time.Now().Add(ttl)
+	for {
+		before := time.Now()
+		data, err := cache.Load(context.Background())
+		if elapsed := time.Since(before); elapsed >= sleep {
+			t.Errorf("Load blocked for %v during background refresh", elapsed)
+		}
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh not finished in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls, want 2", calls)
+	}
+}
+
+func TestCacheBackgroundRefreshFailed(t *testing.T) {
+	const (
+		ttl   = time.Minute
+		ahead = 10 * time.Second
+	)
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, errors.New("bg failed")
+			}
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+		stalecache.WithBackgroundRefresh[int64](ahead),
+	)
+	ctx := context.Background()
+	if _, err := cache.Load(ctx); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	// Now in the refresh ahead window, with the background refresh failing.
+	fail.Store(true)
+	clock.Advance(ttl - ahead)
+	if data, err := cache.Load(ctx); err != nil || *data != 1 {
+		t.Fatalf("Load got %v, %v, want 1, nil", data, err)
+	}
+	for deadline := time.Now().Add(time.Second); loaderCalls.Load() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh not started in time")
+		}
+	}
+	if err := cache.Drain(ctx); err != nil {
+		t.Fatalf("Drain got error: %v", err)
+	}
+
+	// Now stale, the failed background refresh must not be reused.
+	fail.Store(false)
+	clock.Advance(ahead)
+	data, err := cache.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != 3 {
+		t.Errorf("Load got %d, want 3", *data)
+	}
+}
+
+func TestCacheForceReload(t *testing.T) {
+	const (
+		n     = 5
+		sleep = 20 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			time.Sleep(sleep)
+			if fail.Load() {
+				return nil, errors.New("error")
+			}
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](time.Hour),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				data, err := cache.ForceReload(context.Background())
+				if err != nil {
+					t.Errorf("ForceReload #%d got error: %v", i, err)
+					return
+				}
+				if *data != 2 {
+					t.Errorf("ForceReload #%d got %d, want 2", i, *data)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if calls := loaderCalls.Load(); calls != 2 {
+			t.Errorf("Got %d loader calls, want 2", calls)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		fail.Store(true)
+		data, err := cache.ForceReload(context.Background())
+		if err == nil {
+			t.Error("ForceReload got no error")
+		}
+		if data != nil {
+			t.Errorf("ForceReload got stale data %d", *data)
+		}
+	})
+}
+
+func TestCachePeek(t *testing.T) {
+	const (
+		n   = 5
+		ttl = 10 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, errors.New("error")
+			}
+			data := n
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	t.Run("never-loaded", func(t *testing.T) {
+		data, loaded, err := cache.Peek()
+		if data != nil || !loaded.IsZero() || err != nil {
+			t.Errorf("Peek got %v, %v, %v, want nil, zero, nil", data, loaded, err)
+		}
+	})
+	cache.Load(context.Background())
+	t.Run("fresh", func(t *testing.T) {
+		data, loaded, err := cache.Peek()
+		if err != nil {
+			t.Errorf("Peek got error: %v", err)
+		}
+		if data == nil || *data != n {
+			t.Errorf("Peek got data %v, want %d", data, n)
+		}
+		if loaded.IsZero() {
+			t.Error("Peek got zero loaded time")
+		}
+	})
+	clock.Advance(ttl)
+	t.Run("stale", func(t *testing.T) {
+		data, _, err := cache.Peek()
+		if err != nil {
+			t.Errorf("Peek got error: %v", err)
+		}
+		if data == nil || *data != n {
+			t.Errorf("Peek got data %v, want %d", data, n)
+		}
+	})
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls, want 1", calls)
+	}
+	fail.Store(true)
+	cache.Load(context.Background())
+	t.Run("error", func(t *testing.T) {
+		data, _, err := cache.Peek()
+		if err == nil {
+			t.Error("Peek got no error")
+		}
+		if data != nil {
+			t.Errorf("Peek got data %d, want nil", *data)
+		}
+	})
+}
+
+func TestCacheErrorTTL(t *testing.T) {
+	const (
+		ttl      = 10 * time.Millisecond
+		errorTTL = 5 * time.Millisecond
+		n        = 5
+	)
+	wantErr := errors.New("foo")
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			if fail.Load() {
+				return nil, wantErr
+			}
+			data := n
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithErrorTTL[int](errorTTL),
+		stalecache.WithClock[int](clock),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	clock.Advance(ttl)
+	fail.Store(true)
+
+	for i := 0; i < n; i++ {
+		data, err := cache.Load(context.Background())
+		if !errors.Is(err, wantErr) {
+			t.Errorf("Load #%d got error %v, want %v", i, err, wantErr)
+		}
+		if data == nil || *data != n {
+			t.Errorf("Load #%d got data %v, want stale %d", i, data, n)
+		}
+	}
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls within error ttl, want 2", calls)
+	}
+
+	clock.Advance(errorTTL)
+	fail.Store(false)
+	data, err := cache.Load(context.Background())
+	if err != nil {
+		t.Errorf("Load after error ttl got error: %v", err)
+	}
+	if data == nil || *data != n {
+		t.Errorf("Load after error ttl got data %v, want %d", data, n)
+	}
+	if calls := loaderCalls.Load(); calls != 3 {
+		t.Errorf("Got %d loader calls after error ttl, want 3", calls)
+	}
+}
+
+func TestCacheReset(t *testing.T) {
+	const sleep = 10 * time.Millisecond
+	var loaderCalls atomic.Int64
+	started := make(chan struct{}, 1)
+	cache := stalecache.New(func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(sleep)
+		return &calls, nil
+	})
+
+	t.Run("loaded", func(t *testing.T) {
+		cache.Load(context.Background())
+		<-started
+		cache.Reset()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 2 {
+			t.Errorf("Load after Reset got %d, want 2", *data)
+		}
+		<-started
+	})
+
+	t.Run("in-flight", func(t *testing.T) {
+		cache.Reset()
+		go cache.Load(context.Background())
+		<-started
+		cache.Reset()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 4 {
+			t.Errorf("Load after Reset got %d, want 4", *data)
+		}
+	})
+}
+
+func TestCacheResetDiscardsInFlight(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		if calls == 1 {
+			close(started)
+			<-release
+		}
+		return &calls, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := cache.Subscribe(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Load(context.Background())
+	}()
+	<-started
+	cache.Reset()
+	close(release)
+	<-done
+
+	select {
+	case data := <-sub:
+		t.Errorf("Subscriber notified with %d from the discarded load", *data)
+	default:
+	}
+	data, version, err := cache.LoadWithVersion(context.Background())
+	if err != nil {
+		t.Fatalf("LoadWithVersion got error: %v", err)
+	}
+	if *data != 2 {
+		t.Errorf("LoadWithVersion got %d, want 2", *data)
+	}
+	if version != 1 {
+		t.Errorf("LoadWithVersion got version %d, want 1", version)
+	}
+}
+
+func TestCacheMaxStale(t *testing.T) {
+	const (
+		ttl      = 10 * time.Millisecond
+		maxStale = 30 * time.Millisecond
+	)
+	wantErr := errors.New("foo")
+	for _, c := range []struct {
+		label     string
+		age       time.Duration
+		wantStale bool
+	}{
+		{"stale", ttl, true},
+		{"too-stale", maxStale, false},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			var fail atomic.Bool
+			clock := stalecachetest.NewFakeClock(time.Now())
+			cache := stalecache.New(
+				func(context.Context) (*int, error) {
+					if fail.Load() {
+						return nil, wantErr
+					}
+					var data int
+					return &data, nil
+				},
+				stalecache.WithTTL[int](ttl),
+				stalecache.WithMaxStale[int](maxStale),
+				stalecache.WithClock[int](clock),
+			)
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Fatalf("Load got error: %v", err)
+			}
+			clock.Advance(c.age)
+			fail.Store(true)
+			data, err := cache.Load(context.Background())
+			if !errors.Is(err, wantErr) {
+				t.Errorf("Load got error %v, want %v", err, wantErr)
+			}
+			if gotStale := data != nil; gotStale != c.wantStale {
+				t.Errorf("Load got stale data %v, want %v", gotStale, c.wantStale)
+			}
+		})
+	}
+}
+
+func TestCacheSlidingTTL(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			var data int
+			return &data, nil
+		},
+		stalecache.WithSlidingTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	for i := 0; i < 5; i++ {
+		cache.Load(context.Background())
+		clock.Advance(ttl / 2)
+	}
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls while being accessed, want 1", calls)
+	}
+	clock.Advance(ttl)
+	cache.Load(context.Background())
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls after not accessed, want 2", calls)
+	}
+
+	t.Run("conflict", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("New with both WithTTL and WithSlidingTTL did not panic")
+			}
+		}()
+		stalecache.New(
+			func(context.Context) (*int, error) {
+				return nil, nil
+			},
+			stalecache.WithTTL[int](ttl),
+			stalecache.WithSlidingTTL[int](ttl),
+		)
+	})
+}
+
+func TestCacheLoadOrUpdate(t *testing.T) {
+	const (
+		ttl     = 10 * time.Millisecond
+		loaded  = "loaded"
+		updated = "updated"
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			loaderCalls.Add(1)
+			s := loaded
+			return &s, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+	)
+
+	check := func(t *testing.T, newVal *string, want string, wantCalls int64) {
+		t.Helper()
+		data, err := cache.LoadOrUpdate(context.Background(), newVal)
+		if err != nil {
+			t.Fatalf("LoadOrUpdate got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("LoadOrUpdate got %q, want %q", *data, want)
+		}
+		if calls := loaderCalls.Load(); calls != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", calls, wantCalls)
+		}
+	}
+	s := updated
+
+	t.Run("never-loaded", func(t *testing.T) {
+		check(t, &s, updated, 0)
+	})
+	t.Run("fresh", func(t *testing.T) {
+		other := "other"
+		check(t, &other, updated, 0)
+	})
+	t.Run("stale-nil", func(t *testing.T) {
+		clock.Advance(ttl)
+		check(t, nil, loaded, 1)
+	})
+	t.Run("stale", func(t *testing.T) {
+		clock.Advance(ttl)
+		check(t, &s, updated, 1)
+	})
+}
+
+func TestCacheLoadOrUpdateLostRace(t *testing.T) {
+	const ttl = time.Minute
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	var armed atomic.Bool
+	var cache *stalecache.Cache[string]
+	cache = stalecache.New(
+		func(context.Context) (*string, error) {
+			loaderCalls.Add(1)
+			data := "loaded"
+			return &data, nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithClock[string](clock),
+		stalecache.WithOnPoolGet[string](func() {
+			if armed.CompareAndSwap(true, false) {
+				// Make the LoadOrUpdate getting this entry lose the race.
+				other := "other"
+				cache.Update(context.Background(), &other)
+			}
+		}),
+	)
+	ctx := context.Background()
+	if _, err := cache.Load(ctx); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	clock.Advance(ttl)
+	armed.Store(true)
+	pushed := "pushed"
+	if data, err := cache.LoadOrUpdate(ctx, &pushed); err != nil || *data != "other" {
+		t.Fatalf("LoadOrUpdate got %v, %v, want other, nil", data, err)
+	}
+
+	cache.Reset()
+	data, err := cache.Load(ctx)
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != "loaded" {
+		t.Errorf("Load after Reset got %q, want %q", *data, "loaded")
+	}
+	if calls := loaderCalls.Load(); calls != 2 {
+		t.Errorf("Got %d loader calls, want 2", calls)
+	}
+}
+
+func TestCacheJitter(t *testing.T) {
+	const (
+		ttl = time.Second
+		n   = 100
+	)
+	for _, factor := range []float64{0.1, 0.5, 1, 2} {
+		t.Run(fmt.Sprintf("%v", factor), func(t *testing.T) {
+			lo := time.Duration(float64(ttl) * (1 - factor))
+			hi := time.Duration(float64(ttl) * (1 + factor))
+			if factor > 1 {
+				lo, hi = 0, 2*ttl
+			}
+			seen := make(map[time.Duration]bool)
+			for i := 0; i < n; i++ {
+				cache := stalecache.New(
+					func(context.Context) (*int, error) {
+						var data int
+						return &data, nil
+					},
+					stalecache.WithTTL[int](ttl),
+					stalecache.WithJitter[int](factor),
+					stalecache.WithClock[int](stalecachetest.NewFakeClock(time.Now())),
+				)
+				_, got, err := cache.ContextualLoad(context.Background())
+				if err != nil {
+					t.Fatalf("ContextualLoad got error: %v", err)
+				}
+				if got <= 0 || got < lo || got > hi {
+					t.Errorf("Got ttl %v, want positive and within [%v, %v]", got, lo, hi)
+				}
+				seen[got] = true
+			}
+			if len(seen) < 2 {
+				t.Errorf("Got the same ttl for all %d caches: %v", n, seen)
+			}
+		})
+	}
+}
+
+func TestCacheIsStale(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var loaderCalls atomic.Int64
+	var valid atomic.Bool
+	valid.Store(true)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithValidator(func(context.Context, *int, time.Time) bool {
+			return valid.Load()
+		}),
+	)
+
+	check := func(t *testing.T, want bool) {
+		t.Helper()
+		if got := cache.IsStale(context.Background()); got != want {
+			t.Errorf("IsStale got %v, want %v", got, want)
+		}
+	}
+
+	t.Run("never-loaded", func(t *testing.T) {
+		check(t, true)
+		if got := cache.LoadedAt(); !got.IsZero() {
+			t.Errorf("LoadedAt got %v, want zero", got)
+		}
+	})
+	now := clock.Now()
+	cache.Load(context.Background())
+	t.Run("fresh", func(t *testing.T) {
+		check(t, false)
+		if got := cache.LoadedAt(); !got.Equal(now) {
+			t.Errorf("LoadedAt got %v, want %v", got, now)
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		valid.Store(false)
+		defer valid.Store(true)
+		check(t, true)
+	})
+	t.Run("expired", func(t *testing.T) {
+		clock.Advance(ttl)
+		check(t, true)
+		if got := cache.LoadedAt(); !got.Equal(now) {
+			t.Errorf("LoadedAt got %v, want %v", got, now)
+		}
+	})
+	if calls := loaderCalls.Load(); calls != 1 {
+		t.Errorf("Got %d loader calls, want 1", calls)
+	}
+}
+
+func TestCacheWarmUp(t *testing.T) {
+	const (
+		sleep = 5 * time.Millisecond
+		n     = 5
+	)
+	wantErr := errors.New("foo")
+	var loaderCalls atomic.Int64
+	var fail atomic.Bool
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		loaderCalls.Add(1)
+		time.Sleep(sleep)
+		if fail.Load() {
+			return nil, wantErr
+		}
+		var data int
+		return &data, nil
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		fail.Store(true)
+		defer fail.Store(false)
+		if err := cache.WarmUp(context.Background()); !errors.Is(err, wantErr) {
+			t.Errorf("WarmUp got error %v, want %v", err, wantErr)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		before := loaderCalls.Load()
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				if err := cache.WarmUp(context.Background()); err != nil {
+					t.Errorf("WarmUp #%d got error: %v", i, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if calls := loaderCalls.Load() - before; calls != 1 {
+			t.Errorf("Got %d loader calls, want 1", calls)
+		}
+	})
+
+	t.Run("already-warm", func(t *testing.T) {
+		before := loaderCalls.Load()
+		if err := cache.WarmUp(context.Background()); err != nil {
+			t.Errorf("WarmUp got error: %v", err)
+		}
+		if calls := loaderCalls.Load() - before; calls != 0 {
+			t.Errorf("Got %d loader calls, want 0", calls)
+		}
+	})
+}
+
+func TestCacheTransform(t *testing.T) {
+	wantErr := errors.New("foo")
+	var fail atomic.Bool
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			s := "raw"
+			return &s, nil
+		},
+		stalecache.WithTransform(func(_ context.Context, data *string) (*string, error) {
+			if fail.Load() {
+				return nil, wantErr
+			}
+			s := "transformed " + *data
+			return &s, nil
+		}),
+	)
+
+	data, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if want := "transformed raw"; *data != want {
+		t.Errorf("Load got %q, want %q", *data, want)
+	}
+
+	fail.Store(true)
+	if _, err := cache.ForceReload(context.Background()); !errors.Is(err, wantErr) {
+		t.Errorf("ForceReload got error %v, want %v", err, wantErr)
+	}
+}
+
+func TestCacheDrain(t *testing.T) {
+	const timeout = 5 * time.Millisecond
+	release := make(chan struct{})
+	started := make(chan struct{})
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		close(started)
+		<-release
+		var data int
+		return &data, nil
+	})
+
+	t.Run("not-started", func(t *testing.T) {
+		if err := cache.Drain(context.Background()); err != nil {
+			t.Errorf("Drain got error: %v", err)
+		}
+	})
+
+	go cache.Load(context.Background())
+	<-started
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := cache.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Drain got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		close(release)
+		if err := cache.Drain(context.Background()); err != nil {
+			t.Errorf("Drain got error: %v", err)
+		}
+		if data, _, _ := cache.Peek(); data == nil {
+			t.Error("Peek after Drain got nil data")
+		}
+	})
+}
+
+func TestCacheLoadCancellation(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	loaderCtx := make(chan context.Context, 1)
+	cache := stalecache.New(func(ctx context.Context) (*int, error) {
+		loaderCtx <- ctx
+		close(started)
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var data int
+		return &data, nil
+	})
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Load(first)
+		firstErr <- err
+	}()
+	<-started
+	ctx := <-loaderCtx
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Load(second)
+		secondErr <- err
+	}()
+	// Give the second Load some time to start waiting.
+	time.Sleep(10 * time.Millisecond)
+
+	t.Run("one-canceled", func(t *testing.T) {
+		cancelFirst()
+		if err := <-firstErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("Load got error %v, want %v", err, context.Canceled)
+		}
+		if err := ctx.Err(); err != nil {
+			t.Errorf("Loader ctx canceled with waiters left: %v", err)
+		}
+	})
+
+	t.Run("all-canceled", func(t *testing.T) {
+		cancelSecond()
+		if err := <-secondErr; !errors.Is(err, context.Canceled) {
+			t.Errorf("Load got error %v, want %v", err, context.Canceled)
+		}
+		<-ctx.Done()
+	})
+}
+
+func TestCacheLoaderPanic(t *testing.T) {
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		if calls == 1 {
+			panic("foo")
+		}
+		return &calls, nil
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r != "foo" {
+				t.Errorf("Recovered %v, want foo", r)
+			}
+		}()
+		cache.Load(context.Background())
+		t.Error("Load did not panic")
+	})
+
+	t.Run("after", func(t *testing.T) {
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 2 {
+			t.Errorf("Load got %d, want 2", *data)
+		}
+	})
+}
+
+func TestCacheLoadAfterCanceled(t *testing.T) {
+	proceed := make(chan struct{})
+	started := make(chan struct{})
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(func(ctx context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		if calls == 1 {
+			close(started)
+			<-ctx.Done()
+			<-proceed
+			return nil, ctx.Err()
+		}
+		return &calls, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := cache.Load(ctx)
+		errCh <- err
+	}()
+	<-started
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Load got error %v, want %v", err, context.Canceled)
+	}
+
+	// Join the canceled loader call before it returns.
+	type result struct {
+		data *int64
+		err  error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		data, err := cache.Load(context.Background())
+		resultCh <- result{data, err}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(proceed)
+	r := <-resultCh
+	if r.err != nil {
+		t.Fatalf("Load got error: %v", r.err)
+	}
+	if *r.data != 2 {
+		t.Errorf("Load got %d, want 2", *r.data)
+	}
+}
+
+func TestCacheEqualFunc(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			data := 1
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithEqualFunc(func(a, b *int) bool {
+			return *a == *b
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := cache.Subscribe(ctx)
+	cache.Load(context.Background())
+	<-ch
+
+	check := func(t *testing.T, want uint64, notified bool) {
+		t.Helper()
+		if _, _, got, _ := cache.PeekWithVersion(); got != want {
+			t.Errorf("PeekWithVersion got version %d, want %d", got, want)
+		}
+		select {
+		case data := <-ch:
+			if !notified {
+				t.Errorf("Subscriber got unexpected %d", *data)
+			}
+		default:
+			if notified {
+				t.Error("Subscriber not notified")
+			}
+		}
+	}
+
+	t.Run("update-equal", func(t *testing.T) {
+		data := 1
+		cache.Update(context.Background(), &data)
+		check(t, 1, false)
+	})
+	t.Run("load-or-update-equal", func(t *testing.T) {
+		clock.Advance(ttl)
+		data := 1
+		cache.LoadOrUpdate(context.Background(), &data)
+		check(t, 1, false)
+	})
+	t.Run("reload-equal", func(t *testing.T) {
+		before, _, _ := cache.Peek()
+		clock.Advance(ttl)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if data != before {
+			t.Errorf("Load got %p, want the unchanged %p", data, before)
+		}
+		check(t, 1, false)
+		if cache.IsStale(context.Background()) {
+			t.Error("Cache is stale after reloading unchanged value")
+		}
+	})
+	t.Run("update-different", func(t *testing.T) {
+		data := 2
+		cache.Update(context.Background(), &data)
+		check(t, 2, true)
+	})
+}
+
+func TestCacheTryUpdate(t *testing.T) {
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		data := 1
+		return &data, nil
+	})
+	expected, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	replacement := 2
+	if !cache.TryUpdate(expected, &replacement) {
+		t.Fatal("TryUpdate with current data got false")
+	}
+	if data, _ := cache.Load(context.Background()); data != &replacement {
+		t.Errorf("Load got %d, want %d", *data, replacement)
+	}
+
+	other := 3
+	if cache.TryUpdate(expected, &other) {
+		t.Error("TryUpdate with outdated data got true")
+	}
+	if data, _ := cache.Load(context.Background()); data != &replacement {
+		t.Errorf("Load got %d, want %d", *data, replacement)
+	}
+}
+
+func TestCacheGracePeriod(t *testing.T) {
+	const (
+		ttl   = 10 * time.Millisecond
+		grace = 30 * time.Millisecond
+	)
+	wantErr := errors.New("foo")
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail.Load() {
+				return nil, wantErr
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithGracePeriod[int](grace),
+		stalecache.WithClock[int](clock),
+	)
+	if _, err := cache.Load(context.Background()); err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	// the grace period starts from the first failure,
+	// not from the time the stale data is loaded.
+	clock.Advance(grace)
+	fail.Store(true)
+
+	check := func(t *testing.T, wantStale bool) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if !errors.Is(err, wantErr) {
+			t.Errorf("Load got error %v, want %v", err, wantErr)
+		}
+		if gotStale := data != nil; gotStale != wantStale {
+			t.Errorf("Load got stale data %v, want %v", gotStale, wantStale)
+		}
+	}
+
+	t.Run("first-failure", func(t *testing.T) {
+		check(t, true)
+	})
+	t.Run("within-grace", func(t *testing.T) {
+		clock.Advance(grace - time.Millisecond)
+		check(t, true)
+	})
+	t.Run("after-grace", func(t *testing.T) {
+		clock.Advance(time.Millisecond)
+		check(t, false)
+	})
+	t.Run("recovered", func(t *testing.T) {
+		fail.Store(false)
+		cache.Load(context.Background())
+		clock.Advance(ttl)
+		fail.Store(true)
+		check(t, true)
+	})
+}
+
+func TestCacheContextFunc(t *testing.T) {
+	const (
+		ttl     = 50 * time.Millisecond
+		ahead   = 40 * time.Millisecond
+		timeout = time.Second
+	)
+	type ctxKey struct{}
+	values := make(chan any, 2)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(ctx context.Context) (*int, error) {
+			if ctx.Err() != nil {
+				t.Errorf("Loader called with canceled ctx: %v", ctx.Err())
+			}
+			values <- ctx.Value(ctxKey{})
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+		stalecache.WithBackgroundRefresh[int](ahead),
+		stalecache.WithContextFunc[int](context.WithoutCancel),
+	)
+	receive := func(t *testing.T, want any) {
+		t.Helper()
+		select {
+		case got := <-values:
+			if got != want {
+				t.Errorf("Loader got ctx value %v, want %v", got, want)
+			}
+		case <-time.After(timeout):
+			t.Fatalf("Loader not called in %v", timeout)
+		}
+	}
+
+	t.Run("foreground", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey{}, "foreground")
+		cache.Load(ctx)
+		receive(t, "foreground")
+	})
+	t.Run("background", func(t *testing.T) {
+		clock.Advance(ttl - ahead)
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "background"))
+		cache.Load(ctx)
+		cancel()
+		receive(t, "background")
+	})
+}
+
+func TestCacheLoadWithDeadline(t *testing.T) {
+	const (
+		ttl      = time.Hour
+		maxStale = time.Minute
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+	)
+	cache.Load(context.Background())
+
+	check := func(t *testing.T, data *int64, err error, want int64) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	t.Run("fresh", func(t *testing.T) {
+		data, err := cache.LoadWithDeadline(context.Background(), maxStale)
+		check(t, data, err, 1)
+	})
+	clock.Advance(maxStale)
+	t.Run("lenient", func(t *testing.T) {
+		data, err := cache.Load(context.Background())
+		check(t, data, err, 1)
+	})
+	t.Run("strict", func(t *testing.T) {
+		data, err := cache.LoadWithDeadline(context.Background(), maxStale)
+		check(t, data, err, 2)
+	})
+}
+
+func TestCacheMustLoad(t *testing.T) {
+	wantErr := errors.New("foo")
+
+	t.Run("ok", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*int, error) {
+			data := 1
+			return &data, nil
+		})
+		if data := cache.MustLoad(context.Background()); *data != 1 {
+			t.Errorf("MustLoad got %d, want 1", *data)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*int, error) {
+			return nil, wantErr
+		})
+		defer func() {
+			ce, ok := recover().(*stalecache.CacheError)
+			if !ok {
+				t.Fatalf("MustLoad did not panic with *CacheError")
+			}
+			if ce.Code != stalecache.CodeLoadFailed {
+				t.Errorf("Got code %v, want %v", ce.Code, stalecache.CodeLoadFailed)
+			}
+			if !errors.Is(ce, wantErr) {
+				t.Errorf("Got error %v, want %v", ce, wantErr)
+			}
+		}()
+		cache.MustLoad(context.Background())
+	})
+}
+
+func TestCacheSizeLimit(t *testing.T) {
+	const (
+		ttl      = time.Hour
+		maxBytes = 3
+	)
+	var loaderCalls atomic.Int64
+	var oversized []int64
+	var data atomic.Pointer[string]
+	cache := stalecache.New(
+		func(context.Context) (*string, error) {
+			loaderCalls.Add(1)
+			return data.Load(), nil
+		},
+		stalecache.WithTTL[string](ttl),
+		stalecache.WithSizeLimit(func(s *string) int64 {
+			return int64(len(*s))
+		}, maxBytes),
+		stalecache.WithHooks(stalecache.Hooks[string]{
+			OnOversized: func(_ *string, size int64) {
+				oversized = append(oversized, size)
+			},
+		}),
+	)
+
+	check := func(t *testing.T, want string, wantCalls int64) {
+		t.Helper()
+		got, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *got != want {
+			t.Errorf("Load got %q, want %q", *got, want)
+		}
+		if calls := loaderCalls.Load(); calls != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", calls, wantCalls)
+		}
+	}
+
+	large := "large"
+	data.Store(&large)
+	t.Run("oversized", func(t *testing.T) {
+		check(t, large, 1)
+		check(t, large, 2)
+		if len(oversized) != 2 || oversized[0] != int64(len(large)) {
+			t.Errorf("OnOversized got %v, want 2 calls of %d", oversized, len(large))
+		}
+	})
+
+	small := "foo"
+	data.Store(&small)
+	t.Run("within-limit", func(t *testing.T) {
+		check(t, small, 3)
+		check(t, small, 3)
+	})
+}
+
+func TestCacheCopyFunc(t *testing.T) {
+	type data struct {
+		Foo string
+	}
+	cache := stalecache.New(
+		func(context.Context) (*data, error) {
+			return &data{Foo: "foo"}, nil
+		},
+		stalecache.WithCopyFunc(stalecache.ShallowCopy[data]),
+	)
+	got, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	got.Foo = "bar"
+
+	got, err = cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if got.Foo != "foo" {
+		t.Errorf("Load got %q after modifying the returned value, want %q", got.Foo, "foo")
+	}
+	if peek, _, _ := cache.PeekStale(); peek == got {
+		t.Error("Load returned the same pointer as PeekStale")
+	}
+}
+
+func TestCacheWriteBack(t *testing.T) {
+	wantErr := errors.New("foo")
+	var written []int
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			var data int
+			return &data, nil
+		},
+		stalecache.WithWriteBack(func(_ context.Context, data *int) error {
+			if *data < 0 {
+				return wantErr
+			}
+			written = append(written, *data)
+			return nil
+		}),
+	)
+	cache.Load(context.Background())
+
+	t.Run("ok", func(t *testing.T) {
+		data := 1
+		if err := cache.Update(context.Background(), &data); err != nil {
+			t.Fatalf("Update got error: %v", err)
+		}
+		if got, _ := cache.Load(context.Background()); *got != data {
+			t.Errorf("Load got %d, want %d", *got, data)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		data := -1
+		if err := cache.Update(context.Background(), &data); !errors.Is(err, wantErr) {
+			t.Errorf("Update got error %v, want %v", err, wantErr)
+		}
+		if got, _ := cache.Load(context.Background()); *got != 1 {
+			t.Errorf("Load got %d after failed Update, want 1", *got)
+		}
+	})
+
+	if len(written) != 1 || written[0] != 1 {
+		t.Errorf("Writer got %v, want [1]", written)
+	}
+}
+
+func TestCacheOnErrorFallback(t *testing.T) {
+	loaderErr := errors.New("loader")
+	fallbackErr := errors.New("fallback")
+	var fallbackFail atomic.Bool
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls.Add(1)
+			return nil, loaderErr
+		},
+		stalecache.WithOnErrorFallback(func(context.Context) (*int, error) {
+			if fallbackFail.Load() {
+				return nil, fallbackErr
+			}
+			data := 1
+			return &data, nil
+		}),
+	)
+
+	t.Run("fallback", func(t *testing.T) {
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 1 {
+			t.Errorf("Load got %d, want 1", *data)
+		}
+		if data, _, _ := cache.PeekStale(); data != nil {
+			t.Errorf("Fallback result is cached: %d", *data)
+		}
+	})
+
+	t.Run("both-failed", func(t *testing.T) {
+		fallbackFail.Store(true)
+		calls := loaderCalls.Load()
+		_, err := cache.Load(context.Background())
+		for _, want := range []error{loaderErr, fallbackErr} {
+			if !errors.Is(err, want) {
+				t.Errorf("Load got error %v, want %v", err, want)
+			}
+		}
+		if got := loaderCalls.Load(); got <= calls {
+			t.Errorf("Loader not called again after fallback")
+		}
+	})
+}
+
+func TestCacheRollback(t *testing.T) {
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		return &calls, nil
+	})
+	if cache.Rollback() {
+		t.Error("Rollback before Load got true")
+	}
+	cache.Load(context.Background())
+	if cache.Rollback() {
+		t.Error("Rollback after the first Load got true")
+	}
+	cache.ForceReload(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := cache.Subscribe(ctx)
+	if !cache.Rollback() {
+		t.Fatal("Rollback got false")
+	}
+	data, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != 1 {
+		t.Errorf("Load after Rollback got %d, want 1", *data)
+	}
+	if _, _, version, _ := cache.PeekWithVersion(); version != 1 {
+		t.Errorf("Got version %d after Rollback, want 1", version)
+	}
+	select {
+	case data := <-ch:
+		t.Errorf("Subscriber got %d after Rollback", *data)
+	default:
+	}
+	if cache.Rollback() {
+		t.Error("Second Rollback got true")
+	}
+}
+
+func TestCacheErrorWrapper(t *testing.T) {
+	loaderErr := errors.New("foo")
+	wrapped := errors.New("wrapped")
+	wrapper := stalecache.WithErrorWrapper[int](func(err error) error {
+		return fmt.Errorf("%w: %w", wrapped, err)
+	})
+
+	t.Run("loader", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*int, error) {
+			return nil, loaderErr
+		}, wrapper)
+		_, err := cache.Load(context.Background())
+		for _, want := range []error{loaderErr, wrapped} {
+			if !errors.Is(err, want) {
+				t.Errorf("Load got error %v, want %v", err, want)
+			}
+		}
+	})
+
+	t.Run("internal", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*int, error) {
+			return nil, stalecache.ErrCircuitOpen
+		}, wrapper)
+		_, err := cache.Load(context.Background())
+		if errors.Is(err, wrapped) {
+			t.Errorf("Load got wrapped error %v for CacheError", err)
+		}
+	})
+}
+
+func TestCacheDynamicTTL(t *testing.T) {
+	type token struct {
+		ID  int64
+		TTL time.Duration
+	}
+	var loaderCalls atomic.Int64
+	var ttl atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*token, error) {
+			return &token{
+				ID:  loaderCalls.Add(1),
+				TTL: time.Duration(ttl.Load()),
+			}, nil
+		},
+		stalecache.WithTTL[token](time.Hour),
+		stalecache.WithClock[token](clock),
+		stalecache.WithDynamicTTL(func(t *token) time.Duration {
+			return t.TTL
+		}),
+	)
+	check := func(t *testing.T, want int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if data.ID != want {
+			t.Errorf("Load got %d, want %d", data.ID, want)
+		}
+	}
+
+	ttl.Store(int64(time.Minute))
+	t.Run("ttl", func(t *testing.T) {
+		check(t, 1)
+		clock.Advance(time.Minute - time.Second)
+		check(t, 1)
+		clock.Advance(time.Second)
+		check(t, 2)
+	})
+
+	ttl.Store(0)
+	t.Run("no-cache", func(t *testing.T) {
+		clock.Advance(time.Minute)
+		check(t, 3)
+		// the fake clock never moves by itself
+		clock.Advance(time.Nanosecond)
+		check(t, 4)
+	})
+}
+
+func TestCacheOf(t *testing.T) {
+	t.Run("initial", func(t *testing.T) {
+		initial := 1
+		cache := stalecache.CacheOf(
+			&initial,
+			stalecache.WithTTL[int](time.Nanosecond),
+			stalecache.WithValidator(func(context.Context, *int, time.Time) bool {
+				return false
+			}),
+		)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if data != &initial {
+			t.Errorf("Load got %d, want %d", *data, initial)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		cache := stalecache.CacheOf[int](nil)
+		if _, err := cache.Load(context.Background()); !errors.Is(err, stalecache.ErrNoLoader) {
+			t.Errorf("Load got error %v, want %v", err, stalecache.ErrNoLoader)
+		}
+		data := 2
+		if err := cache.Update(context.Background(), &data); err != nil {
+			t.Fatalf("Update got error: %v", err)
+		}
+		got, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if got != &data {
+			t.Errorf("Load got %d, want %d", *got, data)
+		}
+	})
+}
+
+func TestCacheUpdateIfVersion(t *testing.T) {
+	const n = 10
+	cache := stalecache.New(func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	})
+	_, version, err := cache.LoadWithVersion(context.Background())
+	if err != nil {
+		t.Fatalf("LoadWithVersion got error: %v", err)
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var succeeded atomic.Int64
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ok, err := cache.UpdateIfVersion(context.Background(), version, &i)
+				if err != nil {
+					t.Errorf("UpdateIfVersion #%d got error: %v", i, err)
+				}
+				if ok {
+					succeeded.Add(1)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if got := succeeded.Load(); got != 1 {
+			t.Errorf("Got %d successful UpdateIfVersion calls, want 1", got)
+		}
+	})
+
+	t.Run("write-back-error", func(t *testing.T) {
+		wantErr := errors.New("foo")
+		cache := stalecache.CacheOf(new(int), stalecache.WithWriteBack(func(context.Context, *int) error {
+			return wantErr
+		}))
+		_, version, _ := cache.LoadWithVersion(context.Background())
+		ok, err := cache.UpdateIfVersion(context.Background(), version, new(int))
+		if ok || !errors.Is(err, wantErr) {
+			t.Errorf("UpdateIfVersion got (%v, %v), want (false, %v)", ok, err, wantErr)
+		}
+	})
+}
+
+func TestCacheIdleTTL(t *testing.T) {
+	const (
+		ttl     = time.Second
+		idleTTL = 10 * time.Millisecond
+	)
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithIdleTTL[int64](idleTTL),
+		stalecache.WithClock[int64](clock),
+	)
+	check := func(t *testing.T, want int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	t.Run("active", func(t *testing.T) {
+		check(t, 1)
+		for i := 0; i < 5; i++ {
+			clock.Advance(idleTTL / 2)
+			check(t, 1)
+		}
+	})
+	t.Run("idle", func(t *testing.T) {
+		clock.Advance(idleTTL)
+		check(t, 2)
+	})
+	t.Run("ttl", func(t *testing.T) {
+		for i := time.Duration(0); i < ttl; i += idleTTL / 2 {
+			clock.Advance(idleTTL / 2)
+			cache.Load(context.Background())
+		}
+		check(t, 3)
+	})
+}
+
+func TestCacheExtendTTL(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var loaderCalls atomic.Int64
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			calls := loaderCalls.Add(1)
+			return &calls, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+	)
+	cache.ExtendTTL(0)
+	cache.Load(context.Background())
+
+	check := func(t *testing.T, want int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+	}
+
+	t.Run("full", func(t *testing.T) {
+		clock.Advance(ttl - time.Millisecond)
+		cache.ExtendTTL(0)
+		clock.Advance(ttl - time.Millisecond)
+		check(t, 1)
+	})
+	t.Run("custom", func(t *testing.T) {
+		cache.ExtendTTL(3 * ttl)
+		clock.Advance(3*ttl - time.Millisecond)
+		check(t, 1)
+		clock.Advance(time.Millisecond)
+		check(t, 2)
+	})
+}
+
+func TestNewE(t *testing.T) {
+	loader := func(context.Context) (*int, error) {
+		var data int
+		return &data, nil
+	}
+	for _, c := range []struct {
+		label   string
+		options []stalecache.Option[int]
+		wantErr bool
+	}{
+		{
+			label: "valid",
+			options: []stalecache.Option[int]{
+				stalecache.WithTTL[int](time.Second),
+				stalecache.WithCircuitBreaker[int](3, time.Second),
+			},
+		},
+		{
+			label:   "negative-max-stale",
+			options: []stalecache.Option[int]{stalecache.WithMaxStale[int](-1)},
+		},
+		{
+			label:   "negative-ttl",
+			options: []stalecache.Option[int]{stalecache.WithTTL[int](-time.Second)},
+			wantErr: true,
+		},
+		{
+			label: "conflicting-ttl",
+			options: []stalecache.Option[int]{
+				stalecache.WithTTL[int](time.Second),
+				stalecache.WithSlidingTTL[int](time.Second),
+			},
+			wantErr: true,
+		},
+		{
+			label:   "negative-circuit-threshold",
+			options: []stalecache.Option[int]{stalecache.WithCircuitBreaker[int](-1, time.Second)},
+			wantErr: true,
+		},
+	} {
+		t.Run(c.label, func(t *testing.T) {
+			err := stalecache.ValidateOptions(c.options...)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Errorf("ValidateOptions got error %v, want error: %v", err, c.wantErr)
+			}
+			cache, err := stalecache.NewE(loader, c.options...)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Errorf("NewE got error %v, want error: %v", err, c.wantErr)
+			}
+			if c.wantErr {
+				if cache != nil {
+					t.Errorf("NewE got non-nil Cache with error %v", err)
+				}
+				return
+			}
+			if _, err := cache.Load(context.Background()); err != nil {
+				t.Errorf("Load got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCachePeekRefresh(t *testing.T) {
+	const (
+		ttl = time.Minute
+		n   = 5
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	release := make(chan struct{})
+	loaded := make(chan struct{}, n)
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			<-release
+			data := loaderCalls.Add(1)
+			loaded <- struct{}{}
+			return &data, nil
+		},
+		stalecache.WithTTL[int64](ttl),
+		stalecache.WithClock[int64](clock),
+	)
+	peekAll := func(t *testing.T, want int64) {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		for i := 0; i < n; i++ {
+			data, _, err := cache.PeekRefresh(ctx)
+			if err != nil {
+				t.Fatalf("PeekRefresh #%d got error: %v", i, err)
+			}
+			if want == 0 {
+				if data != nil {
+					t.Errorf("PeekRefresh #%d got %d, want nil", i, *data)
+				}
+				continue
+			}
+			if data == nil || *data != want {
+				t.Errorf("PeekRefresh #%d got %v, want %d", i, data, want)
+			}
+		}
+	}
+
+	peekAll(t, 0)
+	release <- struct{}{}
+	<-loaded
+	cache.Drain(context.Background())
+	peekAll(t, 1)
+
+	clock.Advance(ttl)
+	peekAll(t, 1)
+	release <- struct{}{}
+	<-loaded
+	// The background reload swaps the entry after the loader returns.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if data, _, _ := cache.Peek(); *data == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	peekAll(t, 2)
+
+	close(release)
+	if got := loaderCalls.Load(); got != 2 {
+		t.Errorf("Got %d loader calls, want 2", got)
+	}
+}
+
+func TestBucketTTL(t *testing.T) {
+	const bucket = 30 * time.Second
+	start := time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)
+	clock := stalecachetest.NewFakeClock(start)
+	var loaderCalls int
+	newCache := func() *stalecache.Cache[int] {
+		return stalecache.New(
+			func(context.Context) (*int, error) {
+				loaderCalls++
+				return &loaderCalls, nil
+			},
+			stalecache.WithBucketTTL[int](bucket),
+			stalecache.WithClock[int](clock),
+		)
+	}
+	early := newCache()
+	early.Load(context.Background())
+	clock.Advance(15 * time.Second) // 12:00:25
+	late := newCache()
+	late.Load(context.Background())
+	if loaderCalls != 2 {
+		t.Fatalf("Got %d loader calls, want 2", loaderCalls)
+	}
+
+	clock.Advance(4 * time.Second) // 12:00:29
+	early.Load(context.Background())
+	late.Load(context.Background())
+	if loaderCalls != 2 {
+		t.Errorf("Got %d loader calls before the boundary, want 2", loaderCalls)
+	}
+
+	clock.Advance(time.Second) // 12:00:30
+	for _, c := range []*stalecache.Cache[int]{early, late} {
+		if !c.IsStale(context.Background()) {
+			t.Error("Cache not stale at the boundary")
+		}
+	}
+
+	t.Run("sliding", func(t *testing.T) {
+		_, err := stalecache.NewE(
+			func(context.Context) (*int, error) {
+				return nil, nil
+			},
+			stalecache.WithBucketTTL[int](bucket),
+			stalecache.WithSlidingTTL[int](bucket),
+		)
+		if err == nil {
+			t.Error("NewE with both WithBucketTTL and WithSlidingTTL got no error")
+		}
+	})
+}
+
+func TestCacheLoadIfOlderThan(t *testing.T) {
+	var loaderCalls int
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			loaderCalls++
+			return &loaderCalls, nil
+		},
+		stalecache.WithTTL[int](time.Hour),
+	)
+	data, version, err := cache.LoadIfOlderThan(context.Background(), 0)
+	if err != nil || *data != 1 || version != 1 {
+		t.Fatalf("LoadIfOlderThan(0) got %v, %d, %v, want 1, 1, nil", data, version, err)
+	}
+	data, version, err = cache.LoadIfOlderThan(context.Background(), 1)
+	if err != nil || *data != 1 || version != 1 {
+		t.Errorf("LoadIfOlderThan(1) got %v, %d, %v, want 1, 1, nil", data, version, err)
+	}
+	data, version, err = cache.LoadIfOlderThan(context.Background(), 2)
+	if err != nil || *data != 2 || version != 2 {
+		t.Errorf("LoadIfOlderThan(2) got %v, %d, %v, want 2, 2, nil", data, version, err)
+	}
+	if loaderCalls != 2 {
+		t.Errorf("Got %d loader calls, want 2", loaderCalls)
+	}
+}
+
+func TestProbabilisticExpiry(t *testing.T) {
+	const (
+		ttl          = time.Minute
+		loadDuration = 10 * time.Second
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	var loaderCalls atomic.Int64
+	reloaded := make(chan struct{}, 1)
+	newCache := func(beta float64) *stalecache.Cache[int64] {
+		loaderCalls.Store(0)
+		return stalecache.New(
+			func(context.Context) (*int64, error) {
+				clock.Advance(loadDuration)
+				data := loaderCalls.Add(1)
+				if data > 1 {
+					reloaded <- struct{}{}
+				}
+				return &data, nil
+			},
+			stalecache.WithTTL[int64](ttl),
+			stalecache.WithClock[int64](clock),
+			stalecache.WithProbabilisticExpiry[int64](beta),
+		)
+	}
+
+	t.Run("far", func(t *testing.T) {
+		// probability is exp(-60/0.1), effectively 0.
+		cache := newCache(0.01)
+		for i := 0; i < 100; i++ {
+			cache.Load(context.Background())
+		}
+		if got := loaderCalls.Load(); got != 1 {
+			t.Errorf("Got %d loader calls, want 1", got)
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		cache := newCache(1)
+		cache.Load(context.Background())
+		clock.Advance(ttl - time.Nanosecond)
+		// probability is exp(-1ns/10s), effectively 1.
+		data, err := cache.Load(context.Background())
+		if err != nil || *data != 1 {
+			t.Errorf("Load got %v, %v, want the fresh 1", data, err)
+		}
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			t.Error("Early reload not started")
+		}
+	})
+}
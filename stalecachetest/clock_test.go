@@ -0,0 +1,27 @@
+package stalecachetest_test
+
+import (
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+var _ stalecache.Clock = (*stalecachetest.FakeClock)(nil)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	clock := stalecachetest.NewFakeClock(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("Now() got %v, want %v", got, start)
+	}
+	clock.Advance(time.Minute)
+	if got, want := clock.Now(), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("Now() after Advance got %v, want %v", got, want)
+	}
+	clock.Set(start)
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("Now() after Set got %v, want %v", got, start)
+	}
+}
@@ -0,0 +1,151 @@
+package stalecachetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+type result[T any] struct {
+	data *T
+	err  error
+}
+
+// FakeCache is a stalecache.Cacher returning the results set by
+// SetNextResult, for testing code using stalecache.Cacher.
+//
+// It's safe for concurrent use.
+type FakeCache[T any] struct {
+	mu     sync.Mutex
+	now    func() time.Time
+	next   []result[T]
+	data   *T
+	loaded time.Time
+	err    error
+	calls  int
+	stats  stalecache.Stats
+	chans  []chan *T
+}
+
+var _ stalecache.Cacher[int] = (*FakeCache[int])(nil)
+
+// NewFakeCache creates a new FakeCache, with nothing loaded.
+//
+// If clock is nil, time.Now is used as the time the results are loaded.
+func NewFakeCache[T any](clock stalecache.Clock) *FakeCache[T] {
+	now := time.Now
+	if clock != nil {
+		now = clock.Now
+	}
+	return &FakeCache[T]{now: now}
+}
+
+// SetNextResult queues a result to be returned by a future Load.
+//
+// Every Load takes the next queued result as if it's returned by the loader,
+// a successful one replaces the cached data (and notifies the subscribers),
+// while a failed one is returned with the last successful data.
+// When there's no queued result,
+// Load returns the cached data as a cache hit.
+func (f *FakeCache[T]) SetNextResult(data *T, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.next = append(f.next, result[T]{data: data, err: err})
+}
+
+// CallCount returns how many times Load has been called.
+func (f *FakeCache[T]) CallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+// Load implements stalecache.Cacher.
+func (f *FakeCache[T]) Load(context.Context) (*T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if len(f.next) == 0 {
+		f.stats.Hits++
+		return f.data, f.err
+	}
+	r := f.next[0]
+	f.next = f.next[1:]
+	f.stats.Misses++
+	f.stats.Loads++
+	f.loaded = f.now()
+	f.err = r.err
+	if r.err != nil {
+		f.stats.LoadErrors++
+		return f.data, r.err
+	}
+	f.set(r.data)
+	return f.data, nil
+}
+
+// Peek implements stalecache.Cacher.
+func (f *FakeCache[T]) Peek() (*T, time.Time, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.data, f.loaded, f.err
+}
+
+// Update implements stalecache.Cacher.
+func (f *FakeCache[T]) Update(_ context.Context, data *T) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loaded = f.now()
+	f.err = nil
+	f.set(data)
+	return nil
+}
+
+// Subscribe implements stalecache.Cacher.
+//
+// The same as stalecache.Cache.Subscribe,
+// the channel has a buffer of 1 with only the latest data kept,
+// and it's closed after ctx is canceled.
+func (f *FakeCache[T]) Subscribe(ctx context.Context) <-chan *T {
+	ch := make(chan *T, 1)
+	f.mu.Lock()
+	f.chans = append(f.chans, ch)
+	f.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i, sub := range f.chans {
+			if sub == ch {
+				f.chans = append(f.chans[:i], f.chans[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// Stats implements stalecache.Cacher.
+//
+// Load calls taking a queued result are counted as misses and loads,
+// and the ones without as hits.
+func (f *FakeCache[T]) Stats() stalecache.Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// set sets data and notifies the subscribers, f.mu must be held.
+func (f *FakeCache[T]) set(data *T) {
+	f.data = data
+	for _, ch := range f.chans {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- data
+	}
+}
@@ -0,0 +1,56 @@
+package stalecachetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestFakeCache(t *testing.T) {
+	clock := stalecachetest.NewFakeClock(time.Now())
+	fake := stalecachetest.NewFakeCache[int](clock)
+	var cache stalecache.Cacher[int] = fake
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := cache.Subscribe(ctx)
+
+	if data, err := cache.Load(context.Background()); data != nil || err != nil {
+		t.Errorf("Load without results got %v, %v, want nil, nil", data, err)
+	}
+
+	one, wantErr := 1, errors.New("foo")
+	fake.SetNextResult(&one, nil)
+	fake.SetNextResult(nil, wantErr)
+	if data, err := cache.Load(context.Background()); data != &one || err != nil {
+		t.Errorf("Load #1 got %v, %v, want %v, nil", data, err, &one)
+	}
+	if data := <-ch; data != &one {
+		t.Errorf("Subscriber got %v, want %v", data, &one)
+	}
+	clock.Advance(time.Second)
+	if data, err := cache.Load(context.Background()); data != &one || !errors.Is(err, wantErr) {
+		t.Errorf("Load #2 got %v, %v, want %v, %v", data, err, &one, wantErr)
+	}
+	if data, loaded, err := cache.Peek(); data != &one || !loaded.Equal(clock.Now()) || err == nil {
+		t.Errorf("Peek got %v, %v, %v", data, loaded, err)
+	}
+
+	two := 2
+	cache.Update(context.Background(), &two)
+	if data, err := cache.Load(context.Background()); data != &two || err != nil {
+		t.Errorf("Load after Update got %v, %v, want %v, nil", data, err, &two)
+	}
+
+	if got := fake.CallCount(); got != 4 {
+		t.Errorf("CallCount got %d, want 4", got)
+	}
+	want := stalecache.Stats{Hits: 2, Misses: 2, Loads: 2, LoadErrors: 1}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Stats got %+v, want %+v", got, want)
+	}
+}
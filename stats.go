@@ -0,0 +1,46 @@
+package stalecache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Stats are the cumulative counters of a Cache.
+type Stats struct {
+	// Number of Load calls returned fresh cached data without calling the
+	// loader.
+	Hits uint64
+	// Number of Load calls found the cached data stale.
+	Misses uint64
+	// Number of loader calls, and the ones returned errors.
+	Loads      uint64
+	LoadErrors uint64
+}
+
+type statsCounters struct {
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+	loads      atomic.Uint64
+	loadErrors atomic.Uint64
+}
+
+// Stats returns the counters collected since the Cache is created.
+func (c *Cache[T]) Stats() Stats {
+	return Stats{
+		Hits:       c.stats.hits.Load(),
+		Misses:     c.stats.misses.Load(),
+		Loads:      c.stats.loads.Load(),
+		LoadErrors: c.stats.loadErrors.Load(),
+	}
+}
+
+func statsLoader[T any](s *statsCounters, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		data, err := loader(ctx)
+		s.loads.Add(1)
+		if err != nil {
+			s.loadErrors.Add(1)
+		}
+		return data, err
+	}
+}
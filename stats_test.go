@@ -0,0 +1,57 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestStats(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var fail atomic.Bool
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			if fail.Load() {
+				return nil, errors.New("foo")
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	check := func(t *testing.T, want stalecache.Stats) {
+		t.Helper()
+		if got := cache.Stats(); got != want {
+			t.Errorf("Stats got %+v, want %+v", got, want)
+		}
+	}
+
+	t.Run("first-load", func(t *testing.T) {
+		cache.Load(context.Background())
+		check(t, stalecache.Stats{Loads: 1})
+	})
+	t.Run("hit", func(t *testing.T) {
+		cache.Load(context.Background())
+		cache.Load(context.Background())
+		check(t, stalecache.Stats{Hits: 2, Loads: 1})
+	})
+	t.Run("miss", func(t *testing.T) {
+		clock.Advance(ttl)
+		cache.Load(context.Background())
+		check(t, stalecache.Stats{Hits: 2, Misses: 1, Loads: 2})
+	})
+	t.Run("error", func(t *testing.T) {
+		clock.Advance(ttl)
+		fail.Store(true)
+		cache.Load(context.Background())
+		check(t, stalecache.Stats{Hits: 2, Misses: 2, Loads: 3, LoadErrors: 1})
+	})
+}
@@ -0,0 +1,113 @@
+package stalecache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Default reconnect backoff of NewStreamed.
+const (
+	DefaultStreamBackoffMin = 100 * time.Millisecond
+	DefaultStreamBackoffMax = 30 * time.Second
+)
+
+// StreamLoader defines the callback to stream incremental updates from
+// external source.
+//
+// It's called with the current cached data (nil if there's none),
+// and should call emit with every incremental update received,
+// until the stream ends or ctx is canceled.
+type StreamLoader[T any] func(ctx context.Context, current *T, emit func(*T)) error
+
+// WithFallbackLoader is an Option to set the loader used by a Cache created by
+// NewStreamed when Load finds it stale (or empty),
+// for example when the stream is disconnected before sending the first update.
+//
+// Default is nil, means Load returns ErrNoLoader instead.
+// It's ignored by New and other constructors.
+func WithFallbackLoader[T any](loader Loader[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.streamFallback = loader
+	}
+}
+
+// WithReconnectBackoff is an Option to set the backoff between the reconnects
+// of the stream of a Cache created by NewStreamed.
+//
+// Default is DefaultStreamBackoffMin and DefaultStreamBackoffMax.
+// The backoff starts from min and doubles after every reconnect without
+// updates from the stream, up to max.
+// It's ignored by New and other constructors.
+func WithReconnectBackoff[T any](min, max time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.streamBackoffMin = min
+		o.streamBackoffMax = max
+	}
+}
+
+// NewStreamed creates a new Cache updated by the incremental updates from
+// stream.
+//
+// stream is called in a background goroutine right away,
+// and every update it emits is merged with the current cached data by merge
+// (with nil old when there's none) via UpdateFunc,
+// so merge could be called multiple times for the same update with
+// concurrent updates, and it should not modify old.
+// When stream returns (with or without an error),
+// it's called again after the backoff set by WithReconnectBackoff,
+// until Close is called.
+//
+// Between the updates the cache follows the options as usual,
+// for example with WithTTL,
+// Load calls the loader set by WithFallbackLoader if there's no update
+// received from the stream within ttl.
+func NewStreamed[T any](stream StreamLoader[T], merge func(old, update *T) *T, options ...Option[T]) *Cache[T] {
+	var o opt[T]
+	for _, option := range options {
+		option(&o)
+	}
+	loader := o.streamFallback
+	if loader == nil {
+		loader = func(context.Context) (*T, error) {
+			return nil, ErrNoLoader
+		}
+	}
+	c := New(loader, options...)
+	c.startBackground(func(ctx context.Context) {
+		c.stream(ctx, stream, merge)
+	})
+	return c
+}
+
+func (c *Cache[T]) stream(ctx context.Context, stream StreamLoader[T], merge func(old, update *T) *T) {
+	minBackoff, maxBackoff := c.opt.streamBackoffMin, c.opt.streamBackoffMax
+	if minBackoff <= 0 {
+		minBackoff = DefaultStreamBackoffMin
+	}
+	if maxBackoff < minBackoff {
+		maxBackoff = max(DefaultStreamBackoffMax, minBackoff)
+	}
+	backoff := minBackoff
+	for {
+		var emitted atomic.Bool
+		current, _, _ := c.PeekStale()
+		stream(ctx, current, func(update *T) {
+			emitted.Store(true)
+			c.UpdateFunc(func(old *T) *T {
+				return merge(old, update)
+			})
+		})
+		if emitted.Load() {
+			backoff = minBackoff
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
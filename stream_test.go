@@ -0,0 +1,94 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestNewStreamed(t *testing.T) {
+	add := func(old, update *int) *int {
+		data := *update
+		if old != nil {
+			data += *old
+		}
+		return &data
+	}
+	ptr := func(i int) *int {
+		return &i
+	}
+
+	t.Run("reconnect", func(t *testing.T) {
+		var connects atomic.Int64
+		var gotCurrent atomic.Pointer[int]
+		cache := stalecache.NewStreamed(
+			func(ctx context.Context, current *int, emit func(*int)) error {
+				switch connects.Add(1) {
+				case 1:
+					emit(ptr(1))
+					emit(ptr(2))
+					return errors.New("disconnected")
+				default:
+					gotCurrent.Store(current)
+					emit(ptr(10))
+					<-ctx.Done()
+					return ctx.Err()
+				}
+			},
+			add,
+			stalecache.WithReconnectBackoff[int](time.Millisecond, time.Millisecond),
+		)
+		defer cache.Close()
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if data, _, _ := cache.Peek(); data != nil && *data == 13 {
+				break
+			}
+			if time.Now().After(deadline) {
+				data, _, _ := cache.Peek()
+				t.Fatalf("Got %v, want 13", data)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if current := gotCurrent.Load(); current == nil || *current != 3 {
+			t.Errorf("Reconnected stream got current %v, want 3", current)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		cache := stalecache.NewStreamed(
+			func(context.Context, *int, func(*int)) error {
+				return errors.New("unavailable")
+			},
+			add,
+			stalecache.WithFallbackLoader(func(context.Context) (*int, error) {
+				return ptr(100), nil
+			}),
+			stalecache.WithReconnectBackoff[int](time.Millisecond, time.Millisecond),
+		)
+		defer cache.Close()
+		data, err := cache.Load(context.Background())
+		if err != nil || *data != 100 {
+			t.Errorf("Load got %v, %v, want 100, nil", data, err)
+		}
+	})
+
+	t.Run("no-fallback", func(t *testing.T) {
+		cache := stalecache.NewStreamed(
+			func(ctx context.Context, _ *int, _ func(*int)) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			add,
+		)
+		defer cache.Close()
+		if _, err := cache.Load(context.Background()); !errors.Is(err, stalecache.ErrNoLoader) {
+			t.Errorf("Load got error %v, want %v", err, stalecache.ErrNoLoader)
+		}
+	})
+}
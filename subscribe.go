@@ -0,0 +1,54 @@
+package stalecache
+
+import (
+	"context"
+	"sync"
+)
+
+type subscribers[T any] struct {
+	mu    sync.Mutex
+	chans []chan *T
+}
+
+// Subscribe returns a channel receiving the new data every time the cache is
+// reloaded successfully or updated.
+//
+// The channel has a buffer of 1,
+// and a pending data not received yet is replaced by the newer one,
+// so slow receivers only miss the intermediate data but never block Load.
+// The channel is closed after ctx is canceled.
+func (c *Cache[T]) Subscribe(ctx context.Context) <-chan *T {
+	ch := make(chan *T, 1)
+	c.subs.mu.Lock()
+	c.subs.chans = append(c.subs.chans, ch)
+	c.subs.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.subs.mu.Lock()
+		defer c.subs.mu.Unlock()
+		for i, sub := range c.subs.chans {
+			if sub == ch {
+				c.subs.chans = append(c.subs.chans[:i], c.subs.chans[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// notify sends data to all the subscribers.
+func (s *subscribers[T]) notify(data *T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ch := range s.chans {
+		// drop the pending one, if any
+		select {
+		case <-ch:
+		default:
+		}
+		// there's only one sender (guarded by mu), so this never blocks.
+		ch <- data
+	}
+}
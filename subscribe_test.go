@@ -0,0 +1,67 @@
+package stalecache_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestSubscribe(t *testing.T) {
+	const timeout = time.Second
+	var loaderCalls atomic.Int64
+	cache := stalecache.New(func(context.Context) (*int64, error) {
+		calls := loaderCalls.Add(1)
+		return &calls, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := cache.Subscribe(ctx)
+
+	receive := func(t *testing.T, want int64) {
+		t.Helper()
+		select {
+		case data := <-ch:
+			if *data != want {
+				t.Errorf("Received %d, want %d", *data, want)
+			}
+		case <-time.After(timeout):
+			t.Fatalf("Did not receive %d in %v", want, timeout)
+		}
+	}
+
+	t.Run("load", func(t *testing.T) {
+		cache.Load(context.Background())
+		receive(t, 1)
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		for i := int64(10); i <= 12; i++ {
+			i := i
+			cache.Update(context.Background(), &i)
+		}
+		receive(t, 12)
+		select {
+		case data := <-ch:
+			t.Errorf("Received unexpected %d", *data)
+		default:
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		cancel()
+		select {
+		case _, ok := <-ch:
+			if ok {
+				t.Error("Channel not closed after ctx canceled")
+			}
+		case <-time.After(timeout):
+			t.Fatalf("Channel not closed in %v", timeout)
+		}
+		// Updates after unsubscribed should not panic.
+		var data int64
+		cache.Update(context.Background(), &data)
+	})
+}
@@ -0,0 +1,49 @@
+package stalecache
+
+import (
+	"context"
+	"sync"
+)
+
+// TaggedLoader defines the callback to load value from external source
+// conditionally, for example with HTTP ETag or Last-Modified headers.
+//
+// It's called with the tag returned by the last successful call,
+// or empty string for the first call,
+// and returns the new value with its tag.
+// When the value is not modified since tag,
+// it should return nil data with the same tag and nil error.
+type TaggedLoader[T any] func(ctx context.Context, tag string) (data *T, newTag string, err error)
+
+// NewTagged creates a new Cache with a TaggedLoader and options.
+//
+// The Cache stores the tag alongside the value,
+// and passes it into the loader on every reload.
+// When the loader reports not modified,
+// the time the value is loaded is updated while the value is kept as-is,
+// so the same pointer is returned by Load.
+//
+// The tag is only updated by the loader,
+// Update and other ways of updating the value directly do not change it.
+func NewTagged[T any](loader TaggedLoader[T], options ...Option[T]) *Cache[T] {
+	var (
+		mu   sync.Mutex
+		data *T
+		tag  string
+	)
+	return New(func(ctx context.Context) (*T, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		newData, newTag, err := loader(ctx, tag)
+		if err != nil {
+			return nil, err
+		}
+		if newData == nil && newTag == tag {
+			// not modified
+			return data, nil
+		}
+		data, tag = newData, newTag
+		return data, nil
+	}, options...)
+}
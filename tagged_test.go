@@ -0,0 +1,69 @@
+package stalecache_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestTagged(t *testing.T) {
+	const ttl = 10 * time.Millisecond
+	var (
+		version  = 1
+		lastTags []string
+	)
+	clock := stalecachetest.NewFakeClock(time.Now())
+	cache := stalecache.NewTagged(
+		func(_ context.Context, tag string) (*int, string, error) {
+			lastTags = append(lastTags, tag)
+			newTag := fmt.Sprintf("v%d", version)
+			if tag == newTag {
+				return nil, tag, nil
+			}
+			data := version
+			return &data, newTag, nil
+		},
+		stalecache.WithTTL[int](ttl),
+		stalecache.WithClock[int](clock),
+	)
+
+	first, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+
+	t.Run("not-modified", func(t *testing.T) {
+		clock.Advance(ttl)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if data != first {
+			t.Errorf("Load got %p, want %p", data, first)
+		}
+		if got := cache.LoadedAt(); !got.Equal(clock.Now()) {
+			t.Errorf("LoadedAt got %v, want %v", got, clock.Now())
+		}
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		version = 2
+		clock.Advance(ttl)
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != 2 {
+			t.Errorf("Load got %d, want 2", *data)
+		}
+	})
+
+	want := []string{"", "v1", "v1"}
+	if fmt.Sprint(lastTags) != fmt.Sprint(want) {
+		t.Errorf("Loader got tags %q, want %q", lastTags, want)
+	}
+}
@@ -0,0 +1,46 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// Operation names passed into TraceFunc.
+const (
+	TraceOpLoad     = "load"
+	TraceOpValidate = "validate"
+)
+
+// TraceFunc starts tracing an operation,
+// op is one of the TraceOp constants.
+//
+// It returns the ctx to be used by the operation,
+// and the function to be called with the error of the operation when it
+// finishes.
+type TraceFunc func(ctx context.Context, op string) (context.Context, func(error))
+
+// WithTraceFunc is an Option to trace the loader and validator calls with
+// start, without depending on any tracing library.
+//
+// Default is nil, means no tracing.
+// When set, start is called with TraceOpLoad for every reload,
+// outside of all the other loader related Options like WithRetry,
+// and with TraceOpValidate for every validator call (with nil error,
+// as validators don't return errors).
+//
+// It can be used to wire a Cache into OpenTelemetry, Datadog, or any other
+// tracer.
+// For OpenTelemetry, see also the go.yhsif.com/stalecache/otel package.
+func WithTraceFunc[T any](start TraceFunc) Option[T] {
+	return func(o *opt[T]) {
+		o.traceFunc = start
+	}
+}
+
+func traceLoader[T any](start TraceFunc, loader Loader[T]) Loader[T] {
+	return func(ctx context.Context) (*T, error) {
+		ctx, finish := start(ctx, TraceOpLoad)
+		data, err := loader(ctx)
+		finish(err)
+		return data, err
+	}
+}
@@ -0,0 +1,61 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestTraceFunc(t *testing.T) {
+	type traceKey struct{}
+	var ops []string
+	var errs []error
+	loaderErr := errors.New("foo")
+	fail := false
+	cache := stalecache.New(
+		func(ctx context.Context) (*int, error) {
+			if got, _ := ctx.Value(traceKey{}).(string); got != stalecache.TraceOpLoad {
+				t.Errorf("Loader got trace ctx value %q, want %q", got, stalecache.TraceOpLoad)
+			}
+			if fail {
+				return nil, loaderErr
+			}
+			var data int
+			return &data, nil
+		},
+		stalecache.WithValidator(func(ctx context.Context, _ *int, _ time.Time) bool {
+			if got, _ := ctx.Value(traceKey{}).(string); got != stalecache.TraceOpValidate {
+				t.Errorf("Validator got trace ctx value %q, want %q", got, stalecache.TraceOpValidate)
+			}
+			return true
+		}),
+		stalecache.WithTraceFunc[int](func(ctx context.Context, op string) (context.Context, func(error)) {
+			ops = append(ops, op)
+			return context.WithValue(ctx, traceKey{}, op), func(err error) {
+				errs = append(errs, err)
+			}
+		}),
+	)
+
+	cache.Load(context.Background())
+	cache.Load(context.Background())
+	fail = true
+	cache.ForceReload(context.Background())
+
+	// The validator is called for both Load calls.
+	if want := []string{
+		stalecache.TraceOpLoad,
+		stalecache.TraceOpValidate,
+		stalecache.TraceOpValidate,
+		stalecache.TraceOpLoad,
+	}; !slices.Equal(ops, want) {
+		t.Errorf("Got ops %v, want %v", ops, want)
+	}
+	if want := []error{nil, nil, nil, loaderErr}; !slices.Equal(errs, want) {
+		t.Errorf("Got errors %v, want %v", errs, want)
+	}
+}
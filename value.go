@@ -0,0 +1,82 @@
+package stalecache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Value is the result of LoadValue.
+//
+// Unlike Result, its fields are not exported,
+// so it can only be read via its methods.
+type Value[T any] struct {
+	ptr *T
+	err error
+}
+
+// LoadValue is the same as Load, but returns the result as a Value.
+func (c *Cache[T]) LoadValue(ctx context.Context) Value[T] {
+	ptr, err := c.Load(ctx)
+	return Value[T]{ptr: ptr, err: err}
+}
+
+// Get returns the loaded value and true,
+// or the zero value and false when Load returned an error or nil data.
+func (v Value[T]) Get() (T, bool) {
+	if v.err != nil || v.ptr == nil {
+		var zero T
+		return zero, false
+	}
+	return *v.ptr, true
+}
+
+// Ptr returns the data returned by Load, which could be nil.
+func (v Value[T]) Ptr() *T {
+	return v.ptr
+}
+
+// Err returns the error returned by Load.
+func (v Value[T]) Err() error {
+	return v.err
+}
+
+// Must returns the loaded value,
+// or panics when Load returned an error the same as Result.Must.
+//
+// It returns the zero value if Load returned nil data without an error.
+func (v Value[T]) Must() T {
+	ptr := newResult(v.ptr, v.err).Must()
+	if ptr == nil {
+		var zero T
+		return zero
+	}
+	return *ptr
+}
+
+// String implements fmt.Stringer.
+//
+// It's the error when Load returned an error,
+// "<nil>" when Load returned nil data,
+// or the loaded value formatted with %v otherwise.
+func (v Value[T]) String() string {
+	if v.err != nil {
+		return fmt.Sprintf("error: %v", v.err)
+	}
+	if v.ptr == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%v", *v.ptr)
+}
+
+// MarshalJSON implements json.Marshaler.
+//
+// It marshals the loaded value, or null if Load returned nil data.
+// It returns the error returned by Load, if any, so json.Marshal fails instead
+// of writing a partial response.
+func (v Value[T]) MarshalJSON() ([]byte, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return json.Marshal(v.ptr)
+}
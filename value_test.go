@@ -0,0 +1,72 @@
+package stalecache_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestLoadValue(t *testing.T) {
+	type data struct {
+		Foo string `json:"foo"`
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		cache := stalecache.New(func(context.Context) (*data, error) {
+			return &data{Foo: "bar"}, nil
+		})
+		v := cache.LoadValue(context.Background())
+		if got, ok := v.Get(); !ok || got.Foo != "bar" {
+			t.Errorf("Get got (%v, %v), want ({bar}, true)", got, ok)
+		}
+		if v.Ptr() == nil || v.Err() != nil {
+			t.Errorf("Ptr, Err got %v, %v", v.Ptr(), v.Err())
+		}
+		if got := v.Must(); got.Foo != "bar" {
+			t.Errorf("Must got %v", got)
+		}
+		if got, want := v.String(), "{bar}"; got != want {
+			t.Errorf("String got %q, want %q", got, want)
+		}
+		b, err := json.Marshal(map[string]any{"data": v})
+		if err != nil {
+			t.Fatalf("json.Marshal got error: %v", err)
+		}
+		if got, want := string(b), `{"data":{"foo":"bar"}}`; got != want {
+			t.Errorf("json.Marshal got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("err", func(t *testing.T) {
+		wantErr := errors.New("foo")
+		cache := stalecache.New(func(context.Context) (*data, error) {
+			return nil, wantErr
+		})
+		v := cache.LoadValue(context.Background())
+		if got, ok := v.Get(); ok {
+			t.Errorf("Get got (%v, true), want false", got)
+		}
+		if v.Ptr() != nil || v.Err() != wantErr {
+			t.Errorf("Ptr, Err got %v, %v, want nil, %v", v.Ptr(), v.Err(), wantErr)
+		}
+		if got, want := v.String(), "error: foo"; got != want {
+			t.Errorf("String got %q, want %q", got, want)
+		}
+		if _, err := json.Marshal(v); !errors.Is(err, wantErr) {
+			t.Errorf("json.Marshal got error %v, want %v", err, wantErr)
+		}
+		defer func() {
+			ce, ok := recover().(*stalecache.CacheError)
+			if !ok {
+				t.Fatal("Must did not panic with *CacheError")
+			}
+			if !errors.Is(ce, wantErr) {
+				t.Errorf("Must panicked with %v, want %v", ce, wantErr)
+			}
+		}()
+		v.Must()
+	})
+}
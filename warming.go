@@ -0,0 +1,95 @@
+package stalecache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// WithCacheWarming is an Option to periodically push pre-computed data into
+// the cache.
+//
+// Default is nil.
+// When set with a positive interval,
+// a background goroutine calls warmer every interval,
+// and updates the cache with every returned data (so the last one wins),
+// the same as Update except that WithWriteBack is not used.
+// This supports the "batch pre-compute and push" pattern,
+// where a background job computes fresh data and pushes it into the cache,
+// instead of waiting for the ttl to expire.
+//
+// The background goroutine stops when Close is called.
+// It's not supported by Map and LRUMap (NewMap and NewLRUMap panic with it),
+// as the warmed values are not keyed.
+func WithCacheWarming[T any](warmer func(context.Context) []*T, interval time.Duration) Option[T] {
+	return func(o *opt[T]) {
+		o.warmer = warmer
+		o.warmerInterval = interval
+	}
+}
+
+func (c *Cache[T]) warm(ctx context.Context) {
+	ticker := time.NewTicker(c.opt.warmerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, data := range c.opt.warmer(ctx) {
+			// same as Update but without WithWriteBack,
+			// as the data is from the source already.
+			if c.update(data) {
+				c.publish(ctx, data)
+			}
+		}
+	}
+}
+
+// WarmFromURL fetches a snapshot from url with http.DefaultClient,
+// decodes the response body with decode,
+// and updates the cache with the decoded data.
+//
+// It's meant to pre-populate the cache from a snapshot endpoint at startup,
+// before the loader takes over.
+// The same as WithCacheWarming, WithWriteBack is not used.
+// When the response has a valid Last-Modified header,
+// the data is treated as loaded at that time,
+// so the ttl is counted from the time of the snapshot,
+// and it's ignored if the cache already has newer data.
+//
+// It returns an error and leaves the cache as-is if the request fails,
+// the response status is not 200,
+// or decode fails.
+func (c *Cache[T]) WarmFromURL(ctx context.Context, url string, decode func(io.Reader) (*T, error)) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stalecache: warm from %q got status %s", url, resp.Status)
+	}
+	data, err := decode(resp.Body)
+	if err != nil {
+		return err
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		if c.restore(data, lastModified) {
+			c.subs.notify(data)
+			c.publish(ctx, data)
+		}
+		return nil
+	}
+	if c.update(data) {
+		c.publish(ctx, data)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package stalecache_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+	"go.yhsif.com/stalecache/stalecachetest"
+)
+
+func TestCacheWarming(t *testing.T) {
+	const (
+		loaded = 1
+		warmed = 2
+
+		interval = time.Millisecond
+	)
+	var warmerCalls atomic.Int64
+	cache := stalecache.New(
+		func(context.Context) (*int, error) {
+			data := loaded
+			return &data, nil
+		},
+		stalecache.WithCacheWarming(
+			func(context.Context) []*int {
+				warmerCalls.Add(1)
+				data := warmed
+				return []*int{&data}
+			},
+			interval,
+		),
+	)
+	defer cache.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for warmerCalls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("warmer not called")
+		}
+		time.Sleep(interval)
+	}
+	data, err := cache.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load got error: %v", err)
+	}
+	if *data != warmed {
+		t.Errorf("Load got %d, want %d", *data, warmed)
+	}
+
+	cache.Close()
+	calls := warmerCalls.Load()
+	time.Sleep(10 * interval)
+	if after := warmerCalls.Load(); after != calls {
+		t.Errorf("warmer called %d times after Close", after-calls)
+	}
+}
+
+func TestWarmFromURL(t *testing.T) {
+	const ttl = time.Hour
+	now := time.Now().Truncate(time.Second)
+	snapshotTime := now.Add(-ttl / 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/snapshot":
+			w.Header().Set("Last-Modified", snapshotTime.UTC().Format(http.TimeFormat))
+			io.WriteString(w, "42")
+		case "/no-last-modified":
+			io.WriteString(w, "43")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	decode := func(r io.Reader) (*int, error) {
+		var data int
+		if err := json.NewDecoder(r).Decode(&data); err != nil {
+			return nil, err
+		}
+		return &data, nil
+	}
+	var loaderCalls int
+	newCache := func() *stalecache.Cache[int] {
+		return stalecache.New(
+			func(context.Context) (*int, error) {
+				loaderCalls++
+				return &loaderCalls, nil
+			},
+			stalecache.WithTTL[int](ttl),
+			stalecache.WithClock[int](stalecachetest.NewFakeClock(now)),
+		)
+	}
+
+	t.Run("last-modified", func(t *testing.T) {
+		cache := newCache()
+		if err := cache.WarmFromURL(context.Background(), server.URL+"/snapshot", decode); err != nil {
+			t.Fatalf("WarmFromURL got error: %v", err)
+		}
+		data, loadedAt, _ := cache.Peek()
+		if data == nil || *data != 42 {
+			t.Errorf("Peek got %v, want 42", data)
+		}
+		if !loadedAt.Equal(snapshotTime) {
+			t.Errorf("Peek got loaded at %v, want %v", loadedAt, snapshotTime)
+		}
+	})
+
+	t.Run("no-last-modified", func(t *testing.T) {
+		cache := newCache()
+		if err := cache.WarmFromURL(context.Background(), server.URL+"/no-last-modified", decode); err != nil {
+			t.Fatalf("WarmFromURL got error: %v", err)
+		}
+		data, loadedAt, _ := cache.Peek()
+		if data == nil || *data != 43 {
+			t.Errorf("Peek got %v, want 43", data)
+		}
+		if !loadedAt.Equal(now) {
+			t.Errorf("Peek got loaded at %v, want %v", loadedAt, now)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		cache := newCache()
+		if err := cache.WarmFromURL(context.Background(), server.URL+"/not-found", decode); err == nil {
+			t.Error("WarmFromURL got no error for 404")
+		}
+		if data, _, _ := cache.Peek(); data != nil {
+			t.Errorf("Peek got %d after failed WarmFromURL, want nil", *data)
+		}
+	})
+
+	if loaderCalls != 0 {
+		t.Errorf("Got %d loader calls, want 0", loaderCalls)
+	}
+}
@@ -0,0 +1,155 @@
+package stalecache
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ewmaAlpha is the weight of the latest sample used by the exponentially
+// weighted moving averages of the loader stats.
+const ewmaAlpha = 0.2
+
+// LoaderWeight defines a loader and its weight used by WithWeightedLoaders.
+type LoaderWeight[T any] struct {
+	Loader Loader[T]
+	Weight float64
+}
+
+// LoaderStats are the stats of a loader set by WithWeightedLoaders.
+type LoaderStats struct {
+	// The configured weight.
+	Weight float64
+	// The weight actually used by the weighted random selection.
+	// It's the same as Weight unless WithAdaptiveWeights is set.
+	EffectiveWeight float64
+
+	Calls  uint64
+	Errors uint64
+
+	// Exponentially weighted moving averages of the recent loader calls.
+	Latency     time.Duration
+	SuccessRate float64
+}
+
+// WithWeightedLoaders is an Option to use multiple loaders.
+//
+// Default is nil, means the loader passed into New is used.
+// When set, the loader passed into New is ignored,
+// and on each reload one of the loaders is picked by weighted random
+// selection.
+// Per-loader stats are available via LoaderStats.
+func WithWeightedLoaders[T any](loaders ...LoaderWeight[T]) Option[T] {
+	return func(o *opt[T]) {
+		o.weightedLoaders = loaders
+	}
+}
+
+// WithAdaptiveWeights is an Option to automatically adjust the weights set by
+// WithWeightedLoaders based on the recent performance of the loaders.
+//
+// Default is false.
+// When set to true, after every loader has been called at least once,
+// the effective weight of each loader is its configured weight multiplied by
+// its recent success rate, and divided by its recent latency,
+// so faster and more reliable loaders are picked more often.
+func WithAdaptiveWeights[T any](adaptive bool) Option[T] {
+	return func(o *opt[T]) {
+		o.adaptiveWeights = adaptive
+	}
+}
+
+type weightedLoaders[T any] struct {
+	adaptive bool
+	loaders  []LoaderWeight[T]
+
+	mu    sync.Mutex
+	stats []LoaderStats
+}
+
+func newWeightedLoaders[T any](loaders []LoaderWeight[T], adaptive bool) *weightedLoaders[T] {
+	w := &weightedLoaders[T]{
+		adaptive: adaptive,
+		loaders:  loaders,
+		stats:    make([]LoaderStats, len(loaders)),
+	}
+	for i, l := range loaders {
+		w.stats[i].Weight = l.Weight
+		w.stats[i].EffectiveWeight = l.Weight
+	}
+	return w
+}
+
+func (w *weightedLoaders[T]) load(ctx context.Context) (*T, error) {
+	i := w.pick()
+	start := time.Now()
+	data, err := w.loaders[i].Loader(ctx)
+	w.record(i, time.Since(start), err)
+	return data, err
+}
+
+func (w *weightedLoaders[T]) pick() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var total float64
+	for _, s := range w.stats {
+		total += s.EffectiveWeight
+	}
+	r := rand.Float64() * total
+	for i, s := range w.stats {
+		r -= s.EffectiveWeight
+		if r < 0 {
+			return i
+		}
+	}
+	return len(w.stats) - 1
+}
+
+func (w *weightedLoaders[T]) record(i int, latency time.Duration, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := &w.stats[i]
+	var success float64
+	if err == nil {
+		success = 1
+	} else {
+		s.Errors++
+	}
+	if s.Calls == 0 {
+		s.Latency = latency
+		s.SuccessRate = success
+	} else {
+		s.Latency = time.Duration(ewmaAlpha*float64(latency) + (1-ewmaAlpha)*float64(s.Latency))
+		s.SuccessRate = ewmaAlpha*success + (1-ewmaAlpha)*s.SuccessRate
+	}
+	s.Calls++
+
+	if !w.adaptive {
+		return
+	}
+	for _, s := range w.stats {
+		if s.Calls == 0 {
+			// not all loaders are tried yet, keep using configured weights.
+			return
+		}
+	}
+	for i := range w.stats {
+		s := &w.stats[i]
+		s.EffectiveWeight = s.Weight * s.SuccessRate / float64(s.Latency+time.Microsecond)
+	}
+}
+
+// LoaderStats returns the stats of the loaders set by WithWeightedLoaders,
+// in the same order as they are passed into WithWeightedLoaders.
+//
+// It returns nil if WithWeightedLoaders is not set.
+func (c *Cache[T]) LoaderStats() []LoaderStats {
+	w := c.opt.weighted
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]LoaderStats(nil), w.stats...)
+}
@@ -0,0 +1,78 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestWeightedLoaders(t *testing.T) {
+	const n = 100
+	constLoader := func(i int) stalecache.Loader[int] {
+		return func(context.Context) (*int, error) {
+			return &i, nil
+		}
+	}
+	failingLoader := func(context.Context) (*int, error) {
+		time.Sleep(time.Millisecond)
+		return nil, errors.New("foo")
+	}
+
+	t.Run("static", func(t *testing.T) {
+		cache := stalecache.New(
+			nil,
+			stalecache.WithValidator(func(context.Context, *int, time.Time) bool {
+				return false
+			}),
+			stalecache.WithWeightedLoaders(
+				stalecache.LoaderWeight[int]{Loader: constLoader(0), Weight: 1},
+				stalecache.LoaderWeight[int]{Loader: constLoader(1), Weight: 0},
+			),
+		)
+		for i := 0; i < n; i++ {
+			data, err := cache.Load(context.Background())
+			if err != nil {
+				t.Fatalf("Load got error: %v", err)
+			}
+			if *data != 0 {
+				t.Fatalf("Load got %d from a 0 weight loader", *data)
+			}
+		}
+		stats := cache.LoaderStats()
+		if len(stats) != 2 {
+			t.Fatalf("LoaderStats got %+v", stats)
+		}
+		if stats[0].Calls == 0 || stats[1].Calls != 0 {
+			t.Errorf("LoaderStats got %+v", stats)
+		}
+	})
+
+	t.Run("adaptive", func(t *testing.T) {
+		cache := stalecache.New(
+			nil,
+			stalecache.WithValidator(func(context.Context, *int, time.Time) bool {
+				return false
+			}),
+			stalecache.WithWeightedLoaders(
+				stalecache.LoaderWeight[int]{Loader: constLoader(0), Weight: 1},
+				stalecache.LoaderWeight[int]{Loader: failingLoader, Weight: 1},
+			),
+			stalecache.WithAdaptiveWeights[int](true),
+		)
+		for i := 0; i < n; i++ {
+			cache.Load(context.Background())
+		}
+		stats := cache.LoaderStats()
+		t.Logf("LoaderStats: %+v", stats)
+		if stats[0].EffectiveWeight <= stats[1].EffectiveWeight {
+			t.Errorf(
+				"EffectiveWeight of the good loader %v <= the failing loader %v",
+				stats[0].EffectiveWeight,
+				stats[1].EffectiveWeight,
+			)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package stalecache
+
+import (
+	"context"
+)
+
+// WriteAside implements the write path of the cache-aside pattern with c.
+//
+// It calls writer to write to the source of truth (for example, the database).
+// When writer succeeds,
+// the returned data is stored into c with Update (so the ttl starts over),
+// and later Loads return it without calling the loader.
+// When writer fails,
+// c is Reset so the next Load reloads from the source,
+// as the source could be partially written.
+//
+// As it uses Update, the writer set by WithWriteBack (if any) is still called
+// with the data returned by writer,
+// and when it fails c is Reset the same as writer failures.
+// The returned error is from writer or WithWriteBack.
+func WriteAside[T any](c *Cache[T], ctx context.Context, writer func(context.Context) (*T, error)) (*T, error) {
+	data, err := writer(ctx)
+	if err == nil {
+		err = c.Update(ctx, data)
+	}
+	if err != nil {
+		c.Reset()
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,77 @@
+package stalecache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"go.yhsif.com/stalecache"
+)
+
+func TestWriteAside(t *testing.T) {
+	var source atomic.Int64
+	var loaderCalls atomic.Int64
+	var writeBackErr error
+	cache := stalecache.New(
+		func(context.Context) (*int64, error) {
+			loaderCalls.Add(1)
+			data := source.Load()
+			return &data, nil
+		},
+		stalecache.WithWriteBack(func(context.Context, *int64) error {
+			return writeBackErr
+		}),
+	)
+	load := func(t *testing.T, want, wantCalls int64) {
+		t.Helper()
+		data, err := cache.Load(context.Background())
+		if err != nil {
+			t.Fatalf("Load got error: %v", err)
+		}
+		if *data != want {
+			t.Errorf("Load got %d, want %d", *data, want)
+		}
+		if got := loaderCalls.Load(); got != wantCalls {
+			t.Errorf("Got %d loader calls, want %d", got, wantCalls)
+		}
+	}
+	write := func(v int64, err error) func(context.Context) (*int64, error) {
+		return func(context.Context) (*int64, error) {
+			source.Store(v)
+			if err != nil {
+				return nil, err
+			}
+			return &v, nil
+		}
+	}
+
+	load(t, 0, 1)
+
+	t.Run("success", func(t *testing.T) {
+		data, err := stalecache.WriteAside(cache, context.Background(), write(1, nil))
+		if err != nil || *data != 1 {
+			t.Errorf("WriteAside got %v, %v, want 1, nil", data, err)
+		}
+		load(t, 1, 1)
+	})
+
+	t.Run("writer-failure", func(t *testing.T) {
+		wantErr := errors.New("foo")
+		if _, err := stalecache.WriteAside(cache, context.Background(), write(2, wantErr)); !errors.Is(err, wantErr) {
+			t.Errorf("WriteAside got error %v, want %v", err, wantErr)
+		}
+		load(t, 2, 2)
+	})
+
+	t.Run("write-back-failure", func(t *testing.T) {
+		writeBackErr = errors.New("bar")
+		defer func() {
+			writeBackErr = nil
+		}()
+		if _, err := stalecache.WriteAside(cache, context.Background(), write(3, nil)); !errors.Is(err, writeBackErr) {
+			t.Errorf("WriteAside got error %v, want %v", err, writeBackErr)
+		}
+		load(t, 3, 3)
+	})
+}